@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/database"
+)
+
+const (
+	defaultBackupDir    = "backups"
+	defaultBackupRetain = 7
+)
+
+// runBackup implements `subShare-api backup [-dir DIR] [-o FILE]`. It can run
+// while the server is up.
+func runBackup(args []string, dbPath string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	dir := fs.String("dir", envOr("BACKUP_DIR", defaultBackupDir), "directory for the timestamped backup")
+	out := fs.String("o", "", "exact file to write instead of a timestamped one in -dir")
+	fs.Parse(args)
+
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("database %s: %w", dbPath, err)
+	}
+
+	var (
+		ctx  = context.Background()
+		path = *out
+		err  error
+	)
+	if path != "" {
+		err = database.Backup(ctx, dbPath, path)
+	} else {
+		path, err = database.BackupToDir(ctx, dbPath, *dir, time.Now())
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("backed up %s to %s", dbPath, path)
+	return nil
+}
+
+// runRestore implements `subShare-api restore FILE`. Stop the server first:
+// the database file is swapped underneath it.
+func runRestore(args []string, dbPath string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: subShare-api restore BACKUP_FILE")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	backupPath := fs.Arg(0)
+
+	ctx := context.Background()
+	version, err := database.CheckBackup(ctx, backupPath)
+	if err != nil {
+		return err
+	}
+
+	previous, err := database.Restore(ctx, backupPath, dbPath, time.Now())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("restored %s (schema %d) to %s", backupPath, version, dbPath)
+	if previous != "" {
+		log.Printf("the replaced database was kept as %s", previous)
+	}
+	return nil
+}
+
+// startScheduledBackups backs the database up every BACKUP_INTERVAL_HOURS
+// into BACKUP_DIR, keeping the newest BACKUP_RETAIN files. Nothing runs
+// unless BACKUP_DIR is set.
+func startScheduledBackups(ctx context.Context, dbPath string) {
+	dir := os.Getenv("BACKUP_DIR")
+	if dir == "" {
+		return
+	}
+
+	interval := 24 * time.Hour
+	if v := os.Getenv("BACKUP_INTERVAL_HOURS"); v != "" {
+		hours, err := strconv.Atoi(v)
+		if err != nil || hours <= 0 {
+			log.Fatalf("invalid BACKUP_INTERVAL_HOURS: %q", v)
+		}
+		interval = time.Duration(hours) * time.Hour
+	}
+
+	retain := defaultBackupRetain
+	if v := os.Getenv("BACKUP_RETAIN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid BACKUP_RETAIN: %q", v)
+		}
+		retain = n
+	}
+
+	log.Printf("backing up to %s every %s, keeping %d", dir, interval, retain)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				path, err := database.BackupToDir(ctx, dbPath, dir, time.Now())
+				if err != nil {
+					log.Printf("scheduled backup failed: %v", err)
+					continue
+				}
+				log.Printf("backed up database to %s", path)
+
+				removed, err := database.PruneBackups(dir, retain)
+				if err != nil {
+					log.Printf("pruning backups failed: %v", err)
+				}
+				for _, p := range removed {
+					log.Printf("removed old backup %s", p)
+				}
+			}
+		}
+	}()
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
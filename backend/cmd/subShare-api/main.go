@@ -7,16 +7,23 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/joho/godotenv"
+	_ "github.com/mattn/go-sqlite3"
 
 	httpserver "github.com/NoNiiEa/subShare-Discord/source/api"
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
 	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/billVer"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
 	"github.com/NoNiiEa/subShare-Discord/source/database"
+	"github.com/NoNiiEa/subShare-Discord/source/expense"
 	"github.com/NoNiiEa/subShare-Discord/source/group"
-	"github.com/NoNiiEa/subShare-Discord/source/billVer"
+	"github.com/NoNiiEa/subShare-Discord/source/idempotency"
+	"github.com/NoNiiEa/subShare-Discord/source/portable"
+	"github.com/NoNiiEa/subShare-Discord/source/settlement"
 )
 
 func main() {
@@ -25,6 +32,14 @@ func main() {
 		log.Printf("warning: could not load config/.env: %v", err)
 	}
 
+	// openapi needs no database
+	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+		if err := runOpenAPI(os.Args[2:]); err != nil {
+			log.Fatalf("openapi: %v", err)
+		}
+		return
+	}
+
 	// Determine DB path
 	dbPath := os.Getenv("DB_PATH")
 	if dbPath == "" {
@@ -41,6 +56,24 @@ func main() {
 		}
 	}
 
+	// backup and restore work on the database file and exit; any other
+	// arguments are left to the server
+	if len(os.Args) > 1 {
+		var run func(args []string, dbPath string) error
+		switch os.Args[1] {
+		case "backup":
+			run = runBackup
+		case "restore":
+			run = runRestore
+		}
+		if run != nil {
+			if err := run(os.Args[2:], dbPath); err != nil {
+				log.Fatalf("%s: %v", os.Args[1], err)
+			}
+			return
+		}
+	}
+
 	// Open SQLite DB
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
@@ -61,11 +94,46 @@ func main() {
 
 	groupSvc := group.NewService(sqlStore)
 	billSvc := bill.NewService(sqlStore)
-	billVerSvc := billver.NewService(sqlStore, nil,  os.Getenv("EASISLIP_API_URL"), os.Getenv("EASISLIP_API_TOKEN"),)
+	billVerSvc := billver.NewService(sqlStore, groupSvc, nil, os.Getenv("EASISLIP_API_URL"), os.Getenv("EASISLIP_API_TOKEN"))
+	billVerSvc.SetSlipDatePolicy(slipDatePolicyFromEnv())
+
+	// exchange rates for non-THB groups; without a file only THB groups work
+	if ratesFile := os.Getenv("RATES_FILE"); ratesFile != "" {
+		rates, err := currency.NewStaticFileProvider(ratesFile)
+		if err != nil {
+			log.Fatalf("failed to load rates file %s: %v", ratesFile, err)
+		}
+		groupSvc.SetRateProvider(rates)
+		billSvc.SetRateProvider(rates)
+	}
+
+	expenseSvc := expense.NewService(sqlStore, groupSvc, billSvc)
+	settlementSvc := settlement.NewService(sqlStore, groupSvc)
+	portableSvc := portable.NewService(sqlStore, groupSvc)
+
+	auditLog := audit.NewService(sqlStore)
+	groupSvc.SetAuditLog(auditLog)
+	billSvc.SetAuditLog(auditLog)
+	billVerSvc.SetAuditLog(auditLog)
+	expenseSvc.SetAuditLog(auditLog)
+	settlementSvc.SetAuditLog(auditLog)
+	portableSvc.SetAuditLog(auditLog)
 
-	server := httpserver.NewServer(groupSvc, billSvc, billVerSvc)
+	server := httpserver.NewServer(groupSvc, billSvc, billVerSvc, expenseSvc, settlementSvc, portableSvc)
+
+	idempotencySvc := idempotency.NewService(sqlStore)
+	if v := os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"); v != "" {
+		hours, err := strconv.Atoi(v)
+		if err != nil || idempotencySvc.SetRetention(time.Duration(hours)*time.Hour) != nil {
+			log.Fatalf("invalid IDEMPOTENCY_KEY_TTL_HOURS: %q", v)
+		}
+	}
+	server.SetIdempotency(idempotencySvc)
 
 	startDailyPaymentReset(ctx, groupSvc)
+	startScheduledBackups(ctx, dbPath)
+	startAuditPruning(ctx, auditLog)
+	startIdempotencyPruning(ctx, idempotencySvc)
 
 	// Determine port
 	port := os.Getenv("PORT")
@@ -81,6 +149,96 @@ func main() {
 	}
 }
 
+// slipDatePolicyFromEnv reads SLIP_GRACE_BEFORE_DAYS and SLIP_GRACE_AFTER_DAYS,
+// falling back to the billver defaults.
+func slipDatePolicyFromEnv() billver.SlipDatePolicy {
+	policy := billver.DefaultSlipDatePolicy()
+
+	if v := os.Getenv("SLIP_GRACE_BEFORE_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			log.Fatalf("invalid SLIP_GRACE_BEFORE_DAYS: %q", v)
+		}
+		policy.GraceBefore = time.Duration(days) * 24 * time.Hour
+	}
+
+	if v := os.Getenv("SLIP_GRACE_AFTER_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			log.Fatalf("invalid SLIP_GRACE_AFTER_DAYS: %q", v)
+		}
+		policy.GraceAfter = time.Duration(days) * 24 * time.Hour
+	}
+
+	return policy
+}
+
+// startAuditPruning deletes audit events older than AUDIT_RETENTION_DAYS once
+// at startup and then daily. Events are kept forever when it is unset.
+func startAuditPruning(ctx context.Context, auditLog *audit.Service) {
+	v := os.Getenv("AUDIT_RETENTION_DAYS")
+	if v == "" {
+		return
+	}
+	days, err := strconv.Atoi(v)
+	if err != nil || days <= 0 {
+		log.Fatalf("invalid AUDIT_RETENTION_DAYS: %q", v)
+	}
+	retention := time.Duration(days) * 24 * time.Hour
+
+	prune := func() {
+		n, err := auditLog.Prune(ctx, retention, time.Now())
+		if err != nil {
+			log.Printf("pruning audit log failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("pruned %d audit events older than %d days", n, days)
+		}
+	}
+
+	go func() {
+		prune()
+
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				prune()
+			}
+		}
+	}()
+}
+
+// startIdempotencyPruning deletes expired idempotency keys hourly. Expired
+// keys are ignored anyway; this only keeps the table small.
+func startIdempotencyPruning(ctx context.Context, svc *idempotency.Service) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n, err := svc.Prune(ctx, time.Now())
+				if err != nil {
+					log.Printf("pruning idempotency keys failed: %v", err)
+					continue
+				}
+				if n > 0 {
+					log.Printf("pruned %d expired idempotency keys", n)
+				}
+			}
+		}
+	}()
+}
+
 func startDailyPaymentReset(ctx context.Context, svc *group.Service) {
 	go func() {
 		ticker := time.NewTicker(time.Hour)
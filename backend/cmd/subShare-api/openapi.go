@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	httpserver "github.com/NoNiiEa/subShare-Discord/source/api"
+	"github.com/NoNiiEa/subShare-Discord/source/openapi"
+)
+
+// runOpenAPI implements `subShare-api openapi [-o FILE] [-check] [-client FILE]`.
+// Without flags it prints the spec.
+func runOpenAPI(args []string) error {
+	fs := flag.NewFlagSet("openapi", flag.ExitOnError)
+	out := fs.String("o", "", "write the spec to this file instead of stdout")
+	check := fs.Bool("check", false, "fail when the routes and the spec differ")
+	client := fs.String("client", "", "write the generated Go client to this file")
+	fs.Parse(args)
+
+	if *check {
+		// the router is all CheckSpec looks at, so no services are needed
+		server := httpserver.NewServer(nil, nil, nil, nil, nil, nil)
+		if err := httpserver.CheckSpec(server); err != nil {
+			return err
+		}
+		log.Printf("spec covers all %d operations", len(httpserver.Endpoints))
+		return nil
+	}
+
+	if *client != "" {
+		src, err := openapi.GenerateClient("client", "subShare-api openapi", httpserver.Endpoints)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(*client, src, 0o644)
+	}
+
+	doc, err := httpserver.Spec()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*out, data, 0o644)
+}
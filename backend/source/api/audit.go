@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// actorHeader names the Discord user a request is made for. Handlers whose
+// body carries no actor rely on it to attribute audit events.
+const actorHeader = "X-Actor-ID"
+
+// auditContext passes the request ID and the acting user on to the audit
+// log and echoes the request ID back. It runs after middleware.RequestID.
+func auditContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if id := middleware.GetReqID(ctx); id != "" {
+			ctx = audit.WithRequestID(ctx, id)
+			w.Header().Set(middleware.RequestIDHeader, id)
+		}
+		if actor := r.Header.Get(actorHeader); actor != "" {
+			ctx = audit.WithActor(ctx, actor)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// handleGetAuditLog lists a group's audit events, newest first, to its
+// owner. Filter with action=, page with cursor= and limit=.
+func (s *Server) handleGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	query := r.URL.Query()
+
+	ownerID := query.Get("owner_id")
+	if ownerID == "" {
+		ownerID = r.Header.Get(actorHeader)
+	}
+
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			writeServiceError(w, audit.ErrInvalidLimit)
+			return
+		}
+	}
+
+	page, err := s.groupSvc.GetAuditLog(r.Context(), group.AuditLogRequest{
+		GroupID: id,
+		ActorID: ownerID,
+		Action:  audit.Action(query.Get("action")),
+		Cursor:  query.Get("cursor"),
+		Limit:   limit,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
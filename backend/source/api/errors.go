@@ -0,0 +1,276 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/billVer"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/database"
+	"github.com/NoNiiEa/subShare-Discord/source/expense"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+	"github.com/NoNiiEa/subShare-Discord/source/idempotency"
+	"github.com/NoNiiEa/subShare-Discord/source/portable"
+	"github.com/NoNiiEa/subShare-Discord/source/settlement"
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+)
+
+// ErrorResponse is the body of every error the API returns. Code is stable
+// and meant for programs; Message is for people and may change. Details
+// carries extra data for some codes, e.g. every field error of a
+// validation_failed or the statuses of an illegal bill transition.
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// Codes for errors raised by the handlers themselves rather than a service.
+const (
+	CodeInvalidRequest       = "invalid_request" // malformed path, query, JSON or form
+	CodeNotFound             = "not_found"
+	CodeTooLarge             = "request_too_large"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeInternal             = "internal_error"
+)
+
+// errorCode ties a service error to its HTTP status and stable code.
+type errorCode struct {
+	err    error
+	status int
+	code   string
+}
+
+// errorCodes lists every error a service returns on purpose. Errors that
+// mean the same thing in different packages share a code. Entries are
+// matched with errors.Is, in order.
+var errorCodes = []errorCode{
+	{database.ErrNotFound, http.StatusNotFound, CodeNotFound},
+
+	// before the packages' own errors, which a validation error also matches
+	{validate.ErrInvalid, http.StatusBadRequest, "validation_failed"},
+	{validate.ErrMalformed, http.StatusBadRequest, CodeInvalidRequest},
+
+	// group
+	{group.ErrInvalidName, http.StatusBadRequest, "invalid_name"},
+	{group.ErrInvalidAmount, http.StatusBadRequest, "invalid_amount"},
+	{group.ErrInvalidDueDay, http.StatusBadRequest, "invalid_due_day"},
+	{group.ErrInvalidGuildID, http.StatusBadRequest, "invalid_guild_id"},
+	{group.ErrInvalidOwnerID, http.StatusBadRequest, "invalid_owner_id"},
+	{group.ErrInvalidMemberID, http.StatusBadRequest, "invalid_member_id"},
+	{group.ErrNoMembersProvided, http.StatusBadRequest, "no_members"},
+	{group.ErrInvalidGroupID, http.StatusBadRequest, "invalid_group_id"},
+	{group.ErrMemberNotFound, http.StatusNotFound, "member_not_found"},
+	{group.ErrNotActiveMember, http.StatusNotFound, "member_not_active"},
+	{group.ErrAlreadyPaid, http.StatusBadRequest, "already_paid"},
+	{group.ErrAleadyInvited, http.StatusBadRequest, "already_invited"},
+	{group.ErrAleadyMembered, http.StatusBadRequest, "already_member"},
+	{group.ErrInvitedPermission, http.StatusForbidden, "invite_not_allowed"},
+	{group.ErrNotInvited, http.StatusBadRequest, "not_invited"},
+	{group.ErrNoUserID, http.StatusBadRequest, "missing_user_id"},
+	{group.ErrNotValidSlip, http.StatusUnprocessableEntity, "invalid_slip"},
+	{group.ErrNotOwner, http.StatusForbidden, "not_owner"},
+	{group.ErrInvalidRefundAmount, http.StatusBadRequest, "invalid_refund_amount"},
+	{group.ErrInsufficientCredit, http.StatusBadRequest, "insufficient_credit"},
+	{group.ErrInvalidPaymentMethod, http.StatusBadRequest, "invalid_payment_method"},
+	{group.ErrInvalidPaymentAmount, http.StatusBadRequest, "invalid_payment_amount"},
+	{group.ErrBillClosed, http.StatusConflict, "bill_closed"},
+	{group.ErrNoBillChanges, http.StatusBadRequest, "no_bill_changes"},
+	{group.ErrNotOwedToOwner, http.StatusConflict, "not_owed_to_owner"},
+	{group.ErrNoPaymentAccount, http.StatusBadRequest, "no_payment_account"},
+	{group.ErrInvalidPaymentAccount, http.StatusBadRequest, "invalid_payment_account"},
+	{group.ErrDuplicatePaymentAccount, http.StatusBadRequest, "duplicate_payment_account"},
+	{group.ErrMultiplePreferred, http.StatusBadRequest, "multiple_preferred_accounts"},
+	{group.ErrInvalidMemberStatus, http.StatusBadRequest, "invalid_member_status"},
+	{group.ErrVersionMismatch, http.StatusPreconditionFailed, "version_mismatch"},
+	{group.ErrInvalidPatch, http.StatusBadRequest, "invalid_patch"},
+	{group.ErrCurrencyLocked, http.StatusConflict, "currency_locked"},
+	{group.ErrInvalidSort, http.StatusBadRequest, "invalid_sort"},
+	{group.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{group.ErrInvalidLimit, http.StatusBadRequest, "invalid_limit"},
+
+	// bill
+	{bill.ErrInvalidGroupID, http.StatusBadRequest, "invalid_group_id"},
+	{bill.ErrInvalidMemberID, http.StatusBadRequest, "invalid_member_id"},
+	{bill.ErrInvalidBillID, http.StatusBadRequest, "invalid_bill_id"},
+	{bill.ErrInvalidYear, http.StatusBadRequest, "invalid_year"},
+	{bill.ErrInvalidMonth, http.StatusBadRequest, "invalid_month"},
+	{bill.ErrInvalidAmount, http.StatusBadRequest, "invalid_amount"},
+	{bill.ErrInvalidCurrency, http.StatusBadRequest, "invalid_currency"},
+	{bill.ErrCurrencyMismatch, http.StatusUnprocessableEntity, "currency_mismatch"},
+	{bill.ErrInvalidExpenseID, http.StatusBadRequest, "invalid_expense_id"},
+	{bill.ErrSlipTooSmall, http.StatusBadRequest, "slip_too_small"},
+	{bill.ErrBillNotFound, http.StatusNotFound, "bill_not_found"},
+	{bill.ErrBillMemberMismatch, http.StatusForbidden, "bill_member_mismatch"},
+	{bill.ErrBillAlreadyVerified, http.StatusConflict, "bill_already_verified"},
+	{bill.ErrVerificationFailed, http.StatusUnprocessableEntity, "verification_failed"},
+	{bill.ErrAttachmentNotFound, http.StatusNotFound, "attachment_not_found"},
+	{bill.ErrIllegalTransition, http.StatusConflict, "illegal_transition"},
+	{bill.ErrInvalidStatus, http.StatusBadRequest, "invalid_status"},
+	{bill.ErrStatusNotSettable, http.StatusBadRequest, "status_not_settable"},
+	{bill.ErrInvalidActorID, http.StatusBadRequest, "invalid_actor_id"},
+	{bill.ErrNotGroupOwner, http.StatusForbidden, "not_owner"},
+	{bill.ErrInvalidKind, http.StatusBadRequest, "invalid_kind"},
+	{bill.ErrInvalidPeriod, http.StatusBadRequest, "invalid_period"},
+	{bill.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{bill.ErrInvalidLimit, http.StatusBadRequest, "invalid_limit"},
+	{bill.ErrEmptyImport, http.StatusBadRequest, "empty_import"},
+	{bill.ErrTooManyImportRows, http.StatusBadRequest, "too_many_import_rows"},
+
+	// billver
+	{billver.ErrConfigNotSet, http.StatusServiceUnavailable, "slip_verification_unavailable"},
+	{billver.ErrSlipTooSmall, http.StatusBadRequest, "slip_too_small"},
+	{billver.ErrBillMemberMismatch, http.StatusForbidden, "bill_member_mismatch"},
+	{billver.ErrBillAlreadyVerified, http.StatusConflict, "bill_already_verified"},
+	{billver.ErrVerificationFailed, http.StatusUnprocessableEntity, "verification_failed"},
+	{billver.ErrWrongReciever, http.StatusUnprocessableEntity, "wrong_receiver"},
+	{billver.ErrDuplicateSlip, http.StatusConflict, "duplicate_slip"},
+	{billver.ErrSlipDateMissing, http.StatusUnprocessableEntity, "slip_date_missing"},
+	{billver.ErrSlipDateInvalid, http.StatusUnprocessableEntity, "slip_date_invalid"},
+	{billver.ErrSlipPredatesBill, http.StatusUnprocessableEntity, "slip_predates_bill"},
+	{billver.ErrSlipInFuture, http.StatusUnprocessableEntity, "slip_in_future"},
+	{billver.ErrSlipOutsideCycle, http.StatusUnprocessableEntity, "slip_outside_cycle"},
+
+	// currency
+	{currency.ErrInvalidCurrency, http.StatusBadRequest, "invalid_currency"},
+	{currency.ErrUnsupportedCurrency, http.StatusUnprocessableEntity, "unsupported_currency"},
+	{currency.ErrNoRateProvider, http.StatusUnprocessableEntity, "no_rate_provider"},
+
+	// expense
+	{expense.ErrInvalidGroupID, http.StatusBadRequest, "invalid_group_id"},
+	{expense.ErrInvalidActorID, http.StatusBadRequest, "invalid_actor_id"},
+	{expense.ErrInvalidAmount, http.StatusBadRequest, "invalid_amount"},
+	{expense.ErrInvalidDescription, http.StatusBadRequest, "invalid_description"},
+	{expense.ErrNotMember, http.StatusForbidden, "not_member"},
+	{expense.ErrInvalidPayer, http.StatusBadRequest, "invalid_payer"},
+	{expense.ErrInvalidParticipant, http.StatusBadRequest, "invalid_participant"},
+	{expense.ErrDuplicateParticipant, http.StatusBadRequest, "duplicate_participant"},
+	{expense.ErrNoOtherParticipants, http.StatusBadRequest, "no_other_participants"},
+
+	// settlement
+	{settlement.ErrInvalidGuildID, http.StatusBadRequest, "invalid_guild_id"},
+	{settlement.ErrInvalidActorID, http.StatusBadRequest, "invalid_actor_id"},
+	{settlement.ErrNothingToSettle, http.StatusBadRequest, "nothing_to_settle"},
+	{settlement.ErrPlanChanged, http.StatusConflict, "plan_changed"},
+	{settlement.ErrNotParticipant, http.StatusForbidden, "not_participant"},
+	{settlement.ErrAlreadyConfirmed, http.StatusConflict, "already_confirmed"},
+	{settlement.ErrSettlementBusy, http.StatusConflict, "settlement_busy"},
+	{settlement.ErrInvalidTransferAmount, http.StatusBadRequest, "invalid_transfer_amount"},
+
+	// portable
+	{portable.ErrInvalidGroupID, http.StatusBadRequest, "invalid_group_id"},
+	{portable.ErrInvalidDocument, http.StatusBadRequest, "invalid_document"},
+	{portable.ErrUnsupportedVersion, http.StatusUnprocessableEntity, "unsupported_document_version"},
+	{portable.ErrInvalidGroup, http.StatusBadRequest, "invalid_document_group"},
+	{portable.ErrUnknownBill, http.StatusBadRequest, "unknown_bill"},
+	{portable.ErrUnknownExpense, http.StatusBadRequest, "unknown_expense"},
+	{portable.ErrDuplicateRecordID, http.StatusBadRequest, "duplicate_record_id"},
+	{portable.ErrInvalidBillRecord, http.StatusBadRequest, "invalid_bill_record"},
+	{portable.ErrSlipAlreadyCounted, http.StatusConflict, "duplicate_slip"},
+
+	// audit
+	{audit.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{audit.ErrInvalidLimit, http.StatusBadRequest, "invalid_limit"},
+
+	// idempotency
+	{idempotency.ErrInvalidKey, http.StatusBadRequest, "invalid_idempotency_key"},
+	{idempotency.ErrKeyReused, http.StatusUnprocessableEntity, "idempotency_key_reused"},
+	{idempotency.ErrInProgress, http.StatusConflict, "idempotency_key_in_progress"},
+}
+
+// lookupError returns the status and code for err, or 500 and
+// CodeInternal when it is not a known service error.
+func lookupError(err error) (int, string, bool) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge, CodeTooLarge, true
+	}
+
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.status, c.code, true
+		}
+	}
+	return http.StatusInternalServerError, CodeInternal, false
+}
+
+// errorDetails returns the extra data carried by typed service errors.
+func errorDetails(err error) any {
+	var fieldErrs validate.Errors
+	if errors.As(err, &fieldErrs) {
+		return map[string]any{"fields": fieldErrs}
+	}
+
+	var transErr *bill.TransitionError
+	if errors.As(err, &transErr) {
+		return transErr
+	}
+
+	var mismatchErr *billver.ReceiverMismatchError
+	if errors.As(err, &mismatchErr) {
+		return map[string]any{"receiver_match": mismatchErr.Match}
+	}
+
+	var dateErr *billver.SlipDateError
+	if errors.As(err, &dateErr) {
+		return map[string]any{"slip_date": dateErr}
+	}
+
+	return nil
+}
+
+// writeError answers with the JSON error envelope.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, ErrorResponse{Code: code, Message: message})
+}
+
+// badRequest answers a request the handler could not parse.
+func badRequest(w http.ResponseWriter, message string) {
+	writeError(w, http.StatusBadRequest, CodeInvalidRequest, message)
+}
+
+// formError answers a form that could not be parsed: 413 when it is over the
+// route's limit, 400 otherwise.
+func formError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeServiceError(w, err)
+		return
+	}
+	badRequest(w, "invalid form data: "+err.Error())
+}
+
+// writeServiceError answers with the status and code of a service error.
+// Unknown errors are logged and hidden behind a 500.
+func writeServiceError(w http.ResponseWriter, err error) {
+	writeServiceErrorDetails(w, err, errorDetails(err))
+}
+
+// writeServiceErrorDetails is writeServiceError with details chosen by the
+// handler.
+func writeServiceErrorDetails(w http.ResponseWriter, err error, details any) {
+	status, code, known := lookupError(err)
+	if !known {
+		log.Printf("internal error: %v", err)
+		writeError(w, status, code, "internal error")
+		return
+	}
+
+	message := err.Error()
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		message = "not found"
+	case code == CodeTooLarge:
+		message = "request body is too large"
+	}
+
+	writeJSON(w, status, ErrorResponse{
+		Code:    code,
+		Message: message,
+		Details: details,
+	})
+}
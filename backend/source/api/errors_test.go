@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+)
+
+func TestErrorCodesAreReachable(t *testing.T) {
+	statuses := map[string]int{}
+	for _, c := range errorCodes {
+		// an earlier entry the error also matches would hide this one
+		status, code, ok := lookupError(fmt.Errorf("wrapped: %w", c.err))
+		if !ok || status != c.status || code != c.code {
+			t.Errorf("%q maps to %d %s, want %d %s", c.err, status, code, c.status, c.code)
+		}
+
+		if prev, ok := statuses[c.code]; ok && prev != c.status {
+			t.Errorf("code %s is sent with both %d and %d", c.code, prev, c.status)
+		}
+		statuses[c.code] = c.status
+	}
+}
+
+func TestWriteServiceError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantFields int
+	}{
+		{name: "service error", err: group.ErrNotOwner, wantStatus: http.StatusForbidden, wantCode: "not_owner"},
+		{
+			name:       "field errors win over their own codes",
+			err:        validate.Errors{{Field: "name", Err: group.ErrInvalidName}, {Field: "amount", Err: group.ErrInvalidAmount}},
+			wantStatus: http.StatusBadRequest, wantCode: "validation_failed", wantFields: 2,
+		},
+		{name: "body too large", err: &http.MaxBytesError{Limit: 1}, wantStatus: http.StatusRequestEntityTooLarge, wantCode: CodeTooLarge},
+		{name: "illegal transition", err: &bill.TransitionError{From: bill.BillStatusVerified, To: bill.BillStatusPending}, wantStatus: http.StatusConflict, wantCode: "illegal_transition"},
+		{name: "anything else is hidden", err: errors.New("disk on fire"), wantStatus: http.StatusInternalServerError, wantCode: CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeServiceError(w, tt.err)
+
+			var resp struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+				Details struct {
+					Fields []json.RawMessage `json:"fields"`
+				} `json:"details"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.wantStatus || resp.Code != tt.wantCode {
+				t.Errorf("got %d %s, want %d %s", w.Code, resp.Code, tt.wantStatus, tt.wantCode)
+			}
+			if resp.Message == "" || (tt.wantCode == CodeInternal && resp.Message == tt.err.Error()) {
+				t.Errorf("message = %q", resp.Message)
+			}
+			if len(resp.Details.Fields) != tt.wantFields {
+				t.Errorf("%d field errors, want %d", len(resp.Details.Fields), tt.wantFields)
+			}
+		})
+	}
+}
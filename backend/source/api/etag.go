@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+var errInvalidIfMatch = errors.New(`If-Match must be a single ETag such as "3", or *`)
+
+// groupETag is the ETag of a group: its version, which every change bumps.
+func groupETag(g *group.Group) string {
+	return strconv.Quote(strconv.FormatInt(g.Version, 10))
+}
+
+// writeGroup answers with a group and its ETag.
+func writeGroup(w http.ResponseWriter, status int, g *group.Group) {
+	w.Header().Set("ETag", groupETag(g))
+	writeJSON(w, status, g)
+}
+
+// ifMatchVersion reads the group version a client expects from If-Match.
+// Without the header, or with *, there is nothing to check and it returns
+// nil.
+func ifMatchVersion(r *http.Request) (*int64, error) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return nil, nil
+	}
+
+	tag, err := strconv.Unquote(h)
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version < 1 {
+		return nil, errInvalidIfMatch
+	}
+	return &version, nil
+}
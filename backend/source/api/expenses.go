@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/NoNiiEa/subShare-Discord/source/expense"
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) handleCreateExpense(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	var req expense.CreateExpenseRequest
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	e, err := s.expenseSvc.CreateExpense(r.Context(), req, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func (s *Server) handleGetExpenses(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	expenses, err := s.expenseSvc.GetExpensesByGroup(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, expenses)
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/sheet"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxImportSize = 5 << 20 // 5 MB
+
+var billSheetColumns = []sheet.Column{
+	{Name: "bill_id", Numeric: true},
+	{Name: "kind"},
+	{Name: "member_id"},
+	{Name: "payee_id"},
+	{Name: "period"},
+	{Name: "amount_due", Numeric: true},
+	{Name: "amount_paid", Numeric: true},
+	{Name: "currency"},
+	{Name: "status"},
+	{Name: "description"},
+	{Name: "created_at"},
+	{Name: "verified_at"},
+	{Name: "paid_at"},
+}
+
+var ledgerSheetColumns = []sheet.Column{
+	{Name: "entry_id", Numeric: true},
+	{Name: "member_id"},
+	{Name: "kind"},
+	{Name: "debit", Numeric: true},
+	{Name: "credit", Numeric: true},
+	{Name: "balance", Numeric: true},
+	{Name: "bill_id", Numeric: true},
+	{Name: "actor_id"},
+	{Name: "note"},
+	{Name: "created_at"},
+}
+
+// handleExportGroupBills exports a group's bills, optionally limited with
+// from/to periods, as format=csv (default) or format=xlsx.
+func (s *Server) handleExportGroupBills(w http.ResponseWriter, r *http.Request) {
+	id, format, ok := parseExportRequest(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+
+	if _, err := s.groupSvc.GetGroup(r.Context(), id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	bills, err := s.allGroupBills(r, bill.ListBillsRequest{
+		GroupID: id,
+		From:    query.Get("from"),
+		To:      query.Get("to"),
+		Limit:   200,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	out := sheet.Sheet{Name: "Bills", Columns: billSheetColumns}
+	for i := len(bills) - 1; i >= 0; i-- { // oldest first
+		b := bills[i]
+		out.AddRow(
+			strconv.FormatInt(b.ID, 10),
+			string(b.Kind),
+			b.MemberID,
+			b.PayeeID,
+			fmt.Sprintf("%04d-%02d", b.Year, b.Month),
+			formatAmount(b.AmountDue),
+			formatAmount(b.AmountPaid),
+			b.Currency,
+			string(b.Status),
+			b.Description,
+			formatTime(&b.CreatedAt),
+			formatTime(b.VerifiedAt),
+			formatTime(b.PaidAt),
+		)
+	}
+
+	writeSheet(w, format, fmt.Sprintf("group-%d-bills", id), out)
+}
+
+// handleExportGroupLedger exports the ledger of every member of a group, or
+// of one with member=, as format=csv (default) or format=xlsx.
+func (s *Server) handleExportGroupLedger(w http.ResponseWriter, r *http.Request) {
+	id, format, ok := parseExportRequest(w, r)
+	if !ok {
+		return
+	}
+	memberID := r.URL.Query().Get("member")
+
+	entries, err := s.groupSvc.GetGroupLedger(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	ledger := sheet.Sheet{Name: "Ledger", Columns: ledgerSheetColumns}
+	for _, e := range entries {
+		if memberID != "" && e.MemberID != memberID {
+			continue
+		}
+		billID := ""
+		if e.BillID != nil {
+			billID = strconv.FormatInt(*e.BillID, 10)
+		}
+		ledger.AddRow(
+			strconv.FormatInt(e.ID, 10),
+			e.MemberID,
+			string(e.Kind),
+			e.Debit.String(),
+			e.Credit.String(),
+			e.Balance.String(),
+			billID,
+			e.ActorID,
+			e.Note,
+			formatTime(&e.CreatedAt),
+		)
+	}
+
+	writeSheet(w, format, fmt.Sprintf("group-%d-ledger", id), ledger)
+}
+
+// handleImportPayments takes a CSV of historical payments, either as the
+// request body or as the "file" field of a multipart form. With dry_run=true
+// the rows are only checked. Any row error fails the whole import with 422.
+func (s *Server) handleImportPayments(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	req := bill.ImportPaymentsRequest{GroupID: id}
+	var body io.Reader = r.Body
+	param := r.URL.Query().Get // raw bodies aren't parsed as forms, whatever their content type
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxImportSize); err != nil {
+			formError(w, err)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			badRequest(w, "file is required")
+			return
+		}
+		defer file.Close()
+		body = file
+		param = r.FormValue
+	}
+	req.CSV = body
+
+	req.ActorID = param("owner_id")
+	if dryRun := param("dry_run"); dryRun != "" {
+		if req.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			badRequest(w, "invalid dry_run")
+			return
+		}
+	}
+
+	g, err := s.groupSvc.GetGroup(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	req.Currency = g.Currency
+	for _, m := range g.Members {
+		req.MemberIDs = append(req.MemberIDs, m.MemberID)
+	}
+
+	result, err := s.billSvc.ImportPayments(r.Context(), req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	switch {
+	case len(result.Errors) > 0:
+		writeJSON(w, http.StatusUnprocessableEntity, result)
+	case result.DryRun:
+		writeJSON(w, http.StatusOK, result)
+	default:
+		writeJSON(w, http.StatusCreated, result)
+	}
+}
+
+// allGroupBills pages through every bill matching req, newest first.
+func (s *Server) allGroupBills(r *http.Request, req bill.ListBillsRequest) ([]bill.Bill, error) {
+	var all []bill.Bill
+	for {
+		page, err := s.billSvc.ListBills(r.Context(), req)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Bills...)
+		if page.NextCursor == "" {
+			return all, nil
+		}
+		req.Cursor = page.NextCursor
+	}
+}
+
+func parseExportRequest(w http.ResponseWriter, r *http.Request) (int64, string, bool) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return 0, "", false
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		badRequest(w, "format must be csv or xlsx")
+		return 0, "", false
+	}
+
+	return id, format, true
+}
+
+func writeSheet(w http.ResponseWriter, format, filename string, sh sheet.Sheet) {
+	if format == "xlsx" {
+		w.Header().Set("Content-Type", sheet.ContentTypeXLSX)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, filename))
+		w.WriteHeader(http.StatusOK)
+		_ = sheet.WriteXLSX(w, sh)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+	w.WriteHeader(http.StatusOK)
+	_ = sheet.WriteCSV(w, sh)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/idempotency"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	// replayedHeader marks a response sent again for a repeated key.
+	replayedHeader = "Idempotent-Replayed"
+)
+
+// replayedHeaders are the response headers stored with a key and sent again
+// on replay, besides the body.
+var replayedHeaders = []string{"Content-Type", "Content-Disposition", "ETag", "Location"}
+
+// SetIdempotency turns on Idempotency-Key handling. Without it the header is
+// ignored.
+func (s *Server) SetIdempotency(svc *idempotency.Service) {
+	s.idempotency = svc
+}
+
+// idempotent runs a mutating request that carries an Idempotency-Key once.
+// Keys are scoped to the actor, method and path that sent them. A repeat of
+// the same request gets the stored response back; the same key on a
+// different request is refused. Server errors release the key so the
+// client can retry with it.
+func (s *Server) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if s.idempotency == nil || key == "" || !mutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// limitBody has capped the body at the route's limit
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		actorID := r.Header.Get(actorHeader)
+		scope := idempotency.Scope{ActorID: actorID, Method: r.Method, Route: r.URL.Path}
+		fingerprint := idempotency.Fingerprint(r.Method, r.URL.RequestURI(), actorID, fingerprintBody(r, body))
+		rec, replay, err := s.idempotency.Begin(r.Context(), scope, key, fingerprint, time.Now())
+		if err != nil {
+			if errors.Is(err, idempotency.ErrInProgress) {
+				w.Header().Set("Retry-After", "1")
+			}
+			writeServiceError(w, err)
+			return
+		}
+
+		if replay {
+			for name, value := range rec.Header {
+				w.Header().Set(name, value)
+			}
+			w.Header().Set(replayedHeader, "true")
+			w.WriteHeader(rec.Status)
+			_, _ = w.Write(rec.Body)
+			return
+		}
+
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			// the handler panicked or failed; the key must not stay claimed
+			// by a request that has no response to replay
+			if err := s.idempotency.Release(context.WithoutCancel(r.Context()), rec); err != nil {
+				log.Printf("releasing idempotency key failed: %v", err)
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+
+		if rw.status >= http.StatusInternalServerError {
+			return
+		}
+
+		header := map[string]string{}
+		for _, name := range replayedHeaders {
+			if v := rw.Header().Get(name); v != "" {
+				header[name] = v
+			}
+		}
+		err = s.idempotency.Complete(context.WithoutCancel(r.Context()), rec, rw.status, header, rw.body.Bytes(), time.Now())
+		if err != nil {
+			log.Printf("storing idempotent response failed: %v", err)
+			return
+		}
+		completed = true
+	})
+}
+
+// fingerprintBody is the part of the body that identifies a request. A
+// multipart body gets a new boundary on every retry, so it counts by its
+// fields and a hash of each file instead; one that doesn't parse counts as is
+// and is left to the handler to refuse.
+func fingerprintBody(r *http.Request, body []byte) []byte {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return body
+	}
+
+	var fields [][2]string
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return body
+		}
+
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return body
+		}
+		if part.FileName() != "" {
+			sum := sha256.Sum256(value)
+			value = []byte("sha256:" + hex.EncodeToString(sum[:]))
+		}
+		fields = append(fields, [2]string{part.FormName(), string(value)})
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i][0] != fields[j][0] {
+			return fields[i][0] < fields[j][0]
+		}
+		return fields[i][1] < fields[j][1]
+	})
+	canonical, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// recordingWriter keeps a copy of the status and body it writes through.
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+)
+
+type formPart struct {
+	name, filename, value string
+}
+
+func multipartRequest(t *testing.T, boundary string, parts ...formPart) ([]byte, string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.SetBoundary(boundary); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range parts {
+		if p.filename != "" {
+			fw, err := w.CreateFormFile(p.name, p.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fw.Write([]byte(p.value))
+			continue
+		}
+		if err := w.WriteField(p.name, p.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body.Bytes(), w.FormDataContentType()
+}
+
+func TestFingerprintBody(t *testing.T) {
+	member := formPart{"member_id", "", "100000000000000001"}
+	slip := formPart{"file", "slip.jpg", "slip bytes"}
+
+	digest := func(boundary string, parts ...formPart) string {
+		body, contentType := multipartRequest(t, boundary, parts...)
+		r := httptest.NewRequest("POST", "/bill/1/pay", bytes.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		return string(fingerprintBody(r, body))
+	}
+
+	base := digest("boundary-one", member, slip)
+	tests := []struct {
+		name string
+		got  string
+		same bool
+	}{
+		{"new boundary", digest("boundary-two", member, slip), true},
+		{"fields in another order", digest("boundary-two", slip, member), true},
+		{"renamed file", digest("boundary-two", member, formPart{"file", "photo.jpg", "slip bytes"}), true},
+		{"other slip", digest("boundary-two", member, formPart{"file", "slip.jpg", "other bytes"}), false},
+		{"other member", digest("boundary-two", formPart{"member_id", "", "100000000000000002"}, slip), false},
+		{"file sent as a field", digest("boundary-two", member, formPart{"file", "", "slip bytes"}), false},
+	}
+	for _, tt := range tests {
+		if (tt.got == base) != tt.same {
+			t.Errorf("%s: same fingerprint = %v, want %v", tt.name, tt.got == base, tt.same)
+		}
+	}
+
+	// other bodies count byte for byte
+	plain := []byte(`{"amount":100}`)
+	r := httptest.NewRequest("POST", "/groups/1/expenses", bytes.NewReader(plain))
+	r.Header.Set("Content-Type", "application/json")
+	if got := fingerprintBody(r, plain); !bytes.Equal(got, plain) {
+		t.Errorf("JSON body became %q", got)
+	}
+
+	broken := []byte("--boundary-one\r\nnot a part")
+	r = httptest.NewRequest("POST", "/bill/1/pay", bytes.NewReader(broken))
+	r.Header.Set("Content-Type", "multipart/form-data; boundary=boundary-one")
+	if got := fingerprintBody(r, broken); !bytes.Equal(got, broken) {
+		t.Errorf("unparsable body became %q", got)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, nil, nil)
+
+	tests := []struct {
+		method, path string
+		want         int64
+	}{
+		{"POST", "/groups", maxBodySize},
+		{"PATCH", "/groups/1", maxBodySize},
+		{"POST", "/groups/import", maxGroupImportSize},
+		{"POST", "/groups/1/import/payments", maxImportSize},
+		{"POST", "/bill/1/pay", maxUploadSize},
+		{"POST", "/bill/1/manual-payment", maxUploadSize},
+		{"POST", "/no/such/route", maxBodySize},
+	}
+	for _, tt := range tests {
+		if got := s.bodyLimit(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+			t.Errorf("%s %s: limit %d, want %d", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	// a body over the limit is refused before any service sees it
+	body := append([]byte(`{"name":"`), bytes.Repeat([]byte("x"), maxBodySize)...)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/groups", bytes.NewReader(body)))
+	if w.Code != 413 {
+		t.Errorf("oversized body: status %d, want 413", w.Code)
+	}
+}
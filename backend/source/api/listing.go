@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+func (s *Server) handleGetGuildGroups(w http.ResponseWriter, r *http.Request) {
+	req, ok := parseListGroupsRequest(w, r)
+	if !ok {
+		return
+	}
+	req.GuildID, ok = snowflakeParam(w, r, "guildID", "guild_id")
+	if !ok {
+		return
+	}
+	req.MemberID = r.URL.Query().Get("member")
+
+	s.writeGroupPage(w, r, req)
+}
+
+func (s *Server) handleGetMemberGroups(w http.ResponseWriter, r *http.Request) {
+	req, ok := parseListGroupsRequest(w, r)
+	if !ok {
+		return
+	}
+	req.MemberID, ok = snowflakeParam(w, r, "id", "member_id")
+	if !ok {
+		return
+	}
+	req.GuildID = r.URL.Query().Get("guild")
+
+	s.writeGroupPage(w, r, req)
+}
+
+// parseListGroupsRequest reads the filters shared by both group listings:
+// status, owner, due_day, sort, cursor and limit.
+func parseListGroupsRequest(w http.ResponseWriter, r *http.Request) (group.ListGroupsRequest, bool) {
+	query := r.URL.Query()
+
+	req := group.ListGroupsRequest{
+		OwnerID: query.Get("owner"),
+		Status:  group.MemberStatus(query.Get("status")),
+		Sort:    query.Get("sort"),
+		Cursor:  query.Get("cursor"),
+	}
+
+	if v := query.Get("due_day"); v != "" {
+		dueDay, err := strconv.Atoi(v)
+		if err != nil {
+			badRequest(w, "invalid due_day")
+			return req, false
+		}
+		req.DueDay = dueDay
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			badRequest(w, "invalid limit")
+			return req, false
+		}
+		req.Limit = limit
+	}
+
+	return req, true
+}
+
+func (s *Server) writeGroupPage(w http.ResponseWriter, r *http.Request, req group.ListGroupsRequest) {
+	page, err := s.groupSvc.ListGroups(r.Context(), req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// parseListBillsRequest reads the filters shared by both bill listings:
+// status (comma separated), kind, from, to, cursor, limit and include=proof.
+func parseListBillsRequest(w http.ResponseWriter, r *http.Request) (bill.ListBillsRequest, bool) {
+	query := r.URL.Query()
+
+	req := bill.ListBillsRequest{
+		Statuses:     bill.ParseStatuses(query.Get("status")),
+		Kind:         bill.BillKind(query.Get("kind")),
+		From:         query.Get("from"),
+		To:           query.Get("to"),
+		Cursor:       query.Get("cursor"),
+		IncludeProof: query.Get("include") == "proof",
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			badRequest(w, "invalid limit")
+			return req, false
+		}
+		req.Limit = limit
+	}
+
+	return req, true
+}
+
+func (s *Server) writeBillPage(w http.ResponseWriter, r *http.Request, req bill.ListBillsRequest) {
+	page, err := s.billSvc.ListBills(r.Context(), req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,336 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/expense"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+	"github.com/NoNiiEa/subShare-Discord/source/openapi"
+	"github.com/NoNiiEa/subShare-Discord/source/portable"
+	"github.com/NoNiiEa/subShare-Discord/source/settlement"
+	"github.com/NoNiiEa/subShare-Discord/source/sheet"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SpecInfo heads the OpenAPI document served at /openapi.json.
+var SpecInfo = openapi.Info{
+	Title:       "subShare API",
+	Version:     "1.0.0",
+	Description: "Shared subscriptions and expenses for Discord groups. Errors use the ErrorResponse envelope.",
+}
+
+func pathInt(name string) openapi.Param {
+	return openapi.Param{Name: name, Type: int64(0)}
+}
+
+func pathString(name string) openapi.Param {
+	return openapi.Param{Name: name, Type: ""}
+}
+
+func query(name, description string) openapi.Param {
+	return openapi.Param{Name: name, Type: "", Description: description}
+}
+
+func queryInt(name, description string) openapi.Param {
+	return openapi.Param{Name: name, Type: 0, Description: description}
+}
+
+// ifMatch makes a group update fail with 412 unless the group is still at the
+// version of the ETag.
+var ifMatch = openapi.Param{Name: "If-Match", Type: "", Description: `ETag of the group as last read, e.g. "3"`}
+
+// idempotencyKey is accepted by every mutating endpoint; see idempotent.
+var idempotencyKey = openapi.Param{Name: idempotencyKeyHeader, Type: "", Description: "makes retries of the request run it once and replay its response"}
+
+// filters of parseListBillsRequest and parseListGroupsRequest
+var (
+	listBillsQuery = []openapi.Param{
+		query("status", "comma separated bill statuses"),
+		query("kind", "recurring or expense"),
+		query("from", "first period, YYYY-MM"),
+		query("to", "last period, YYYY-MM"),
+		query("cursor", "next_cursor of the previous page"),
+		queryInt("limit", "page size"),
+		query("include", "proof to include slip proofs"),
+	}
+	listGroupsQuery = []openapi.Param{
+		query("status", "member status: Active, Invited or Left"),
+		query("owner", "owner's Discord ID"),
+		queryInt("due_day", "day of month bills are due"),
+		query("sort", "id, name, due_day or created_at, - for descending"),
+		query("cursor", "next_cursor of the previous page"),
+		queryInt("limit", "page size"),
+	}
+)
+
+// Endpoints describes every route in routes(). CheckSpec keeps the two in
+// step.
+var Endpoints = []openapi.Endpoint{
+	{Method: "GET", Path: "/health", ID: "Health", Tag: "system", Summary: "Report that the server is up.",
+		Status: http.StatusOK, Response: map[string]string{}},
+	{Method: "GET", Path: "/openapi.json", ID: "OpenAPI", Tag: "system", Summary: "This document.",
+		Status: http.StatusOK, Response: map[string]any{}},
+
+	// groups
+	{Method: "POST", Path: "/groups", ID: "CreateGroup", Tag: "groups", Summary: "Create a group.",
+		Body: group.CreateGroupRequest{}, Status: http.StatusCreated, Response: group.Group{}},
+	{Method: "POST", Path: "/groups/import", ID: "ImportGroup", Tag: "groups",
+		Summary: "Recreate a group from an export document.",
+		Query:   []openapi.Param{query("guild_id", "move the group to this guild")},
+		Body:    portable.Document{}, Status: http.StatusCreated, Response: portable.ImportResult{}},
+	{Method: "GET", Path: "/groups/{id}", ID: "GetGroup", Tag: "groups",
+		Summary:    "Get a group with its members' balances. The ETag is the group's version.",
+		PathParams: []openapi.Param{pathInt("id")}, Status: http.StatusOK, Response: group.Group{}},
+	{Method: "DELETE", Path: "/groups/{id}", ID: "DeleteGroup", Tag: "groups", Summary: "Delete a group.",
+		PathParams: []openapi.Param{pathInt("id")}, Status: http.StatusNoContent},
+	{Method: "PUT", Path: "/groups/{id}", ID: "UpdateGroup", Tag: "groups",
+		Summary:    "Replace a group's settings. Members change through invites only.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Headers:    []openapi.Param{ifMatch},
+		Body:       group.UpdateGroupRequest{}, Status: http.StatusOK, Response: group.Group{}},
+	{Method: "PATCH", Path: "/groups/{id}", ID: "PatchGroup", Tag: "groups",
+		Summary:    "Change some of a group's settings with a JSON merge patch (application/merge-patch+json) of UpdateGroupRequest.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Headers:    []openapi.Param{ifMatch},
+		Body:       map[string]any{}, Status: http.StatusOK, Response: group.Group{}},
+	{Method: "POST", Path: "/groups/{id}/invite", ID: "InviteGroup", Tag: "groups", Summary: "Invite members to a group.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Body:       group.InviteGroupRequest{}, Status: http.StatusOK, Response: group.Group{}},
+	{Method: "POST", Path: "/groups/{id}/accept-invite", ID: "AcceptInvite", Tag: "groups", Summary: "Accept an invite to a group.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Body:       group.AcceptInviteRequest{}, Status: http.StatusOK, Response: group.Group{}},
+	{Method: "POST", Path: "/groups/{GroupID}/member/{MemberID}/pay", ID: "MarkAsPaid", Tag: "members",
+		Summary:    "Credit a payment to a member's ledger.",
+		PathParams: []openapi.Param{pathInt("GroupID"), pathString("MemberID")},
+		Body:       group.MarkAsPaidRequest{}, Status: http.StatusOK, Response: group.GroupMember{}},
+	{Method: "GET", Path: "/groups/{GroupID}/member/{MemberID}/credit", ID: "GetMemberCredit", Tag: "members",
+		Summary:    "Get a member's credit in a group.",
+		PathParams: []openapi.Param{pathInt("GroupID"), pathString("MemberID")},
+		Status:     http.StatusOK, Response: group.MemberCredit{}},
+	{Method: "POST", Path: "/groups/{GroupID}/member/{MemberID}/credit/refund", ID: "RefundCredit", Tag: "members",
+		Summary:    "Pay back part of a member's credit.",
+		PathParams: []openapi.Param{pathInt("GroupID"), pathString("MemberID")},
+		Body:       group.RefundCreditRequest{}, Status: http.StatusOK, Response: group.MemberCredit{}},
+	{Method: "GET", Path: "/groups/{id}/bill", ID: "ListGroupBills", Tag: "bills", Summary: "List a group's bills, newest first.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Query:      append([]openapi.Param{query("member", "only this member's bills")}, listBillsQuery...),
+		Status:     http.StatusOK, Response: bill.BillPage{}},
+	{Method: "GET", Path: "/groups/{id}/members/{memberID}/ledger", ID: "GetMemberLedger", Tag: "members",
+		Summary:    "Get a member's ledger entries and balance.",
+		PathParams: []openapi.Param{pathInt("id"), pathString("memberID")},
+		Status:     http.StatusOK, Response: group.MemberLedger{}},
+	{Method: "GET", Path: "/groups/{id}/report", ID: "GetGroupReport", Tag: "reports",
+		Summary:    "Collection report per month and per member, as JSON or CSV.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Query: []openapi.Param{
+			query("from", "first period, YYYY-MM"),
+			query("to", "last period, YYYY-MM"),
+			query("format", "csv for CSV"),
+			query("view", "CSV table: months or members"),
+		},
+		Status: http.StatusOK, Response: group.GroupReport{}, Downloads: []string{"text/csv"}},
+	{Method: "GET", Path: "/groups/{id}/audit", ID: "GetAuditLog", Tag: "groups", Summary: "List a group's audit events, newest first. Owner only.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Query: []openapi.Param{
+			query("owner_id", "the owner; defaults to the X-Actor-ID header"),
+			query("action", "only this action"),
+			query("cursor", "next_cursor of the previous page"),
+			queryInt("limit", "page size"),
+		},
+		Status: http.StatusOK, Response: audit.Page{}},
+	{Method: "GET", Path: "/groups/{id}/export", ID: "ExportGroup", Tag: "groups",
+		Summary:    "Export a group with its bills, payments and ledger.",
+		PathParams: []openapi.Param{pathInt("id")}, Status: http.StatusOK, Response: portable.Document{}},
+	{Method: "GET", Path: "/groups/{id}/export/bills", ID: "ExportGroupBills", Tag: "reports", Summary: "Export a group's bills as CSV or XLSX.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Query: []openapi.Param{
+			query("format", "csv (default) or xlsx"),
+			query("from", "first period, YYYY-MM"),
+			query("to", "last period, YYYY-MM"),
+		},
+		Status: http.StatusOK, Downloads: []string{"text/csv", sheet.ContentTypeXLSX}},
+	{Method: "GET", Path: "/groups/{id}/export/ledger", ID: "ExportGroupLedger", Tag: "reports", Summary: "Export a group's ledger as CSV or XLSX.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Query: []openapi.Param{
+			query("format", "csv (default) or xlsx"),
+			query("member", "only this member's entries"),
+		},
+		Status: http.StatusOK, Downloads: []string{"text/csv", sheet.ContentTypeXLSX}},
+	{Method: "POST", Path: "/groups/{id}/import/payments", ID: "ImportPayments", Tag: "bills",
+		Summary:    "Import historical payments from CSV. Owner only. Answers 422 with the row errors.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Query: []openapi.Param{
+			query("owner_id", "the owner, for raw CSV bodies"),
+			{Name: "dry_run", Type: false, Description: "check without importing, for raw CSV bodies"},
+		},
+		Form: []openapi.Param{
+			{Name: "owner_id", Type: "", Required: true},
+			{Name: "dry_run", Type: false},
+		},
+		File: "file", NeedsFile: true, RawBody: "text/csv",
+		Status: http.StatusCreated, Response: bill.ImportResult{}},
+	{Method: "POST", Path: "/groups/{id}/expenses", ID: "CreateExpense", Tag: "expenses",
+		Summary:    "Record a one-off expense and bill the participants.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Body:       expense.CreateExpenseRequest{}, Status: http.StatusCreated, Response: expense.Expense{}},
+	{Method: "GET", Path: "/groups/{id}/expenses", ID: "ListExpenses", Tag: "expenses", Summary: "List a group's expenses.",
+		PathParams: []openapi.Param{pathInt("id")}, Status: http.StatusOK, Response: []expense.Expense{}},
+
+	// members
+	{Method: "GET", Path: "/member/{id}/bill", ID: "ListMemberBills", Tag: "bills", Summary: "List a member's bills, newest first.",
+		PathParams: []openapi.Param{pathString("id")},
+		Query:      append([]openapi.Param{queryInt("group", "only bills of this group")}, listBillsQuery...),
+		Status:     http.StatusOK, Response: bill.BillPage{}},
+	{Method: "GET", Path: "/member/{id}/groups", ID: "ListMemberGroups", Tag: "groups", Summary: "List the groups of a member.",
+		PathParams: []openapi.Param{pathString("id")},
+		Query:      append([]openapi.Param{query("guild", "only groups of this guild")}, listGroupsQuery...),
+		Status:     http.StatusOK, Response: group.GroupPage{}},
+	{Method: "GET", Path: "/member/{id}/summary", ID: "GetMemberSummary", Tag: "members",
+		Summary:    "What a member owes and is owed across groups.",
+		PathParams: []openapi.Param{pathString("id")}, Status: http.StatusOK, Response: group.MemberSummary{}},
+
+	// guilds
+	{Method: "GET", Path: "/guilds/{guildID}/groups", ID: "ListGuildGroups", Tag: "groups", Summary: "List the groups of a guild.",
+		PathParams: []openapi.Param{pathString("guildID")},
+		Query:      append([]openapi.Param{query("member", "only groups with this member")}, listGroupsQuery...),
+		Status:     http.StatusOK, Response: group.GroupPage{}},
+	{Method: "GET", Path: "/guilds/{guildID}/settle-up", ID: "SettleUp", Tag: "settlements",
+		Summary:    "Net the guild's outstanding bills into transfers.",
+		PathParams: []openapi.Param{pathString("guildID")}, Status: http.StatusOK, Response: settlement.Plan{}},
+	{Method: "POST", Path: "/guilds/{guildID}/settlements", ID: "RecordSettlement", Tag: "settlements",
+		Summary:    "Confirm the settle-up transfers; the bills close once every payee has.",
+		PathParams: []openapi.Param{pathString("guildID")},
+		Body:       settlement.RecordSettlementRequest{}, Status: http.StatusCreated, Response: settlement.Settlement{}},
+	{Method: "GET", Path: "/guilds/{guildID}/settlements", ID: "ListSettlements", Tag: "settlements", Summary: "List a guild's settlements.",
+		PathParams: []openapi.Param{pathString("guildID")}, Status: http.StatusOK, Response: []settlement.Settlement{}},
+
+	{Method: "POST", Path: "/test/due-day/{DueDay}", ID: "ResetPayment", Tag: "system",
+		Summary:    "Issue the bills of groups due on a day, as the daily job does.",
+		PathParams: []openapi.Param{{Name: "DueDay", Type: 0}}, Status: http.StatusNoContent},
+
+	// bills
+	{Method: "POST", Path: "/bill/{id}/pay", ID: "SubmitBill", Tag: "bills", Summary: "Pay a bill with a bank slip.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Form: []openapi.Param{
+			{Name: "member_id", Type: "", Required: true},
+			{Name: "amount_paid", Type: float64(0)},
+		},
+		File: "file", NeedsFile: true,
+		Status: http.StatusOK, Response: bill.Bill{}},
+	{Method: "GET", Path: "/bill/{id}/payments", ID: "ListBillPayments", Tag: "bills", Summary: "List the payments on a bill.",
+		PathParams: []openapi.Param{pathInt("id")}, Status: http.StatusOK, Response: []bill.Payment{}},
+	{Method: "GET", Path: "/bill/{id}/payments/{paymentID}/attachment", ID: "GetPaymentAttachment", Tag: "bills",
+		Summary:    "Download the receipt attached to a manual payment.",
+		PathParams: []openapi.Param{pathInt("id"), pathInt("paymentID")},
+		Status:     http.StatusOK, Downloads: []string{"application/octet-stream"}},
+	{Method: "POST", Path: "/bill/{id}/manual-payment", ID: "RecordManualPayment", Tag: "bills",
+		Summary:    "Record a cash or other off-slip payment. Owner only. Send multipart to attach a receipt.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Body:       group.RecordPaymentRequest{},
+		Form: []openapi.Param{
+			{Name: "owner_id", Type: "", Required: true},
+			{Name: "method", Type: "", Required: true},
+			{Name: "amount", Type: float64(0), Required: true},
+			{Name: "note", Type: ""},
+			{Name: "paid_at", Type: "", Description: "RFC 3339"},
+		},
+		File:   "file",
+		Status: http.StatusCreated, Response: group.RecordPaymentResult{}},
+	{Method: "POST", Path: "/bill/{id}/transition", ID: "TransitionBill", Tag: "bills", Summary: "Move a bill to another status. Owner only.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Body:       bill.TransitionBillRequest{}, Status: http.StatusOK, Response: bill.Bill{}},
+	{Method: "GET", Path: "/bill/{id}/history", ID: "GetBillHistory", Tag: "bills", Summary: "List a bill's status changes.",
+		PathParams: []openapi.Param{pathInt("id")}, Status: http.StatusOK, Response: []bill.BillEvent{}},
+	{Method: "POST", Path: "/bill/{id}/cancel", ID: "CancelBill", Tag: "bills", Summary: "Cancel a bill. Owner only.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Body:       group.BillActionRequest{}, Status: http.StatusOK, Response: bill.Bill{}},
+	{Method: "POST", Path: "/bill/{id}/waive", ID: "WaiveBill", Tag: "bills", Summary: "Forgive what is left on a bill. Owner only.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Body:       group.BillActionRequest{}, Status: http.StatusOK, Response: bill.Bill{}},
+	{Method: "PATCH", Path: "/bill/{id}", ID: "AmendBill", Tag: "bills", Summary: "Change the amount or description of an open bill. Owner only.",
+		PathParams: []openapi.Param{pathInt("id")},
+		Body:       group.AmendBillRequest{}, Status: http.StatusOK, Response: bill.Bill{}},
+}
+
+var (
+	specOnce sync.Once
+	spec     *openapi.Document
+	specErr  error
+)
+
+// Spec builds the OpenAPI document from Endpoints.
+func Spec() (*openapi.Document, error) {
+	specOnce.Do(func() {
+		endpoints := make([]openapi.Endpoint, len(Endpoints))
+		for i, e := range Endpoints {
+			if mutating(e.Method) {
+				e.Headers = append(e.Headers[:len(e.Headers):len(e.Headers)], idempotencyKey)
+			}
+			endpoints[i] = e
+		}
+		spec, specErr = openapi.Build(SpecInfo, ErrorResponse{}, endpoints)
+	})
+	return spec, specErr
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc, err := Spec()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// CheckSpec reports routes missing from the spec and spec operations no route
+// serves. Run it with `subShare-api openapi -check`.
+func CheckSpec(s *Server) error {
+	doc, err := Spec()
+	if err != nil {
+		return err
+	}
+
+	described := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range *item {
+			described[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	served := map[string]bool{}
+	err = chi.Walk(s.router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		served[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for op := range served {
+		if !described[op] {
+			problems = append(problems, "not in the spec: "+op)
+		}
+	}
+	for op := range described {
+		if !served[op] {
+			problems = append(problems, "no route for: "+op)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+	return fmt.Errorf("routes and spec differ:\n  %s", strings.Join(problems, "\n  "))
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/openapi"
+)
+
+func TestCheckSpec(t *testing.T) {
+	// the router is all CheckSpec looks at, so no services are needed
+	server := NewServer(nil, nil, nil, nil, nil, nil)
+	if err := CheckSpec(server); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientUpToDate(t *testing.T) {
+	want, err := openapi.GenerateClient("client", "subShare-api openapi", Endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("../client/client_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("client/client_gen.go is stale; run go generate ./client")
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/NoNiiEa/subShare-Discord/source/portable"
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxGroupImportSize = 64 << 20 // 64 MB, documents carry slip attachments
+
+func (s *Server) handleExportGroup(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	doc, err := s.portableSvc.Export(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="group-%d.json"`, id))
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// handleImportGroup takes a document from GET /groups/{id}/export as the body;
+// guild_id moves the group to another Discord guild.
+func (s *Server) handleImportGroup(w http.ResponseWriter, r *http.Request) {
+	req := portable.ImportRequest{GuildID: r.URL.Query().Get("guild_id")}
+	if err := validate.DecodeJSON(r.Body, &req.Document); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	result, err := s.portableSvc.Import(r.Context(), req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, result)
+}
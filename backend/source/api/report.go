@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// handleGetGroupReport serves the report as JSON, or as CSV with format=csv
+// or "Accept: text/csv". CSV holds one table: view=months (default) or
+// view=members.
+func (s *Server) handleGetGroupReport(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	query := r.URL.Query()
+	asCSV := query.Get("format") == "csv" ||
+		(query.Get("format") == "" && strings.Contains(r.Header.Get("Accept"), "text/csv"))
+
+	view := query.Get("view")
+	if view == "" {
+		view = "months"
+	}
+	if view != "months" && view != "members" {
+		badRequest(w, "view must be months or members")
+		return
+	}
+
+	report, err := s.groupSvc.GetGroupReport(r.Context(), group.ReportRequest{
+		GroupID: id,
+		From:    query.Get("from"),
+		To:      query.Get("to"),
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	if !asCSV {
+		writeJSON(w, http.StatusOK, report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="group-%d-%s.csv"`, report.GroupID, view))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	if view == "members" {
+		writeMemberReportCSV(cw, report)
+	} else {
+		writeMonthReportCSV(cw, report)
+	}
+	cw.Flush()
+}
+
+func writeMonthReportCSV(cw *csv.Writer, report *group.GroupReport) {
+	cw.Write([]string{"period", "currency", "bills", "due", "collected", "outstanding", "waived"})
+	for _, m := range report.Months {
+		cw.Write([]string{
+			m.Period,
+			report.Currency,
+			strconv.Itoa(m.Bills),
+			formatAmount(m.Due),
+			formatAmount(m.Collected),
+			formatAmount(m.Outstanding),
+			formatAmount(m.Waived),
+		})
+	}
+}
+
+func writeMemberReportCSV(cw *csv.Writer, report *group.GroupReport) {
+	cw.Write([]string{
+		"member_id", "currency", "bills", "paid", "on_time", "late", "on_time_rate",
+		"avg_days_to_pay", "due", "collected", "outstanding", "rejected_slips",
+	})
+	for _, m := range report.Members {
+		cw.Write([]string{
+			m.MemberID,
+			report.Currency,
+			strconv.Itoa(m.Bills),
+			strconv.Itoa(m.Paid),
+			strconv.Itoa(m.OnTime),
+			strconv.Itoa(m.Late),
+			formatOptional(m.OnTimeRate),
+			formatOptional(m.AvgDaysToPay),
+			formatAmount(m.Due),
+			formatAmount(m.Collected),
+			formatAmount(m.Outstanding),
+			strconv.Itoa(m.RejectedSlips),
+		})
+	}
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// formatOptional leaves the cell empty for values that can't be computed yet.
+func formatOptional(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatAmount(*v)
+}
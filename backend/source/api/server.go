@@ -7,21 +7,30 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/NoNiiEa/subShare-Discord/source/bill"
 	"github.com/NoNiiEa/subShare-Discord/source/billVer"
-	"github.com/NoNiiEa/subShare-Discord/source/database"
+	"github.com/NoNiiEa/subShare-Discord/source/expense"
 	"github.com/NoNiiEa/subShare-Discord/source/group"
+	"github.com/NoNiiEa/subShare-Discord/source/idempotency"
+	"github.com/NoNiiEa/subShare-Discord/source/portable"
+	"github.com/NoNiiEa/subShare-Discord/source/settlement"
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 type Server struct {
-	router   *chi.Mux
-	groupSvc *group.Service
-	billSvc  *bill.Service
-	billVerSvc *billver.Service
+	router        *chi.Mux
+	groupSvc      *group.Service
+	billSvc       *bill.Service
+	billVerSvc    *billver.Service
+	expenseSvc    *expense.Service
+	settlementSvc *settlement.Service
+	portableSvc   *portable.Service
+	idempotency   *idempotency.Service
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -30,30 +39,34 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// snowflakeParam is the Discord ID in URL parameter param. When it isn't one,
+// it answers with the validation envelope naming field and returns false.
+func snowflakeParam(w http.ResponseWriter, r *http.Request, param, field string) (string, bool) {
+	id := chi.URLParam(r, param)
+
+	var v validate.Validator
+	v.Snowflake(field, id)
+	if err := v.Err(); err != nil {
+		writeServiceError(w, err)
+		return "", false
+	}
+	return id, true
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
 func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
 	var req group.CreateGroupRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
 	g, err := s.groupSvc.CreateGroup(r.Context(), req)
 	if err != nil {
-		if errors.Is(err, group.ErrInvalidName) ||
-			errors.Is(err, group.ErrInvalidAmount) ||
-			errors.Is(err, group.ErrInvalidDueDay) ||
-			errors.Is(err, group.ErrInvalidGuildID) ||
-			errors.Is(err, group.ErrInvalidOwnerID) ||
-			errors.Is(err, group.ErrNoMembersProvided) {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -62,76 +75,100 @@ func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
 	var req group.UpdateGroupRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		badRequest(w, "invalid id")
+		return
+	}
+
+	req.Version, err = ifMatchVersion(r)
+	if err != nil {
+		badRequest(w, err.Error())
 		return
 	}
 
 	g, err := s.groupSvc.UpdateGroup(r.Context(), req, id)
 	if err != nil {
-		if errors.Is(err, group.ErrInvalidName) ||
-			errors.Is(err, group.ErrInvalidAmount) ||
-			errors.Is(err, group.ErrInvalidDueDay) ||
-			errors.Is(err, group.ErrInvalidGuildID) ||
-			errors.Is(err, group.ErrInvalidOwnerID) ||
-			errors.Is(err, group.ErrNoMembersProvided) {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGroup(w, http.StatusOK, g)
+}
 
-		http.Error(w, "internal error", http.StatusInternalServerError)
+const contentTypeMergePatch = "application/merge-patch+json"
+
+// handlePatchGroup takes a JSON merge patch of the group's settings; plain
+// application/json bodies are read the same way.
+func (s *Server) handlePatchGroup(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, g)
+	ct := r.Header.Get("Content-Type")
+	if ct != "" && !strings.HasPrefix(ct, contentTypeMergePatch) && !strings.HasPrefix(ct, "application/json") {
+		writeError(w, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "send the patch as "+contentTypeMergePatch)
+		return
+	}
+
+	var req group.PatchGroupRequest
+	if err := validate.DecodeJSON(r.Body, &req.Patch); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	req.Version, err = ifMatchVersion(r)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	g, err := s.groupSvc.PatchGroup(r.Context(), req, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGroup(w, http.StatusOK, g)
 }
 
 func (s *Server) handleGetGroup(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		badRequest(w, "invalid id")
 		return
 	}
 
 	g, err := s.groupSvc.GetGroup(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, database.ErrNotFound) {
-			http.Error(w, "group not found", http.StatusNotFound)
-			return
-		}
-
-		// Other errors → 500
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, g)
+	writeGroup(w, http.StatusOK, g)
 }
 
 func (s *Server) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil || id <= 0{
-		http.Error(w, "invalid id", http.StatusBadRequest)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
 		return
 	}
 
 	err = s.groupSvc.DeleteGroup(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, database.ErrNotFound) {
-			http.Error(w, "group not found", http.StatusNotFound)
-			return
-		}
-
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -141,31 +178,20 @@ func (s *Server) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleInviteGroup(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil || id <= 0{
-		http.Error(w, "invalid id", http.StatusBadRequest)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
 		return
 	}
 
 	var req group.InviteGroupRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
 	g, err := s.groupSvc.InviteGroup(r.Context(), req, id)
 	if err != nil {
-		if errors.Is(err, group.ErrAleadyInvited) ||
-		errors.Is(err, group.ErrAleadyMembered) {
-			http.Error(w, "invalid members invite", http.StatusBadRequest)
-			return
-		}
-
-		if errors.Is(err, group.ErrInvitedPermission) {
-			http.Error(w, "user do not have permission to invite", http.StatusForbidden)
-			return
-		}
-
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -175,25 +201,20 @@ func (s *Server) handleInviteGroup(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleAcceptInvite(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil || id <= 0{
-		http.Error(w, "invalid id", http.StatusBadRequest)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
 		return
 	}
 
 	var req group.AcceptInviteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
 	g, err := s.groupSvc.AcceptInvite(r.Context(), req, id)
 	if err != nil {
-		if errors.Is(err, group.ErrNotInvited) {
-			http.Error(w, "user is not invited or is aleady a member", http.StatusBadRequest)
-			return
-		}
-
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -204,18 +225,12 @@ func (s *Server) handleResetPayment(w http.ResponseWriter, r *http.Request) {
 	DueDayStr := chi.URLParam(r, "DueDay")
 	DueDay, err := strconv.Atoi(DueDayStr)
 	if err != nil {
-		http.Error(w, "invalid due day", http.StatusBadRequest)
+		badRequest(w, "invalid due day")
 		return
 	}
-	
-	if err := s.groupSvc.ResetPaymentForDueday(r.Context(), DueDay); err != nil {
-		if errors.Is(err, group.ErrInvalidDueDay) {
-			http.Error(w, "invalid due day", http.StatusBadRequest)
-			return
-		}
 
-		http.Error(w, "internal error", http.StatusInternalServerError)
-		fmt.Println(err)
+	if err := s.groupSvc.ResetPaymentForDueday(r.Context(), DueDay); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
@@ -225,84 +240,154 @@ func (s *Server) handleResetPayment(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleMarkAsPaid(w http.ResponseWriter, r *http.Request) {
 	GroupIDStr := chi.URLParam(r, "GroupID")
 	GroupID, err := strconv.ParseInt(GroupIDStr, 10, 64)
-	if err != nil || GroupID <= 0{
-		http.Error(w, "invalid id", http.StatusBadRequest)
+	if err != nil || GroupID <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+	MemberID, ok := snowflakeParam(w, r, "MemberID", "member_id")
+	if !ok {
 		return
 	}
-	MemberID := chi.URLParam(r, "MemberID")
 
 	var req group.MarkAsPaidRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
 	member, err := s.groupSvc.MarkMemberPaid(r.Context(), req, GroupID, MemberID)
 	if err != nil {
-		if errors.Is(err, group.ErrInvalidGroupID) || errors.Is(err, group.ErrNoUserID) {
-			http.Error(w, "invalid group_id or user_id", http.StatusBadRequest)
-			return
-		}
+		writeServiceError(w, err)
+		return
+	}
 
-		if errors.Is(err, group.ErrMemberNotFound) || errors.Is(err, group.ErrNotActiveMember) {
-			http.Error(w, "member not found in group", http.StatusNotFound)
-			return
-		}
+	writeJSON(w, http.StatusOK, member)
+}
 
-		if errors.Is(err, group.ErrAlreadyPaid) {
-			http.Error(w, "member already paid", http.StatusBadRequest)
-			return
-		}
+func (s *Server) handleGetMemberCredit(w http.ResponseWriter, r *http.Request) {
+	GroupIDStr := chi.URLParam(r, "GroupID")
+	GroupID, err := strconv.ParseInt(GroupIDStr, 10, 64)
+	if err != nil || GroupID <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+	MemberID, ok := snowflakeParam(w, r, "MemberID", "member_id")
+	if !ok {
+		return
+	}
 
-		http.Error(w, "internal error", http.StatusInternalServerError)
+	credit, err := s.groupSvc.GetMemberCredit(r.Context(), GroupID, MemberID)
+	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, member)
+	writeJSON(w, http.StatusOK, credit)
 }
 
-func (s *Server) handleGetBillByGroupID(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleRefundCredit(w http.ResponseWriter, r *http.Request) {
+	GroupIDStr := chi.URLParam(r, "GroupID")
+	GroupID, err := strconv.ParseInt(GroupIDStr, 10, 64)
+	if err != nil || GroupID <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+	MemberID, ok := snowflakeParam(w, r, "MemberID", "member_id")
+	if !ok {
+		return
+	}
+
+	var req group.RefundCreditRequest
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	credit, err := s.groupSvc.RefundCredit(r.Context(), req, GroupID, MemberID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, credit)
+}
+
+func (s *Server) handleGetMemberLedger(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil || id <= 0{
-		http.Error(w, "invalid id", http.StatusBadRequest)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+	memberID, ok := snowflakeParam(w, r, "memberID", "member_id")
+	if !ok {
 		return
 	}
 
-	bills, err := s.billSvc.GetBillsByGroup(r.Context(), id)
+	ledger, err := s.groupSvc.GetMemberLedger(r.Context(), id, memberID)
 	if err != nil {
-		if errors.Is(err, bill.ErrInvalidGroupID) {
-			http.Error(w, "invalid groupID", http.StatusBadRequest)
-			return
-		}
+		writeServiceError(w, err)
+		return
+	}
 
-		if errors.Is(err, database.ErrNotFound) {
-			http.Error(w, "no bills found", http.StatusNotFound)
-			return
-		}
+	writeJSON(w, http.StatusOK, ledger)
+}
 
-		http.Error(w, "internal error", http.StatusInternalServerError)
+func (s *Server) handleGetBillByGroupID(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	req, ok := parseListBillsRequest(w, r)
+	if !ok {
 		return
 	}
+	req.GroupID = id
+	req.MemberID = r.URL.Query().Get("member")
 
-	writeJSON(w, http.StatusOK, bills)
+	s.writeBillPage(w, r, req)
 }
 
 func (s *Server) handleGetBillsByMemberID(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
+	id, ok := snowflakeParam(w, r, "id", "member_id")
+	if !ok {
+		return
+	}
 
-	bills, err := s.billSvc.GetBillsByMember(r.Context(), id)
-	if err != nil {
-		if errors.Is(err, database.ErrNotFound) {
-			http.Error(w, "no bills found", http.StatusNotFound)
+	req, ok := parseListBillsRequest(w, r)
+	if !ok {
+		return
+	}
+	req.MemberID = id
+
+	if v := r.URL.Query().Get("group"); v != "" {
+		groupID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || groupID <= 0 {
+			badRequest(w, "invalid group")
 			return
 		}
+		req.GroupID = groupID
+	}
+
+	s.writeBillPage(w, r, req)
+}
+
+func (s *Server) handleGetMemberSummary(w http.ResponseWriter, r *http.Request) {
+	id, ok := snowflakeParam(w, r, "id", "member_id")
+	if !ok {
+		return
+	}
 
-		http.Error(w, "internal error", http.StatusInternalServerError)
+	summary, err := s.groupSvc.GetMemberSummary(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, bills)
+	writeJSON(w, http.StatusOK, summary)
 }
 
 func (s *Server) handleSubmitBill(w http.ResponseWriter, r *http.Request) {
@@ -310,22 +395,18 @@ func (s *Server) handleSubmitBill(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		badRequest(w, "invalid id")
 		return
 	}
 
 	// 2) Parse multipart form (for file upload)
-	if err := r.ParseMultipartForm(20 << 20); err != nil { // 20 MB
-		http.Error(w, "invalid form data: "+err.Error(), http.StatusBadRequest)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		formError(w, err)
 		return
 	}
 
-	// 3) member_id (required)
+	// 3) member_id, checked by the service
 	memberID := r.FormValue("member_id")
-	if memberID == "" {
-		http.Error(w, "member_id is required", http.StatusBadRequest)
-		return
-	}
 
 	// 4) amount_paid (optional)
 	var amountPaid float64
@@ -333,7 +414,7 @@ func (s *Server) handleSubmitBill(w http.ResponseWriter, r *http.Request) {
 	if amountStr != "" {
 		amountPaid, err = strconv.ParseFloat(amountStr, 64)
 		if err != nil {
-			http.Error(w, "invalid amount_paid", http.StatusBadRequest)
+			badRequest(w, "invalid amount_paid")
 			return
 		}
 	}
@@ -341,14 +422,14 @@ func (s *Server) handleSubmitBill(w http.ResponseWriter, r *http.Request) {
 	// 5) Get file
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "file is required", http.StatusBadRequest)
+		badRequest(w, "file is required")
 		return
 	}
 	defer file.Close()
 
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
-		http.Error(w, "could not read file", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "could not read file")
 		return
 	}
 
@@ -362,62 +443,309 @@ func (s *Server) handleSubmitBill(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 7) Call service
-	b, _, err := s.billVerSvc.SubmitBillProof(r.Context(), req)
+	b, verResult, err := s.billVerSvc.SubmitBillProof(r.Context(), req)
 	if err != nil {
-		// you can handle specific errors here (not invited, bill not found, verification failed, etc.)
-		fmt.Println("SubmitBillProof error:", err)
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		// a refused slip is still recorded on the bill; tell the member
+		// where the bill stands and what the slip said
+		if errors.Is(err, billver.ErrVerificationFailed) && b != nil && verResult != nil {
+			slip := *verResult
+			slip.RawResponse = nil
+			writeServiceErrorDetails(w, err, map[string]any{
+				"bill_status": b.Status,
+				"slip":        slip,
+			})
+			return
+		}
+
+		writeServiceError(w, err)
 		return
 	}
 
-	markReq := group.MarkAsPaidRequest{
-		Amount: int64(b.AmountPaid),
+	// 8) Return updated bill as JSON
+	writeJSON(w, http.StatusOK, b)
+}
+
+func (s *Server) handleGetBillPayments(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
 	}
-	_, err = s.groupSvc.MarkMemberPaid(r.Context(), markReq, b.GroupID, b.MemberID)
+
+	payments, err := s.billSvc.GetPaymentsByBill(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, group.ErrInvalidGroupID) || errors.Is(err, group.ErrNoUserID) {
-			http.Error(w, "invalid group_id or user_id", http.StatusBadRequest)
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payments)
+}
+
+func (s *Server) handleRecordManualPayment(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	var req group.RecordPaymentRequest
+
+	// multipart when a receipt/photo is attached, plain JSON otherwise
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+			formError(w, err)
 			return
 		}
 
-		if errors.Is(err, group.ErrMemberNotFound) || errors.Is(err, group.ErrNotActiveMember) {
-			http.Error(w, "member not found in group", http.StatusNotFound)
+		if err := validate.DecodeForm(r.MultipartForm.Value, &req); err != nil {
+			writeServiceError(w, err)
 			return
 		}
 
-		if errors.Is(err, group.ErrAlreadyPaid) {
-			http.Error(w, "member already paid", http.StatusBadRequest)
-			return
+		file, header, err := r.FormFile("file")
+		if err == nil {
+			defer file.Close()
+
+			req.Attachment, err = io.ReadAll(file)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, CodeInternal, "could not read file")
+				return
+			}
+			req.AttachmentName = header.Filename
+			req.AttachmentType = header.Header.Get("Content-Type")
 		}
+	} else if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
+		return
+	}
 
-		http.Error(w, "internal error", http.StatusInternalServerError)
+	b, payment, err := s.groupSvc.RecordManualPayment(r.Context(), req, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, group.RecordPaymentResult{Bill: b, Payment: payment})
+}
+
+func (s *Server) handleGetPaymentAttachment(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	paymentIDStr := chi.URLParam(r, "paymentID")
+	paymentID, err := strconv.ParseInt(paymentIDStr, 10, 64)
+	if err != nil || paymentID <= 0 {
+		badRequest(w, "invalid payment id")
+		return
+	}
+
+	p, err := s.billSvc.GetPaymentAttachment(r.Context(), id, paymentID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	contentType := p.AttachmentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", p.AttachmentName))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(p.Attachment)
+}
+
+func (s *Server) handleTransitionBill(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	var req bill.TransitionBillRequest
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	b, err := s.billSvc.TransitionBill(r.Context(), req, id)
+	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
-	// 8) Return updated bill as JSON
 	writeJSON(w, http.StatusOK, b)
 }
 
+func (s *Server) handleGetBillHistory(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	events, err := s.billSvc.GetBillHistory(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (s *Server) handleCancelBill(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	var req group.BillActionRequest
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	b, err := s.groupSvc.CancelBill(r.Context(), req, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, b)
+}
+
+func (s *Server) handleWaiveBill(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	var req group.BillActionRequest
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	b, err := s.groupSvc.WaiveBill(r.Context(), req, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, b)
+}
+
+func (s *Server) handleAmendBill(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	var req group.AmendBillRequest
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	b, err := s.groupSvc.AmendBill(r.Context(), req, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, b)
+}
+
+const (
+	maxBodySize   = 1 << 20  // 1 MB, any JSON request
+	maxUploadSize = 20 << 20 // 20 MB, a slip or receipt with its fields
+)
+
+// bodyLimits are the routes that take more than maxBodySize.
+var bodyLimits = map[string]int64{
+	"/groups/import":               maxGroupImportSize,
+	"/groups/{id}/import/payments": maxImportSize,
+	"/bill/{id}/pay":               maxUploadSize,
+	"/bill/{id}/manual-payment":    maxUploadSize,
+}
+
+// limitBody caps every request body at the limit of the route it goes to,
+// before anything reads it.
+func (s *Server) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, s.bodyLimit(r))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bodyLimit is the largest body the route r matches accepts.
+func (s *Server) bodyLimit(r *http.Request) int64 {
+	rctx := chi.NewRouteContext()
+	if s.router.Match(rctx, r.Method, r.URL.Path) {
+		if limit, ok := bodyLimits[rctx.RoutePattern()]; ok {
+			return limit
+		}
+	}
+	return maxBodySize
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
 
 func (s *Server) routes() {
 	s.router.Get("/health", s.handleHealth)
+	s.router.Get("/openapi.json", s.handleOpenAPI)
 
 	s.router.Route("/groups", func(r chi.Router) {
-		r.Post("/", s.handleCreateGroup)   // POST /groups
-		r.Get("/{id}", s.handleGetGroup)   // GET /groups/1
+		r.Post("/", s.handleCreateGroup) // POST /groups
+		r.Post("/import", s.handleImportGroup)
+		r.Get("/{id}", s.handleGetGroup) // GET /groups/1
 		r.Delete("/{id}", s.handleDeleteGroup)
 		r.Put("/{id}", s.handleUpdateGroup)
+		r.Patch("/{id}", s.handlePatchGroup)
 		r.Post("/{id}/invite", s.handleInviteGroup)
 		r.Post("/{id}/accept-invite", s.handleAcceptInvite)
 		r.Post("/{GroupID}/member/{MemberID}/pay", s.handleMarkAsPaid)
+		r.Get("/{GroupID}/member/{MemberID}/credit", s.handleGetMemberCredit)
+		r.Post("/{GroupID}/member/{MemberID}/credit/refund", s.handleRefundCredit)
 		r.Get("/{id}/bill", s.handleGetBillByGroupID)
+		r.Get("/{id}/members/{memberID}/ledger", s.handleGetMemberLedger)
+		r.Get("/{id}/report", s.handleGetGroupReport)
+		r.Get("/{id}/audit", s.handleGetAuditLog)
+		r.Get("/{id}/export", s.handleExportGroup)
+		r.Get("/{id}/export/bills", s.handleExportGroupBills)
+		r.Get("/{id}/export/ledger", s.handleExportGroupLedger)
+		r.Post("/{id}/import/payments", s.handleImportPayments)
+		r.Post("/{id}/expenses", s.handleCreateExpense)
+		r.Get("/{id}/expenses", s.handleGetExpenses)
 	})
 
 	s.router.Route("/member", func(r chi.Router) {
 		r.Get("/{id}/bill", s.handleGetBillsByMemberID)
+		r.Get("/{id}/groups", s.handleGetMemberGroups)
+		r.Get("/{id}/summary", s.handleGetMemberSummary)
+	})
+
+	s.router.Route("/guilds", func(r chi.Router) {
+		r.Get("/{guildID}/groups", s.handleGetGuildGroups)
+		r.Get("/{guildID}/settle-up", s.handleSettleUp)
+		r.Post("/{guildID}/settlements", s.handleRecordSettlement)
+		r.Get("/{guildID}/settlements", s.handleGetSettlements)
 	})
 
 	s.router.Route("/test", func(r chi.Router) {
@@ -426,24 +754,44 @@ func (s *Server) routes() {
 
 	s.router.Route("/bill", func(r chi.Router) {
 		r.Post("/{id}/pay", s.handleSubmitBill)
+		r.Get("/{id}/payments", s.handleGetBillPayments)
+		r.Get("/{id}/payments/{paymentID}/attachment", s.handleGetPaymentAttachment)
+		r.Post("/{id}/manual-payment", s.handleRecordManualPayment)
+		r.Post("/{id}/transition", s.handleTransitionBill)
+		r.Get("/{id}/history", s.handleGetBillHistory)
+		r.Post("/{id}/cancel", s.handleCancelBill)
+		r.Post("/{id}/waive", s.handleWaiveBill)
+		r.Patch("/{id}", s.handleAmendBill)
 	})
 }
 
-func NewServer(groupSvc *group.Service, billSvc *bill.Service, billVerSvc *billver.Service) *Server {
+func NewServer(groupSvc *group.Service, billSvc *bill.Service, billVerSvc *billver.Service, expenseSvc *expense.Service, settlementSvc *settlement.Service, portableSvc *portable.Service) *Server {
 	r := chi.NewRouter()
 
+	r.Use(middleware.RequestID)
+	r.Use(auditContext)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	s := &Server{
-		router:   r,
-		groupSvc: groupSvc,
-		billSvc: billSvc,
-		billVerSvc: billVerSvc,
-	}
+		router:        r,
+		groupSvc:      groupSvc,
+		billSvc:       billSvc,
+		billVerSvc:    billVerSvc,
+		expenseSvc:    expenseSvc,
+		settlementSvc: settlementSvc,
+		portableSvc:   portableSvc,
+	}
+	r.Use(s.limitBody)
+	r.Use(s.idempotent)
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "no such route")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+	})
 
 	s.routes()
 	return s
 }
-
-
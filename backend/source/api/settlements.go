@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/NoNiiEa/subShare-Discord/source/settlement"
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+)
+
+func (s *Server) handleSettleUp(w http.ResponseWriter, r *http.Request) {
+	guildID, ok := snowflakeParam(w, r, "guildID", "guild_id")
+	if !ok {
+		return
+	}
+
+	plan, err := s.settlementSvc.SettleUp(r.Context(), guildID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, plan)
+}
+
+func (s *Server) handleRecordSettlement(w http.ResponseWriter, r *http.Request) {
+	guildID, ok := snowflakeParam(w, r, "guildID", "guild_id")
+	if !ok {
+		return
+	}
+
+	var req settlement.RecordSettlementRequest
+	if err := validate.DecodeJSON(r.Body, &req); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	st, err := s.settlementSvc.RecordSettlement(r.Context(), req, guildID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, st)
+}
+
+func (s *Server) handleGetSettlements(w http.ResponseWriter, r *http.Request) {
+	guildID, ok := snowflakeParam(w, r, "guildID", "guild_id")
+	if !ok {
+		return
+	}
+
+	settlements, err := s.settlementSvc.GetSettlements(r.Context(), guildID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settlements)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// fieldErrors sends r to a server without services, which every request here
+// must be refused before reaching, and returns the fields of the validation
+// envelope it answers with.
+func fieldErrors(t *testing.T, r *http.Request) []string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	NewServer(nil, nil, nil, nil, nil, nil).ServeHTTP(w, r)
+
+	var resp struct {
+		Code    string `json:"code"`
+		Details struct {
+			Fields []struct {
+				Field string `json:"field"`
+			} `json:"fields"`
+		} `json:"details"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusBadRequest || resp.Code != "validation_failed" {
+		t.Fatalf("got %d %s, want 400 validation_failed", w.Code, resp.Code)
+	}
+
+	var fields []string
+	for _, f := range resp.Details.Fields {
+		fields = append(fields, f.Field)
+	}
+	return fields
+}
+
+func TestPathIDsAreValidated(t *testing.T) {
+	tests := []struct {
+		method, path string
+		field        string
+	}{
+		{"GET", "/member/42/bill", "member_id"},
+		{"GET", "/member/abc/summary", "member_id"},
+		{"GET", "/member/42/groups", "member_id"},
+		{"GET", "/guilds/guild/groups", "guild_id"},
+		{"GET", "/guilds/42/settle-up", "guild_id"},
+		{"POST", "/guilds/42/settlements", "guild_id"},
+		{"GET", "/guilds/42/settlements", "guild_id"},
+		{"POST", "/groups/1/member/42/pay", "member_id"},
+		{"GET", "/groups/1/member/42/credit", "member_id"},
+		{"POST", "/groups/1/member/42/credit/refund", "member_id"},
+		{"GET", "/groups/1/members/42/ledger", "member_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, bytes.NewReader([]byte("{}")))
+			if fields := fieldErrors(t, r); !slices.Equal(fields, []string{tt.field}) {
+				t.Errorf("fields = %v, want [%s]", fields, tt.field)
+			}
+		})
+	}
+}
+
+func TestManualPaymentForm(t *testing.T) {
+	owner := formPart{"owner_id", "", "100000000000000001"}
+	method := formPart{"method", "", "CASH"}
+
+	tests := []struct {
+		name  string
+		parts []formPart
+		want  []string
+	}{
+		{"amount is not a number", []formPart{owner, method, {"amount", "", "ten"}}, []string{"amount"}},
+		{"paid_at is not a time", []formPart{owner, method, {"amount", "", "10"}, {"paid_at", "", "yesterday"}}, []string{"paid_at"}},
+		{"unknown fields", []formPart{owner, method, {"amount", "", "10"}, {"bill_id", "", "2"}, {"member", "", "x"}}, []string{"bill_id", "member"}},
+		{"all problems at once", []formPart{owner, {"amount", "", "ten"}, {"paid_at", "", "yesterday"}, {"tip", "", "1"}}, []string{"amount", "paid_at", "tip"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartRequest(t, "boundary", append(tt.parts, formPart{"file", "receipt.jpg", "receipt"})...)
+			r := httptest.NewRequest("POST", "/bill/1/manual-payment", bytes.NewReader(body))
+			r.Header.Set("Content-Type", contentType)
+
+			if fields := fieldErrors(t, r); !slices.Equal(fields, tt.want) {
+				t.Errorf("fields = %v, want %v", fields, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package audit
+
+import "errors"
+
+var (
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidLimit     = errors.New("limit must be between 1 and 200")
+	ErrInvalidRetention = errors.New("retention must be positive")
+)
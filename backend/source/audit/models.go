@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Action string
+
+const (
+	ActionGroupCreate    Action = "group.create"
+	ActionGroupUpdate    Action = "group.update"
+	ActionGroupDelete    Action = "group.delete"
+	ActionGroupImport    Action = "group.import"
+	ActionMemberInvite   Action = "member.invite"
+	ActionMemberAccept   Action = "member.accept_invite"
+	ActionMemberMarkPaid Action = "member.mark_paid"
+	ActionCreditRefund   Action = "member.refund_credit"
+	ActionBillsIssue     Action = "bills.issue"
+	ActionBillsImport    Action = "bills.import"
+	ActionBillTransition Action = "bill.transition"
+	ActionBillCancel     Action = "bill.cancel"
+	ActionBillWaive      Action = "bill.waive"
+	ActionBillAmend      Action = "bill.amend"
+	ActionSlipSubmit     Action = "bill.submit_slip"
+	ActionManualPayment  Action = "bill.manual_payment"
+	ActionExpenseCreate  Action = "expense.create"
+	ActionSettlement     Action = "settlement.record"
+)
+
+// entity types
+const (
+	EntityGroup      = "group"
+	EntityMember     = "member"
+	EntityBill       = "bill"
+	EntityExpense    = "expense"
+	EntitySettlement = "settlement"
+)
+
+// ActorUnknown is recorded when neither the request nor the context names
+// who acted.
+const ActorUnknown = "unknown"
+
+// Event is one state-changing action. Changes maps each changed field to its
+// value before and after, see Diff.
+type Event struct {
+	ID         int64           `json:"id"`
+	GroupID    int64           `json:"group_id,omitempty"`
+	ActorID    string          `json:"actor_id"`
+	Action     Action          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Changes    json.RawMessage `json:"changes,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Entry is what a service hands to Record. Before and After are any JSON
+// encodable values, nil for creations and deletions.
+type Entry struct {
+	GroupID    int64
+	ActorID    string // falls back to the actor in the context
+	Action     Action
+	EntityType string
+	EntityID   string
+	Before     any
+	After      any
+}
+
+// FieldChange is one entry of Event.Changes.
+type FieldChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+type ListRequest struct {
+	GroupID int64
+	Action  Action
+	Cursor  string
+	Limit   int
+}
+
+// Query is a validated ListRequest; the store returns up to Limit+1 events,
+// newest first, with IDs below BeforeID when it is set.
+type Query struct {
+	GroupID  int64
+	Action   Action
+	BeforeID int64
+	Limit    int
+}
+
+type Page struct {
+	Events     []Event `json:"events"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
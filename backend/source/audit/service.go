@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+type Store interface {
+	NextAuditEventID(ctx context.Context) (int64, error)
+	SaveAuditEvent(ctx context.Context, e Event) error
+	ListAuditEvents(ctx context.Context, q Query) ([]Event, error)
+	DeleteAuditEventsBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type Service struct {
+	store Store
+}
+
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// Record writes an audit event. A nil *Service records nothing, so services
+// work without an audit log configured.
+func (s *Service) Record(ctx context.Context, e Entry) error {
+	if s == nil {
+		return nil
+	}
+
+	changes, err := Diff(e.Before, e.After)
+	if err != nil {
+		return err
+	}
+
+	actorID := e.ActorID
+	if actorID == "" {
+		actorID = Actor(ctx)
+	}
+	if actorID == "" {
+		actorID = ActorUnknown
+	}
+
+	id, err := s.store.NextAuditEventID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.store.SaveAuditEvent(ctx, Event{
+		ID:         id,
+		GroupID:    e.GroupID,
+		ActorID:    actorID,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		Changes:    changes,
+		RequestID:  RequestID(ctx),
+		CreatedAt:  time.Now().UTC(),
+	})
+}
+
+func (s *Service) List(ctx context.Context, req ListRequest) (*Page, error) {
+	q := Query{GroupID: req.GroupID, Action: req.Action, Limit: req.Limit}
+
+	if q.Limit == 0 {
+		q.Limit = defaultLimit
+	}
+	if q.Limit < 0 || q.Limit > maxLimit {
+		return nil, ErrInvalidLimit
+	}
+
+	if req.Cursor != "" {
+		id, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		q.BeforeID = id
+	}
+
+	events, err := s.store.ListAuditEvents(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Events: []Event{}}
+	if len(events) > q.Limit {
+		events = events[:q.Limit]
+		page.NextCursor = encodeCursor(events[len(events)-1].ID)
+	}
+	page.Events = append(page.Events, events...)
+
+	return page, nil
+}
+
+// Prune deletes events older than retention and returns how many went.
+func (s *Service) Prune(ctx context.Context, retention time.Duration, now time.Time) (int64, error) {
+	if retention <= 0 {
+		return 0, ErrInvalidRetention
+	}
+	return s.store.DeleteAuditEventsBefore(ctx, now.Add(-retention))
+}
+
+// Diff compares the JSON encodings of before and after field by field and
+// returns the fields that differ, as a JSON object of FieldChange. It returns
+// nil when nothing changed. Values that don't encode to JSON objects are
+// compared as a whole under the "value" key.
+func Diff(before, after any) (json.RawMessage, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	for k := range b {
+		keys[k] = true
+	}
+	for k := range a {
+		keys[k] = true
+	}
+
+	changes := map[string]FieldChange{}
+	for k := range keys {
+		if !bytes.Equal(b[k], a[k]) {
+			changes[k] = FieldChange{Before: b[k], After: a[k]}
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(changes)
+}
+
+func fields(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal(data, &out); err != nil {
+		return map[string]json.RawMessage{"value": data}, nil
+	}
+	return out, nil
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(s string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(data), 10, 64)
+}
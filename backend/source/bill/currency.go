@@ -0,0 +1,45 @@
+package bill
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
+
+// SnapshotRate fixes the exchange rate between the bill currency and the
+// settlement currency at issue time, so later rate changes don't move what the
+// member owes.
+func (b *Bill) SnapshotRate(ctx context.Context, rates currency.RateProvider, now time.Time) error {
+	rate, err := currency.Lookup(ctx, rates, b.Currency, currency.Settlement, now)
+	if err != nil {
+		return err
+	}
+
+	asOf := rate.AsOf
+	b.SettlementCurrency = currency.Settlement
+	b.ExchangeRate = rate.Value
+	// slips are paid to the satang
+	b.SettlementAmountDue = math.Round(b.AmountDue*rate.Value*100) / 100
+	b.RateSource = rate.Source
+	b.RateAsOf = &asOf
+
+	return nil
+}
+
+// ToBillCurrency converts an amount paid in the bill currency or in its
+// settlement currency into the bill currency using the snapshot rate.
+func (b *Bill) ToBillCurrency(amount float64, cur string) (float64, error) {
+	switch {
+	case cur == b.Currency:
+		return amount, nil
+	case cur == b.SettlementCurrency && b.ExchangeRate > 0:
+		if b.SettlementAmountDue > 0 && math.Abs(amount-b.SettlementAmountDue) < amountEpsilon {
+			// paying exactly the quoted amount settles the bill despite rounding
+			return b.AmountDue, nil
+		}
+		return amount / b.ExchangeRate, nil
+	}
+	return 0, ErrCurrencyMismatch
+}
@@ -3,19 +3,37 @@ package bill
 import "errors"
 
 var (
-	ErrInvalidGroupID  = errors.New("invalid group_id")
-	ErrInvalidMemberID = errors.New("invalid member_id")
-	ErrInvalidBillID = errors.New("invalid bill_id")
-	ErrInvalidYear     = errors.New("invalid year")
-	ErrInvalidMonth    = errors.New("invalid month")
-	ErrInvalidAmount   = errors.New("amount_due must be > 0")
-	ErrInvalidCurrency = errors.New("currency is required")
+	ErrInvalidGroupID   = errors.New("invalid group_id")
+	ErrInvalidMemberID  = errors.New("invalid member_id")
+	ErrInvalidBillID    = errors.New("invalid bill_id")
+	ErrInvalidYear      = errors.New("invalid year")
+	ErrInvalidMonth     = errors.New("invalid month")
+	ErrInvalidAmount    = errors.New("amount_due must be > 0")
+	ErrInvalidCurrency  = errors.New("currency is required")
+	ErrCurrencyMismatch = errors.New("payment currency does not match the bill")
+	ErrInvalidExpenseID = errors.New("expense bills need an expense_id")
 )
 
 var (
-	ErrSlipTooSmall = errors.New("slip too small")
-	ErrBillNotFound = errors.New("bill not found")
-	ErrBillMemberMismatch = errors.New("bill and member mismatch")
+	ErrSlipTooSmall        = errors.New("slip too small")
+	ErrBillNotFound        = errors.New("bill not found")
+	ErrBillMemberMismatch  = errors.New("bill and member mismatch")
 	ErrBillAlreadyVerified = errors.New("bill is already verified")
-	ErrVerificationFailed = errors.New("slip is not valid")
-)
\ No newline at end of file
+	ErrVerificationFailed  = errors.New("slip is not valid")
+	ErrAttachmentNotFound  = errors.New("payment has no attachment")
+	ErrIllegalTransition   = errors.New("illegal bill status transition")
+	ErrInvalidStatus       = errors.New("invalid bill status")
+	ErrStatusNotSettable   = errors.New("status can't be set directly; pay, cancel or waive the bill instead")
+	ErrInvalidActorID      = errors.New("actor_id is required")
+	ErrNotGroupOwner       = errors.New("only the group owner can change this bill")
+)
+var (
+	ErrInvalidKind   = errors.New("kind must be recurring or expense")
+	ErrInvalidPeriod = errors.New("from/to must be YYYY-MM and from must not be after to")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("limit must be between 1 and 200")
+)
+var (
+	ErrEmptyImport       = errors.New("the file has no rows to import")
+	ErrTooManyImportRows = errors.New("the file has too many rows, import at most 5000 at a time")
+)
@@ -0,0 +1,483 @@
+package bill
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
+
+const maxImportRows = 5000
+
+// import CSV columns; the header row names them, in any order
+const (
+	importColMemberID  = "member_id"
+	importColPeriod    = "period" // "YYYY-MM"
+	importColAmount    = "amount"
+	importColCurrency  = "currency"
+	importColPaidAt    = "paid_at" // RFC 3339 or YYYY-MM-DD
+	importColMethod    = "method"
+	importColReference = "reference"
+	importColNote      = "note"
+)
+
+var importRequired = []string{importColMemberID, importColPeriod, importColAmount}
+
+var importColumns = []string{
+	importColMemberID, importColPeriod, importColAmount, importColCurrency,
+	importColPaidAt, importColMethod, importColReference, importColNote,
+}
+
+// ImportPaymentsRequest carries a CSV of payments made before the group was
+// tracked here, one payment per row. Currency and MemberIDs come from the
+// group: rows in another currency, or for someone who is not a member, are
+// rejected.
+type ImportPaymentsRequest struct {
+	GroupID   int64
+	ActorID   string
+	Currency  string
+	MemberIDs []string
+	DryRun    bool
+	CSV       io.Reader
+}
+
+// ImportRowError points at a problem in the file. Row is the line number,
+// the header being row 1.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportResult struct {
+	DryRun   bool             `json:"dry_run"`
+	Rows     int              `json:"rows"`
+	Payments int              `json:"payments"`
+	Bills    []Bill           `json:"bills"` // created, or that would be on a dry run (without IDs)
+	Errors   []ImportRowError `json:"errors"`
+}
+
+type importRow struct {
+	line      int
+	memberID  string
+	year      int
+	month     int
+	amount    float64
+	paidAt    *time.Time
+	method    string
+	reference string
+	note      string
+}
+
+// importBill is the bill one member's rows for one period turn into.
+type importBill struct {
+	bill     Bill
+	payments []importRow
+}
+
+// ImportPayments turns historical payments into verified bills: the rows of a
+// member for a period become one bill whose amount due is what they paid.
+// Nothing is imported unless every row is valid; on a dry run nothing is
+// imported at all. Imported bills are paid in full, so they don't move the
+// members' balances.
+func (s *Service) ImportPayments(ctx context.Context, req ImportPaymentsRequest) (*ImportResult, error) {
+	if req.GroupID <= 0 {
+		return nil, ErrInvalidGroupID
+	}
+	if req.ActorID == "" {
+		return nil, ErrInvalidActorID
+	}
+
+	ownerID, err := s.store.GetGroupOwnerID(ctx, req.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID != req.ActorID {
+		return nil, ErrNotGroupOwner
+	}
+
+	groupCurrency, err := currency.Normalize(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{DryRun: req.DryRun, Bills: []Bill{}, Errors: []ImportRowError{}}
+
+	rows, rowErrs, err := parseImportCSV(req.CSV, groupCurrency, req.MemberIDs)
+	if err != nil {
+		return nil, err
+	}
+	result.Rows = len(rows) + countRows(rowErrs)
+	result.Errors = append(result.Errors, rowErrs...)
+
+	bills, err := s.groupImportRows(ctx, req.GroupID, ownerID, groupCurrency, rows, result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Errors) > 0 {
+		sort.SliceStable(result.Errors, func(i, j int) bool {
+			return result.Errors[i].Row < result.Errors[j].Row
+		})
+		return result, nil
+	}
+
+	for _, ib := range bills {
+		b := ib.bill
+		if !req.DryRun {
+			saved, err := s.saveImportedBill(ctx, ib, req.ActorID)
+			if err != nil {
+				return nil, err
+			}
+			b = *saved
+		}
+		result.Bills = append(result.Bills, b)
+		result.Payments += len(ib.payments)
+	}
+
+	if !req.DryRun {
+		if err := s.audit.Record(ctx, audit.Entry{
+			GroupID:    req.GroupID,
+			ActorID:    req.ActorID,
+			Action:     audit.ActionBillsImport,
+			EntityType: audit.EntityGroup,
+			EntityID:   strconv.FormatInt(req.GroupID, 10),
+			After:      map[string]any{"rows": result.Rows, "bills": len(result.Bills), "payments": result.Payments},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// countRows counts the distinct rows that have errors.
+func countRows(errs []ImportRowError) int {
+	seen := map[int]bool{}
+	for _, e := range errs {
+		if e.Row > 1 {
+			seen[e.Row] = true
+		}
+	}
+	return len(seen)
+}
+
+func parseImportCSV(r io.Reader, groupCurrency string, memberIDs []string) ([]importRow, []ImportRowError, error) {
+	if r == nil {
+		return nil, nil, ErrEmptyImport
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, ErrEmptyImport
+	}
+	if err != nil {
+		return nil, []ImportRowError{{Row: 1, Message: err.Error()}}, nil
+	}
+
+	var errs []ImportRowError
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !isImportColumn(name) {
+			errs = append(errs, ImportRowError{Row: 1, Column: name, Message: "unknown column"})
+			continue
+		}
+		if _, dup := index[name]; dup {
+			errs = append(errs, ImportRowError{Row: 1, Column: name, Message: "column is listed twice"})
+			continue
+		}
+		index[name] = i
+	}
+	for _, name := range importRequired {
+		if _, ok := index[name]; !ok {
+			errs = append(errs, ImportRowError{Row: 1, Column: name, Message: "required column is missing"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	members := map[string]bool{}
+	for _, id := range memberIDs {
+		members[id] = true
+	}
+
+	var (
+		rows       []importRow
+		references = map[string]int{}
+		line       = 1
+	)
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if line-1 > maxImportRows {
+			return nil, nil, ErrTooManyImportRows
+		}
+		if err != nil {
+			errs = append(errs, ImportRowError{Row: line, Message: err.Error()})
+			continue
+		}
+
+		cell := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		fail := func(column, format string, args ...any) {
+			errs = append(errs, ImportRowError{Row: line, Column: column, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if blankRecord(record) {
+			continue
+		}
+
+		row := importRow{
+			line:      line,
+			memberID:  cell(importColMemberID),
+			method:    cell(importColMethod),
+			reference: cell(importColReference),
+			note:      cell(importColNote),
+		}
+		before := len(errs)
+
+		switch {
+		case row.memberID == "":
+			fail(importColMemberID, "member_id is required")
+		case len(members) > 0 && !members[row.memberID]:
+			fail(importColMemberID, "%s is not a member of the group", row.memberID)
+		}
+
+		if period, err := ParsePeriod(cell(importColPeriod)); err != nil || period == 0 {
+			fail(importColPeriod, "period must be YYYY-MM")
+		} else {
+			row.year, row.month = period/100, period%100
+		}
+
+		amount, err := strconv.ParseFloat(cell(importColAmount), 64)
+		if err != nil || amount <= 0 {
+			fail(importColAmount, "amount must be a number > 0")
+		}
+		row.amount = amount
+
+		if c := cell(importColCurrency); c != "" {
+			code, err := currency.Normalize(c)
+			switch {
+			case err != nil:
+				fail(importColCurrency, "%s", err.Error())
+			case code != groupCurrency:
+				fail(importColCurrency, "currency must be %s, the group currency", groupCurrency)
+			}
+		}
+
+		if v := cell(importColPaidAt); v != "" {
+			paidAt, err := parseImportTime(v)
+			if err != nil {
+				fail(importColPaidAt, "paid_at must be RFC 3339 or YYYY-MM-DD")
+			} else {
+				row.paidAt = &paidAt
+			}
+		}
+
+		if row.reference != "" {
+			if first, dup := references[row.reference]; dup {
+				fail(importColReference, "reference is already used on row %d", first)
+			} else {
+				references[row.reference] = line
+			}
+		}
+
+		if len(errs) == before {
+			rows = append(rows, row)
+		}
+	}
+
+	if len(rows) == 0 && len(errs) == 0 {
+		return nil, nil, ErrEmptyImport
+	}
+
+	return rows, errs, nil
+}
+
+func isImportColumn(name string) bool {
+	for _, c := range importColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func blankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseImportTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// groupImportRows builds one bill per member and period, in file order, and
+// reports rows for periods the member already has a bill for.
+func (s *Service) groupImportRows(ctx context.Context, groupID int64, ownerID, cur string, rows []importRow, result *ImportResult) ([]*importBill, error) {
+	var (
+		bills  []*importBill
+		byKey  = map[string]*importBill{}
+		exists = map[string]bool{}
+	)
+
+	for _, row := range rows {
+		key := fmt.Sprintf("%s/%04d-%02d", row.memberID, row.year, row.month)
+
+		taken, checked := exists[key]
+		if !checked {
+			period := row.year*100 + row.month
+			existing, err := s.store.ListBills(ctx, BillQuery{
+				GroupID:    groupID,
+				MemberID:   row.memberID,
+				Kind:       BillKindRecurring,
+				FromPeriod: period,
+				ToPeriod:   period,
+				Limit:      1,
+			})
+			if err != nil {
+				return nil, err
+			}
+			taken = len(existing) > 0
+			exists[key] = taken
+		}
+		if taken {
+			result.Errors = append(result.Errors, ImportRowError{
+				Row:     row.line,
+				Column:  importColPeriod,
+				Message: "the member already has a bill for this period",
+			})
+			continue
+		}
+
+		ib := byKey[key]
+		if ib == nil {
+			createdAt := time.Date(row.year, time.Month(row.month), 1, 0, 0, 0, 0, time.UTC)
+			ib = &importBill{bill: Bill{
+				Kind:        BillKindRecurring,
+				PayeeID:     ownerID,
+				GroupID:     groupID,
+				MemberID:    row.memberID,
+				Year:        row.year,
+				Month:       row.month,
+				Currency:    cur,
+				Status:      BillStatusPending,
+				Description: "imported",
+				CreatedAt:   createdAt,
+				UpdatedAt:   createdAt,
+			}}
+			byKey[key] = ib
+			bills = append(bills, ib)
+		}
+
+		ib.payments = append(ib.payments, row)
+		ib.bill.AmountDue += row.amount
+		ib.bill.AmountPaid += row.amount
+		if row.paidAt != nil && (ib.bill.PaidAt == nil || row.paidAt.After(*ib.bill.PaidAt)) {
+			paidAt := *row.paidAt
+			ib.bill.PaidAt = &paidAt
+		}
+	}
+
+	for _, ib := range bills {
+		ib.bill.Status = BillStatusVerified
+		ib.bill.VerifiedAt = ib.bill.PaidAt
+	}
+
+	return bills, nil
+}
+
+func (s *Service) saveImportedBill(ctx context.Context, ib *importBill, actorID string) (*Bill, error) {
+	now := time.Now().UTC()
+
+	id, err := s.store.NextBillID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b := ib.bill
+	b.ID = id
+	b.Status = BillStatusPending
+	b.AmountPaid = 0
+	b.PaidAt = nil
+	b.VerifiedAt = nil
+
+	if err := b.SnapshotRate(ctx, s.rates, now); err != nil {
+		return nil, err
+	}
+	if err := s.store.SaveBill(ctx, b); err != nil {
+		return nil, err
+	}
+	if err := Issued(ctx, s.store, &b, actorID); err != nil {
+		return nil, err
+	}
+
+	for _, row := range ib.payments {
+		paymentID, err := s.store.NextPaymentID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		p := Payment{
+			ID:         paymentID,
+			BillID:     b.ID,
+			GroupID:    b.GroupID,
+			MemberID:   b.MemberID,
+			Amount:     row.amount,
+			Currency:   b.Currency,
+			Source:     PaymentSourceImport,
+			Status:     PaymentStatusAccepted,
+			Reference:  row.reference,
+			Method:     row.method,
+			Note:       row.note,
+			RecordedBy: actorID,
+			PaidAt:     row.paidAt,
+			CreatedAt:  now,
+		}
+		if err := s.store.SavePayment(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+
+	payments, err := s.store.GetPaymentsByBillID(ctx, b.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep the verification date historical
+	b.VerifiedAt = ib.bill.VerifiedAt
+	if err := Settle(ctx, s.store, &b, payments, actorID, "imported", now); err != nil {
+		return nil, err
+	}
+
+	return s.store.UpdateBill(ctx, b)
+}
@@ -0,0 +1,82 @@
+package bill
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	const (
+		a = "100000000000000002"
+		b = "100000000000000003"
+	)
+	members := []string{a, b}
+
+	type cell struct {
+		row    int
+		column string
+	}
+
+	tests := []struct {
+		name     string
+		csv      string
+		wantRows int
+		wantErrs []cell
+		wantErr  error
+	}{
+		{
+			name:     "columns in any order, with a BOM and blank lines",
+			csv:      "\ufeffAmount, member_id, period,paid_at\n150," + a + ",2024-01,2024-01-05\n\n200.50," + b + ",2024-02,2024-02-03T10:00:00+07:00\n",
+			wantRows: 2,
+		},
+		{
+			name:     "header problems stop the import",
+			csv:      "member_id,period,amount,tip,amount\n" + a + ",2024-01,150,1,150\n",
+			wantErrs: []cell{{1, "tip"}, {1, "amount"}},
+		},
+		{
+			name:     "a required column is missing",
+			csv:      "member_id,amount\n" + a + ",150\n",
+			wantErrs: []cell{{1, "period"}},
+		},
+		{
+			name: "every bad cell is reported, the good rows kept",
+			csv: "member_id,period,amount,currency,paid_at\n" +
+				a + ",2024-01,150,THB,2024-01-05\n" +
+				"100000000000000009,2024-13,0,USD,yesterday\n" +
+				b + ",2024-01,abc,,\n",
+			wantRows: 1,
+			wantErrs: []cell{{3, "member_id"}, {3, "period"}, {3, "amount"}, {3, "currency"}, {3, "paid_at"}, {4, "amount"}},
+		},
+		{
+			name:     "a reference used twice",
+			csv:      "member_id,period,amount,reference\n" + a + ",2024-01,150,TX1\n" + b + ",2024-01,150,TX1\n",
+			wantRows: 1,
+			wantErrs: []cell{{3, "reference"}},
+		},
+		{name: "nothing but a header", csv: "member_id,period,amount\n\n", wantErr: ErrEmptyImport},
+		{name: "empty file", csv: "", wantErr: ErrEmptyImport},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, errs, err := parseImportCSV(strings.NewReader(tt.csv), "THB", members)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(rows) != tt.wantRows {
+				t.Errorf("%d rows, want %d", len(rows), tt.wantRows)
+			}
+
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("errors = %+v, want %v", errs, tt.wantErrs)
+			}
+			for i, e := range errs {
+				if (cell{e.Row, e.Column}) != tt.wantErrs[i] {
+					t.Errorf("error %d at row %d %s, want %v: %s", i, e.Row, e.Column, tt.wantErrs[i], e.Message)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package bill
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultBillLimit = 50
+	maxBillLimit     = 200
+)
+
+// ListBillsRequest holds the query parameters of the bill listings. From and
+// To are inclusive "YYYY-MM" periods.
+type ListBillsRequest struct {
+	GroupID      int64
+	MemberID     string
+	Statuses     []BillStatus
+	Kind         BillKind
+	From         string
+	To           string
+	Cursor       string
+	Limit        int
+	IncludeProof bool
+}
+
+// BillQuery is a validated ListBillsRequest as handed to the store. Periods
+// are year*100+month; the store returns up to Limit+1 bills, newest first.
+type BillQuery struct {
+	GroupID      int64
+	MemberID     string
+	Statuses     []BillStatus
+	Kind         BillKind
+	FromPeriod   int
+	ToPeriod     int
+	After        *BillCursor
+	Limit        int
+	IncludeProof bool
+}
+
+// BillCursor is the position after the last bill of a page.
+type BillCursor struct {
+	Period int   `json:"p"`
+	ID     int64 `json:"id"`
+}
+
+type BillPage struct {
+	Bills      []Bill `json:"bills"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func (s *Service) ListBills(ctx context.Context, req ListBillsRequest) (*BillPage, error) {
+	q, err := buildBillQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	bills, err := s.store.ListBills(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &BillPage{Bills: []Bill{}}
+	if len(bills) > q.Limit {
+		bills = bills[:q.Limit]
+		last := bills[len(bills)-1]
+		page.NextCursor = encodeBillCursor(BillCursor{Period: last.Year*100 + last.Month, ID: last.ID})
+	}
+	page.Bills = append(page.Bills, bills...)
+
+	return page, nil
+}
+
+func buildBillQuery(req ListBillsRequest) (BillQuery, error) {
+	q := BillQuery{
+		GroupID:      req.GroupID,
+		MemberID:     req.MemberID,
+		Kind:         req.Kind,
+		Limit:        req.Limit,
+		IncludeProof: req.IncludeProof,
+	}
+
+	if q.GroupID == 0 && q.MemberID == "" {
+		return q, ErrInvalidGroupID
+	}
+	if q.GroupID < 0 {
+		return q, ErrInvalidGroupID
+	}
+
+	for _, st := range req.Statuses {
+		if !IsValidStatus(st) {
+			return q, ErrInvalidStatus
+		}
+		q.Statuses = append(q.Statuses, st)
+	}
+
+	switch q.Kind {
+	case "", BillKindRecurring, BillKindExpense:
+	default:
+		return q, ErrInvalidKind
+	}
+
+	var err error
+	if q.FromPeriod, err = ParsePeriod(req.From); err != nil {
+		return q, err
+	}
+	if q.ToPeriod, err = ParsePeriod(req.To); err != nil {
+		return q, err
+	}
+	if q.FromPeriod != 0 && q.ToPeriod != 0 && q.FromPeriod > q.ToPeriod {
+		return q, ErrInvalidPeriod
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultBillLimit
+	}
+	if q.Limit < 0 || q.Limit > maxBillLimit {
+		return q, ErrInvalidLimit
+	}
+
+	if req.Cursor != "" {
+		c, err := decodeBillCursor(req.Cursor)
+		if err != nil {
+			return q, ErrInvalidCursor
+		}
+		q.After = &c
+	}
+
+	return q, nil
+}
+
+// ParseStatuses splits a comma separated status filter.
+func ParseStatuses(s string) []BillStatus {
+	var out []BillStatus
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, BillStatus(part))
+		}
+	}
+	return out
+}
+
+// ParsePeriod turns "YYYY-MM" into year*100+month; empty means unbounded.
+func ParsePeriod(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	var year, month int
+	if _, err := fmt.Sscanf(s, "%4d-%2d", &year, &month); err != nil || len(s) != 7 {
+		return 0, ErrInvalidPeriod
+	}
+	if year < 2000 || year > 3000 || month < 1 || month > 12 {
+		return 0, ErrInvalidPeriod
+	}
+	return year*100 + month, nil
+}
+
+func encodeBillCursor(c BillCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeBillCursor(s string) (BillCursor, error) {
+	var c BillCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
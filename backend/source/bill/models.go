@@ -2,50 +2,127 @@ package bill
 
 import (
 	"time"
-
 )
 
 type BillStatus string
 
 const (
-	BillStatusPending   BillStatus = "pending"   // waiting for user to submit proof
-	BillStatusSubmitted BillStatus = "submitted" // user submitted proof, waiting review
-	BillStatusVerified  BillStatus = "verified"  // owner/admin accepted
-	BillStatusRejected  BillStatus = "rejected"  // owner/admin rejected
-	BillStatusCanceled  BillStatus = "canceled"  // group or user canceled it
+	BillStatusPending       BillStatus = "pending"        // waiting for user to submit proof
+	BillStatusSubmitted     BillStatus = "submitted"      // user submitted proof, waiting review
+	BillStatusPartiallyPaid BillStatus = "partially_paid" // accepted payments don't cover amount_due yet
+	BillStatusVerified      BillStatus = "verified"       // owner/admin accepted
+	BillStatusRejected      BillStatus = "rejected"       // owner/admin rejected
+	BillStatusCanceled      BillStatus = "canceled"       // group or user canceled it
+	BillStatusWaived        BillStatus = "waived"         // owner forgave what was left to pay
+)
+
+type BillKind string
+
+const (
+	BillKindRecurring BillKind = "recurring" // issued by the monthly cycle
+	BillKindExpense   BillKind = "expense"   // a member's share of a one-off expense
 )
 
+// Outstanding is what is left to pay, in the bill currency.
+func (b *Bill) Outstanding() float64 {
+	if rest := b.AmountDue - b.AmountPaid; rest > amountEpsilon {
+		return rest
+	}
+	return 0
+}
+
 type Bill struct {
 	ID int64 `json:"id"`
 
+	Kind      BillKind `json:"kind"`
+	ExpenseID *int64   `json:"expense_id,omitempty"` // set for expense bills
+	PayeeID   string   `json:"payee_id"`             // who the member owes: the owner, or the expense payer
+
 	GroupID  int64  `json:"group_id"`  // links to Group.ID
 	MemberID string `json:"member_id"` // Discord user ID of the member
 
-	Year  int `json:"year"`  
+	Year  int `json:"year"`
 	Month int `json:"month"`
 
-	AmountDue   float64 `json:"amount_due"`   // how much this member should pay
-	AmountPaid  float64 `json:"amount_paid"`  // how much they claimed to pay
-	Currency    string  `json:"currency"`     // "THB", "USD", etc.
-	Status      BillStatus `json:"status"`    // pending/submitted/verified/rejected/...
+	AmountDue  float64 `json:"amount_due"`  // how much this member should pay
+	AmountPaid float64 `json:"amount_paid"` // sum of accepted payments
+	Currency   string  `json:"currency"`    // "THB", "USD", etc.
+
+	// conversion snapshot taken when the bill is issued; slips are compared
+	// against SettlementAmountDue
+	SettlementCurrency  string     `json:"settlement_currency"`
+	SettlementAmountDue float64    `json:"settlement_amount_due"`
+	ExchangeRate        float64    `json:"exchange_rate"` // 1 Currency = ExchangeRate SettlementCurrency
+	RateSource          string     `json:"rate_source,omitempty"`
+	RateAsOf            *time.Time `json:"rate_as_of,omitempty"`
+
+	Status      BillStatus `json:"status"`                // pending/submitted/verified/rejected/...
 	Description string     `json:"description,omitempty"` // optional note like "Netflix March"
 
 	// Proof & verification
-	ProofJSON   string `json:"proof_json"`
+	ProofJSON string `json:"proof_json,omitempty"` // left out of listings unless include=proof
 
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
 	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
 	RejectedAt  *time.Time `json:"rejected_at,omitempty"`
+	PaidAt      *time.Time `json:"paid_at,omitempty"` // transfer time from the verified slip
+}
+
+type PaymentSource string
+type PaymentStatus string
+
+const (
+	PaymentSourceSlip       PaymentSource = "slip"       // verified transfer slip
+	PaymentSourceManual     PaymentSource = "manual"     // recorded by the owner
+	PaymentSourceCredit     PaymentSource = "credit"     // member credit applied when the bill was issued
+	PaymentSourceSettlement PaymentSource = "settlement" // closed by a guild settle-up
+	PaymentSourceImport     PaymentSource = "import"     // historical payment imported from a spreadsheet
+
+	PaymentStatusAccepted PaymentStatus = "accepted"
+	PaymentStatusRejected PaymentStatus = "rejected"
+)
+
+// Payment is one slip or manual payment made against a bill. A bill can have
+// several; only accepted ones count towards Bill.AmountPaid.
+type Payment struct {
+	ID int64 `json:"id"`
+
+	BillID   int64  `json:"bill_id"`
+	GroupID  int64  `json:"group_id"`
+	MemberID string `json:"member_id"`
+
+	Amount           float64       `json:"amount"` // in the bill currency
+	Currency         string        `json:"currency"`
+	OriginalAmount   float64       `json:"original_amount,omitempty"` // as transferred, when converted
+	OriginalCurrency string        `json:"original_currency,omitempty"`
+	Source           PaymentSource `json:"source"`
+	Status           PaymentStatus `json:"status"`
+	Reference        string        `json:"reference,omitempty"` // slip transRef, used to spot reused slips
+	Method           string        `json:"method,omitempty"`    // group.PaymentMethod used, e.g. "BANKAC" or "CASH"
+	Note             string        `json:"note,omitempty"`
+	RecordedBy       string        `json:"recorded_by,omitempty"` // owner who recorded a manual payment
+
+	ProofJSON string `json:"proof_json,omitempty"`
+
+	AttachmentName string `json:"attachment_name,omitempty"`
+	AttachmentType string `json:"attachment_type,omitempty"`
+	Attachment     []byte `json:"-"` // only loaded by GetPaymentAttachment
+
+	PaidAt    *time.Time `json:"paid_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
 type CreateBillRequest struct {
-	GroupID    int64   `json:"group_id"`
-	MemberID   string  `json:"member_id"`
-	Year       int     `json:"year"`
-	Month      int     `json:"month"`
-	AmountDue  float64 `json:"amount_due"`
-	Currency   string  `json:"currency"`
-	Description string `json:"description,omitempty"`
-}
\ No newline at end of file
+	GroupID     int64    `json:"group_id"`
+	MemberID    string   `json:"member_id"`
+	Year        int      `json:"year"`
+	Month       int      `json:"month"`
+	AmountDue   float64  `json:"amount_due"`
+	Currency    string   `json:"currency"`
+	Description string   `json:"description,omitempty"`
+	Kind        BillKind `json:"kind,omitempty"` // defaults to recurring
+	ExpenseID   *int64   `json:"expense_id,omitempty"`
+	PayeeID     string   `json:"payee_id,omitempty"`
+}
@@ -0,0 +1,43 @@
+package bill
+
+import "time"
+
+// amounts closer than this are treated as equal
+const amountEpsilon = 0.001
+
+func AcceptedTotal(payments []Payment) float64 {
+	var total float64
+	for _, p := range payments {
+		if p.Status == PaymentStatusAccepted {
+			total += p.Amount
+		}
+	}
+	return total
+}
+
+// ApplyPayments recomputes AmountPaid and PaidAt from the bill's payments and
+// returns the status they call for: partially_paid or verified, or the current
+// status when nothing was accepted. Use Settle to actually move the bill.
+func (b *Bill) ApplyPayments(payments []Payment, now time.Time) BillStatus {
+	b.AmountPaid = AcceptedTotal(payments)
+	b.UpdatedAt = now
+
+	if b.AmountPaid <= 0 {
+		return b.Status
+	}
+
+	for _, p := range payments {
+		if p.Status != PaymentStatusAccepted || p.PaidAt == nil {
+			continue
+		}
+		if b.PaidAt == nil || p.PaidAt.After(*b.PaidAt) {
+			paidAt := *p.PaidAt
+			b.PaidAt = &paidAt
+		}
+	}
+
+	if b.AmountPaid+amountEpsilon >= b.AmountDue {
+		return BillStatusVerified
+	}
+	return BillStatusPartiallyPaid
+}
@@ -2,50 +2,68 @@ package bill
 
 import (
 	"context"
+	"strconv"
 	"time"
-)
 
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
 
 type Store interface {
 	NextBillID(ctx context.Context) (int64, error)
 	SaveBill(ctx context.Context, b Bill) error
 	GetBillByID(ctx context.Context, id int64) (*Bill, error)
 	GetBillsByGroupAndMember(ctx context.Context, groupID int64, memberID string) ([]Bill, error)
+	// GetBillByGroupMemberCycle returns nil when the member has no recurring
+	// bill for the month.
 	GetBillByGroupMemberCycle(ctx context.Context, groupID int64, memberID string, year, month int) (*Bill, error)
 	GetBillsByMemberID(ctx context.Context, memberID string) ([]Bill, error)
 	GetBillsByGroupID(ctx context.Context, groupID int64) ([]Bill, error)
+	ListBills(ctx context.Context, q BillQuery) ([]Bill, error)
 	UpdateBill(ctx context.Context, b Bill) (*Bill, error)
+	NextPaymentID(ctx context.Context) (int64, error)
+	SavePayment(ctx context.Context, p Payment) error
+	GetPaymentsByBillID(ctx context.Context, billID int64) ([]Payment, error)
+	GetPaymentAttachment(ctx context.Context, id int64) (*Payment, error)
+	NextBillEventID(ctx context.Context) (int64, error)
+	SaveBillEvent(ctx context.Context, e BillEvent) error
+	GetBillEvents(ctx context.Context, billID int64) ([]BillEvent, error)
+	GetGroupOwnerID(ctx context.Context, groupID int64) (string, error)
 }
 
 type Service struct {
-	store      Store
+	store Store
+	rates currency.RateProvider
+	audit *audit.Service
 }
 
 func NewService(store Store) *Service {
 	return &Service{
-		store:          store,
+		store: store,
 	}
 }
 
+func (s *Service) SetRateProvider(p currency.RateProvider) {
+	s.rates = p
+}
+
+// SetAuditLog makes the service record the changes owners make to bills.
+func (s *Service) SetAuditLog(a *audit.Service) {
+	s.audit = a
+}
+
 func (s *Service) CreateBill(ctx context.Context, req CreateBillRequest) (*Bill, error) {
-	// Basic validation
-	if req.GroupID <= 0 {
-		return nil, ErrInvalidGroupID
-	}
-	if req.MemberID == "" {
-		return nil, ErrInvalidMemberID
-	}
-	if req.Year < 2000 || req.Year > 3000 { // arbitrary sanity check
-		return nil, ErrInvalidYear
-	}
-	if req.Month < 1 || req.Month > 12 {
-		return nil, ErrInvalidMonth
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
-	if req.AmountDue <= 0 {
-		return nil, ErrInvalidAmount
+	cur, err := currency.Normalize(req.Currency)
+	if err != nil {
+		return nil, err
 	}
-	if req.Currency == "" {
-		return nil, ErrInvalidCurrency
+
+	kind := req.Kind
+	if kind == "" {
+		kind = BillKindRecurring
 	}
 
 	now := time.Now().UTC()
@@ -58,13 +76,16 @@ func (s *Service) CreateBill(ctx context.Context, req CreateBillRequest) (*Bill,
 
 	b := Bill{
 		ID:          id,
+		Kind:        kind,
+		ExpenseID:   req.ExpenseID,
+		PayeeID:     req.PayeeID,
 		GroupID:     req.GroupID,
 		MemberID:    req.MemberID,
 		Year:        req.Year,
 		Month:       req.Month,
 		AmountDue:   req.AmountDue,
 		AmountPaid:  0, // starts unpaid
-		Currency:    req.Currency,
+		Currency:    cur,
 		Status:      BillStatusPending,
 		Description: req.Description,
 
@@ -74,13 +95,96 @@ func (s *Service) CreateBill(ctx context.Context, req CreateBillRequest) (*Bill,
 		// Proof + SubmittedAt/VerifiedAt/RejectedAt = nil by default
 	}
 
+	if err := b.SnapshotRate(ctx, s.rates, now); err != nil {
+		return nil, err
+	}
+
 	if err := s.store.SaveBill(ctx, b); err != nil {
 		return nil, err
 	}
 
+	if err := Issued(ctx, s.store, &b, ActorSystem); err != nil {
+		return nil, err
+	}
+
 	return &b, nil
 }
 
+// TransitionBill lets the group owner move a bill by hand, e.g. reopen a
+// rejected bill or accept one without a slip. Only transitions allowed by the
+// state machine go through.
+func (s *Service) TransitionBill(ctx context.Context, req TransitionBillRequest, billID int64) (*Bill, error) {
+	if billID <= 0 {
+		return nil, ErrInvalidBillID
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	b, err := s.store.GetBillByID(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerID, err := s.store.GetGroupOwnerID(ctx, b.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID != req.ActorID {
+		return nil, ErrNotGroupOwner
+	}
+
+	if err := b.CheckTransition(req.Status); err != nil {
+		return nil, err
+	}
+
+	before := b.Status
+	now := time.Now().UTC()
+	if err := Transition(ctx, s.store, b, req.Status, req.ActorID, req.Reason, now); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.store.UpdateBill(ctx, *b)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit.Record(ctx, audit.Entry{
+		GroupID:    b.GroupID,
+		ActorID:    req.ActorID,
+		Action:     audit.ActionBillTransition,
+		EntityType: audit.EntityBill,
+		EntityID:   strconv.FormatInt(b.ID, 10),
+		Before:     map[string]any{"status": before},
+		After:      map[string]any{"status": updated.Status, "reason": req.Reason},
+	}); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func isManualStatus(status BillStatus) bool {
+	for _, s := range manualStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) GetBillHistory(ctx context.Context, billID int64) ([]BillEvent, error) {
+	if billID <= 0 {
+		return nil, ErrInvalidBillID
+	}
+
+	if _, err := s.store.GetBillByID(ctx, billID); err != nil {
+		return nil, err
+	}
+
+	return s.store.GetBillEvents(ctx, billID)
+}
+
 func (s *Service) GetBillsByGroup(ctx context.Context, groupID int64) ([]Bill, error) {
 	if groupID <= 0 {
 		return nil, ErrInvalidGroupID
@@ -91,4 +195,33 @@ func (s *Service) GetBillsByGroup(ctx context.Context, groupID int64) ([]Bill, e
 
 func (s *Service) GetBillsByMember(ctx context.Context, memberID string) ([]Bill, error) {
 	return s.store.GetBillsByMemberID(ctx, memberID)
-}
\ No newline at end of file
+}
+
+func (s *Service) GetPaymentsByBill(ctx context.Context, billID int64) ([]Payment, error) {
+	if billID <= 0 {
+		return nil, ErrInvalidBillID
+	}
+
+	if _, err := s.store.GetBillByID(ctx, billID); err != nil {
+		return nil, err
+	}
+
+	return s.store.GetPaymentsByBillID(ctx, billID)
+}
+
+func (s *Service) GetPaymentAttachment(ctx context.Context, billID, paymentID int64) (*Payment, error) {
+	if billID <= 0 {
+		return nil, ErrInvalidBillID
+	}
+
+	p, err := s.store.GetPaymentAttachment(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.BillID != billID || len(p.Attachment) == 0 {
+		return nil, ErrAttachmentNotFound
+	}
+
+	return p, nil
+}
@@ -0,0 +1,159 @@
+package bill
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ActorSystem is recorded as the actor of transitions made by the service
+// itself, such as issuing the monthly bills.
+const ActorSystem = "system"
+
+// transitions lists the statuses a bill may move to from each status.
+// Verified, canceled and waived bills are final; a rejected bill has to be
+// reopened (back to pending) before anything can be paid on it again.
+var transitions = map[BillStatus][]BillStatus{
+	BillStatusPending:       {BillStatusSubmitted, BillStatusPartiallyPaid, BillStatusVerified, BillStatusCanceled, BillStatusWaived},
+	BillStatusSubmitted:     {BillStatusPartiallyPaid, BillStatusVerified, BillStatusRejected, BillStatusCanceled, BillStatusWaived},
+	BillStatusPartiallyPaid: {BillStatusSubmitted, BillStatusVerified, BillStatusCanceled, BillStatusWaived},
+	BillStatusRejected:      {BillStatusPending, BillStatusCanceled, BillStatusWaived},
+	BillStatusVerified:      {},
+	BillStatusCanceled:      {},
+	BillStatusWaived:        {},
+}
+
+// manualStatuses are the statuses an owner may set through TransitionBill.
+// Payments, cancellation and waivers have their own endpoints because they
+// also move the member's ledger.
+var manualStatuses = []BillStatus{BillStatusPending, BillStatusRejected}
+
+// BillEvent is one entry of a bill's history. From is empty for the event that
+// issued the bill.
+type BillEvent struct {
+	ID        int64      `json:"id"`
+	BillID    int64      `json:"bill_id"`
+	From      BillStatus `json:"from,omitempty"`
+	To        BillStatus `json:"to"`
+	ActorID   string     `json:"actor_id"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type TransitionBillRequest struct {
+	Status  BillStatus `json:"status"`
+	ActorID string     `json:"actor_id"`
+	Reason  string     `json:"reason"`
+}
+
+// TransitionError is returned for a status change the state machine does not
+// allow; it unwraps to ErrIllegalTransition.
+type TransitionError struct {
+	From BillStatus `json:"from"`
+	To   BillStatus `json:"to"`
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", ErrIllegalTransition, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrIllegalTransition
+}
+
+// EventStore persists bill history. Every service that changes a bill status
+// needs one.
+type EventStore interface {
+	NextBillEventID(ctx context.Context) (int64, error)
+	SaveBillEvent(ctx context.Context, e BillEvent) error
+}
+
+func IsValidStatus(s BillStatus) bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// IsFinal reports whether no further transition is possible.
+func (b *Bill) IsFinal() bool {
+	return len(transitions[b.Status]) == 0
+}
+
+func CanTransition(from, to BillStatus) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTransition returns a *TransitionError when the bill can't move to `to`.
+func (b *Bill) CheckTransition(to BillStatus) error {
+	if !CanTransition(b.Status, to) {
+		return &TransitionError{From: b.Status, To: to}
+	}
+	return nil
+}
+
+// Transition moves b to `to`, stamps the matching timestamp and writes the
+// event to store. The caller still has to save the bill.
+func Transition(ctx context.Context, store EventStore, b *Bill, to BillStatus, actorID, reason string, now time.Time) error {
+	if err := b.CheckTransition(to); err != nil {
+		return err
+	}
+
+	from := b.Status
+	b.Status = to
+	b.UpdatedAt = now
+
+	switch to {
+	case BillStatusSubmitted:
+		b.SubmittedAt = &now
+	case BillStatusVerified:
+		if b.VerifiedAt == nil {
+			b.VerifiedAt = &now
+		}
+	case BillStatusRejected:
+		b.RejectedAt = &now
+	}
+
+	return recordEvent(ctx, store, b.ID, from, to, actorID, reason, now)
+}
+
+// RecordEvent adds a history entry that doesn't change the status, such as an
+// amendment.
+func RecordEvent(ctx context.Context, store EventStore, b *Bill, actorID, reason string, now time.Time) error {
+	return recordEvent(ctx, store, b.ID, b.Status, b.Status, actorID, reason, now)
+}
+
+// Issued records the creation of a bill as the first event of its history.
+func Issued(ctx context.Context, store EventStore, b *Bill, actorID string) error {
+	return recordEvent(ctx, store, b.ID, "", b.Status, actorID, "issued", b.CreatedAt)
+}
+
+// Settle recomputes AmountPaid from payments and moves the bill to the status
+// they call for, recording the transition.
+func Settle(ctx context.Context, store EventStore, b *Bill, payments []Payment, actorID, reason string, now time.Time) error {
+	to := b.ApplyPayments(payments, now)
+	if to == b.Status {
+		return nil
+	}
+	return Transition(ctx, store, b, to, actorID, reason, now)
+}
+
+func recordEvent(ctx context.Context, store EventStore, billID int64, from, to BillStatus, actorID, reason string, at time.Time) error {
+	id, err := store.NextBillEventID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return store.SaveBillEvent(ctx, BillEvent{
+		ID:        id,
+		BillID:    billID,
+		From:      from,
+		To:        to,
+		ActorID:   actorID,
+		Reason:    reason,
+		CreatedAt: at,
+	})
+}
@@ -0,0 +1,165 @@
+package bill
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var allStatuses = []BillStatus{
+	BillStatusPending,
+	BillStatusSubmitted,
+	BillStatusPartiallyPaid,
+	BillStatusVerified,
+	BillStatusRejected,
+	BillStatusCanceled,
+	BillStatusWaived,
+}
+
+func TestTransitions(t *testing.T) {
+	allowed := map[BillStatus][]BillStatus{
+		BillStatusPending:       {BillStatusSubmitted, BillStatusPartiallyPaid, BillStatusVerified, BillStatusCanceled, BillStatusWaived},
+		BillStatusSubmitted:     {BillStatusPartiallyPaid, BillStatusVerified, BillStatusRejected, BillStatusCanceled, BillStatusWaived},
+		BillStatusPartiallyPaid: {BillStatusSubmitted, BillStatusVerified, BillStatusCanceled, BillStatusWaived},
+		BillStatusRejected:      {BillStatusPending, BillStatusCanceled, BillStatusWaived},
+	}
+
+	for _, from := range allStatuses {
+		want := map[BillStatus]bool{}
+		for _, to := range allowed[from] {
+			want[to] = true
+		}
+
+		for _, to := range allStatuses {
+			if got := CanTransition(from, to); got != want[to] {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want[to])
+			}
+		}
+
+		b := Bill{Status: from}
+		if final := len(allowed[from]) == 0; b.IsFinal() != final {
+			t.Errorf("%s: IsFinal = %v, want %v", from, b.IsFinal(), final)
+		}
+		if !IsValidStatus(from) {
+			t.Errorf("IsValidStatus(%s) = false", from)
+		}
+	}
+
+	if IsValidStatus("paid") {
+		t.Error(`IsValidStatus("paid") = true`)
+	}
+}
+
+func TestCheckTransition(t *testing.T) {
+	b := Bill{Status: BillStatusVerified}
+	err := b.CheckTransition(BillStatusPending)
+
+	var transErr *TransitionError
+	if !errors.As(err, &transErr) || !errors.Is(err, ErrIllegalTransition) {
+		t.Fatalf("CheckTransition = %v, want a *TransitionError", err)
+	}
+	if transErr.From != BillStatusVerified || transErr.To != BillStatusPending {
+		t.Errorf("TransitionError = %+v", transErr)
+	}
+}
+
+type eventStore struct {
+	events []BillEvent
+}
+
+func (s *eventStore) NextBillEventID(ctx context.Context) (int64, error) {
+	return int64(len(s.events) + 1), nil
+}
+
+func (s *eventStore) SaveBillEvent(ctx context.Context, e BillEvent) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func TestTransition(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		from, to BillStatus
+		stamp    func(b *Bill) *time.Time
+		wantErr  bool
+	}{
+		{BillStatusPending, BillStatusSubmitted, func(b *Bill) *time.Time { return b.SubmittedAt }, false},
+		{BillStatusSubmitted, BillStatusVerified, func(b *Bill) *time.Time { return b.VerifiedAt }, false},
+		{BillStatusSubmitted, BillStatusRejected, func(b *Bill) *time.Time { return b.RejectedAt }, false},
+		{BillStatusRejected, BillStatusPending, nil, false},
+		{BillStatusRejected, BillStatusVerified, nil, true},
+		{BillStatusCanceled, BillStatusPending, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			store := &eventStore{}
+			b := &Bill{ID: 7, Status: tt.from}
+
+			err := Transition(context.Background(), store, b, tt.to, "owner", "because", now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrIllegalTransition) {
+					t.Fatalf("Transition = %v, want ErrIllegalTransition", err)
+				}
+				if b.Status != tt.from || len(store.events) != 0 {
+					t.Errorf("refused transition changed the bill or wrote %d events", len(store.events))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Transition: %v", err)
+			}
+
+			if b.Status != tt.to || !b.UpdatedAt.Equal(now) {
+				t.Errorf("bill = %s at %s, want %s at %s", b.Status, b.UpdatedAt, tt.to, now)
+			}
+			if tt.stamp != nil {
+				if at := tt.stamp(b); at == nil || !at.Equal(now) {
+					t.Errorf("timestamp for %s = %v, want %s", tt.to, at, now)
+				}
+			}
+
+			want := BillEvent{ID: 1, BillID: 7, From: tt.from, To: tt.to, ActorID: "owner", Reason: "because", CreatedAt: now}
+			if len(store.events) != 1 || store.events[0] != want {
+				t.Errorf("events = %+v, want [%+v]", store.events, want)
+			}
+		})
+	}
+}
+
+func TestSettle(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	paidAt := now.Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		payments []Payment
+		want     BillStatus
+		paid     float64
+	}{
+		{"nothing accepted", []Payment{{Amount: 300, Status: PaymentStatusRejected}}, BillStatusSubmitted, 0},
+		{"part paid", []Payment{{Amount: 100, Status: PaymentStatusAccepted, PaidAt: &paidAt}}, BillStatusPartiallyPaid, 100},
+		{"top-ups add up", []Payment{
+			{Amount: 100, Status: PaymentStatusAccepted, PaidAt: &paidAt},
+			{Amount: 200, Status: PaymentStatusAccepted, PaidAt: &paidAt},
+		}, BillStatusVerified, 300},
+		{"rounding", []Payment{{Amount: 299.9995, Status: PaymentStatusAccepted, PaidAt: &paidAt}}, BillStatusVerified, 299.9995},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bill{AmountDue: 300, Status: BillStatusSubmitted}
+			if err := Settle(context.Background(), &eventStore{}, b, tt.payments, ActorSystem, "", now); err != nil {
+				t.Fatalf("Settle: %v", err)
+			}
+			if b.Status != tt.want || b.AmountPaid != tt.paid {
+				t.Errorf("bill = %s, paid %v; want %s, paid %v", b.Status, b.AmountPaid, tt.want, tt.paid)
+			}
+			if tt.paid > 0 && (b.PaidAt == nil || !b.PaidAt.Equal(paidAt)) {
+				t.Errorf("PaidAt = %v, want %s", b.PaidAt, paidAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package bill
+
+import (
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+)
+
+// Validate checks a bill about to be issued. Member IDs are only required
+// here: bills are issued to members already in a group, whatever their IDs
+// looked like when they joined.
+func (req CreateBillRequest) Validate() error {
+	var v validate.Validator
+	v.Check(req.GroupID > 0, "group_id", ErrInvalidGroupID)
+	v.Check(req.MemberID != "", "member_id", ErrInvalidMemberID)
+	v.Check(req.Year >= 2000 && req.Year <= 3000, "year", ErrInvalidYear) // arbitrary sanity check
+	v.Check(req.Month >= 1 && req.Month <= 12, "month", ErrInvalidMonth)
+	v.Check(req.AmountDue > 0, "amount_due", ErrInvalidAmount)
+	if req.Currency == "" {
+		v.Add("currency", ErrInvalidCurrency)
+	} else if _, err := currency.Normalize(req.Currency); err != nil {
+		v.Add("currency", err)
+	}
+	v.Check(req.Kind == "" || req.Kind == BillKindRecurring || req.Kind == BillKindExpense, "kind", ErrInvalidKind)
+	v.Check(req.Kind != BillKindExpense || req.ExpenseID != nil, "expense_id", ErrInvalidExpenseID)
+	return v.Err()
+}
+
+func (req TransitionBillRequest) Validate() error {
+	var v validate.Validator
+	if !IsValidStatus(req.Status) {
+		v.Add("status", ErrInvalidStatus)
+	} else {
+		v.Check(isManualStatus(req.Status), "status", ErrStatusNotSettable)
+	}
+	v.Snowflake("actor_id", req.ActorID)
+	return v.Err()
+}
@@ -3,10 +3,19 @@ package billver
 import "errors"
 
 var (
-	ErrConfigNotSet = errors.New("config not set")
-	ErrSlipTooSmall = errors.New("Slip too small")
-	ErrBillMemberMismatch = errors.New("bill and member mismatch")
+	ErrConfigNotSet        = errors.New("config not set")
+	ErrSlipTooSmall        = errors.New("Slip too small")
+	ErrBillMemberMismatch  = errors.New("bill and member mismatch")
 	ErrBillAlreadyVerified = errors.New("bill is already verified")
-	ErrVerificationFailed = errors.New("verification failed")
-	ErrWrongReciever = errors.New("wrong reciever in slip")
-)
\ No newline at end of file
+	ErrVerificationFailed  = errors.New("verification failed")
+	ErrWrongReciever       = errors.New("wrong reciever in slip")
+	ErrDuplicateSlip       = errors.New("slip was already used")
+)
+
+var (
+	ErrSlipDateMissing  = errors.New("slip has no transfer date")
+	ErrSlipDateInvalid  = errors.New("slip transfer date is not valid")
+	ErrSlipPredatesBill = errors.New("slip predates the bill")
+	ErrSlipInFuture     = errors.New("slip is dated in the future")
+	ErrSlipOutsideCycle = errors.New("slip is outside the bill cycle")
+)
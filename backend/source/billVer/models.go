@@ -1,15 +1,45 @@
 package billver
 
 import (
+	"time"
+
 	"github.com/NoNiiEa/subShare-Discord/source/group"
 )
 
 type SlipVerificationResult struct {
-	IsValid bool `json:"is_valid"`
-	MatchedAmount float64 `json:"matched_amount"`
-	Method group.PaymentMethod `json:"method"`
-	Account string `json:"account"`
-	RawResponse []byte `json:"raw_response"`
+	IsValid       bool                `json:"is_valid"`
+	MatchedAmount float64             `json:"matched_amount"` // as transferred, in Currency
+	Currency      string              `json:"currency"`
+	BillAmount    float64             `json:"bill_amount"` // MatchedAmount in the bill currency
+	TransRef      string              `json:"trans_ref"`
+	Method        group.PaymentMethod `json:"method"`
+	Account       string              `json:"account"`
+	Receiver      SlipReceiver        `json:"receiver"`
+	ReceiverMatch *ReceiverMatch      `json:"receiver_match,omitempty"`
+	TransferredAt time.Time           `json:"transferred_at"`
+	RawResponse   []byte              `json:"raw_response"`
+
+	// amount in a foreign currency, when EasySlip reports one
+	localAmount   float64
+	localCurrency string
+
+	// why the transfer date EasySlip reported could not be read
+	dateErr error
+}
+
+// SlipReceiver is the receiving side of a slip as reported by EasySlip.
+// Account is kept exactly as printed on the slip, masking included.
+type SlipReceiver struct {
+	BankID      string `json:"bank_id"`
+	BankShort   string `json:"bank_short"`
+	NameTh      string `json:"name_th"`
+	NameEn      string `json:"name_en"`
+	AccountType string `json:"account_type"` // "BANKAC" or a proxy type such as "MSISDN"
+	Account     string `json:"account"`
+
+	// the PromptPay proxy, when the slip shows a bank account as well
+	ProxyType    string `json:"proxy_type,omitempty"`
+	ProxyAccount string `json:"proxy_account,omitempty"`
 }
 
 type easySlipResponse struct {
@@ -47,12 +77,12 @@ type easySlipResponse struct {
 				} `json:"name"`
 
 				Bank *struct {
-					Type    string `json:"type"`    // "BANKAC" | "TOKEN" | "DUMMY"
+					Type    string `json:"type"` // "BANKAC" | "TOKEN" | "DUMMY"
 					Account string `json:"account"`
 				} `json:"bank,omitempty"`
 
 				Proxy *struct {
-					Type    string `json:"type"`    // "NATID" | "MSISDN" | "EWALLETID" | "EMAIL" | "BILLERID"
+					Type    string `json:"type"` // "NATID" | "MSISDN" | "EWALLETID" | "EMAIL" | "BILLERID"
 					Account string `json:"account"`
 				} `json:"proxy,omitempty"`
 			} `json:"account"`
@@ -71,12 +101,12 @@ type easySlipResponse struct {
 				} `json:"name"`
 
 				Bank *struct {
-					Type    string `json:"type"`    // "BANKAC" | "TOKEN" | "DUMMY"
+					Type    string `json:"type"` // "BANKAC" | "TOKEN" | "DUMMY"
 					Account string `json:"account"`
 				} `json:"bank,omitempty"`
 
 				Proxy *struct {
-					Type    string `json:"type"`    // "NATID" | "MSISDN" | "EWALLETID" | "EMAIL" | "BILLERID"
+					Type    string `json:"type"` // "NATID" | "MSISDN" | "EWALLETID" | "EMAIL" | "BILLERID"
 					Account string `json:"account"`
 				} `json:"proxy,omitempty"`
 			} `json:"account"`
@@ -92,4 +122,4 @@ type SubmitBillProofRequest struct {
 	AmountPaid float64 `json:"amount_paid"` // user-claimed, optional
 	ImageBytes []byte  `json:"-"`
 	FileName   string  `json:"-"` // "slip.jpg"
-}
\ No newline at end of file
+}
@@ -0,0 +1,310 @@
+package billver
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+type MismatchReason string
+
+const (
+	MismatchReceiverMissing MismatchReason = "receiver_missing"
+	MismatchMethod          MismatchReason = "method_mismatch"
+	MismatchAccount         MismatchReason = "account_mismatch"
+	MismatchBank            MismatchReason = "bank_mismatch"
+	MismatchName            MismatchReason = "name_mismatch"
+	MismatchLowConfidence   MismatchReason = "low_confidence"
+)
+
+type ReceiverMismatch struct {
+	Reason   MismatchReason `json:"reason"`
+	Expected string         `json:"expected,omitempty"`
+	Actual   string         `json:"actual,omitempty"`
+}
+
+type ReceiverMatch struct {
+	Matched    bool              `json:"matched"`
+	Confidence float64           `json:"confidence"` // 0..1
+	Mismatch   *ReceiverMismatch `json:"mismatch,omitempty"`
+}
+
+// ReceiverMismatchError wraps ErrWrongReciever with the reason the slip
+// receiver was refused, so callers can still use errors.Is.
+type ReceiverMismatchError struct {
+	Match ReceiverMatch
+}
+
+func (e *ReceiverMismatchError) Error() string {
+	if e.Match.Mismatch == nil {
+		return ErrWrongReciever.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrWrongReciever, e.Match.Mismatch.Reason)
+}
+
+func (e *ReceiverMismatchError) Unwrap() error {
+	return ErrWrongReciever
+}
+
+// weights used to build the confidence score
+const (
+	accountWeight = 0.6
+	bankWeight    = 0.2
+	nameWeight    = 0.2
+
+	// number of visible digits after which the account evidence counts fully;
+	// Thai banks usually print four.
+	fullVisibleDigits = 4
+)
+
+type ReceiverMatcher struct {
+	MinConfidence float64
+}
+
+func NewReceiverMatcher() *ReceiverMatcher {
+	return &ReceiverMatcher{MinConfidence: 0.5}
+}
+
+// Match compares the receiver printed on a slip with a group's payout account.
+// Any contradicting evidence (method, visible digits, bank code, name) is a
+// mismatch; otherwise the slip is accepted when the gathered evidence reaches
+// MinConfidence. A slip showing both a bank account and a proxy is matched
+// on each and the better result wins.
+func (m *ReceiverMatcher) Match(expected group.PaymentAccount, got SlipReceiver) ReceiverMatch {
+	res := m.matchAccount(expected, got)
+	if got.ProxyType == "" {
+		return res
+	}
+
+	viaProxy := got
+	viaProxy.AccountType, viaProxy.Account = got.ProxyType, got.ProxyAccount
+	viaProxy.ProxyType, viaProxy.ProxyAccount = "", ""
+	if alt := m.matchAccount(expected, viaProxy); betterMatch(alt, res) {
+		return alt
+	}
+	return res
+}
+
+func (m *ReceiverMatcher) matchAccount(expected group.PaymentAccount, got SlipReceiver) ReceiverMatch {
+	if got.AccountType == "" && got.Account == "" {
+		return mismatch(0, MismatchReceiverMissing, string(expected.Method), "")
+	}
+
+	gotMethod := group.PaymentMethod(got.AccountType)
+	if gotMethod != expected.Method {
+		return mismatch(0, MismatchMethod, string(expected.Method), got.AccountType)
+	}
+
+	var confidence float64
+
+	digits := group.AccountDigits(expected.Method, expected.Account)
+	pattern := normalizeMask(expected.Method, got.Account)
+	visible, aligned, ok := matchMasked(pattern, digits)
+	if !ok {
+		return mismatch(0, MismatchAccount, maskAccount(digits), got.Account)
+	}
+	if visible > 0 {
+		v := visible
+		if v > fullVisibleDigits {
+			v = fullVisibleDigits
+		}
+		score := accountWeight * float64(v) / fullVisibleDigits
+		if !aligned {
+			score /= 2
+		}
+		confidence += score
+	}
+
+	if expected.Method == group.BankAccount && expected.BankCode != "" && got.BankID != "" {
+		if strings.TrimLeft(expected.BankCode, "0") != strings.TrimLeft(got.BankID, "0") {
+			return mismatch(confidence, MismatchBank, expected.BankCode, got.BankID)
+		}
+		confidence += bankWeight
+	}
+
+	compared, matched := false, false
+	if expected.NameEn != "" && got.NameEn != "" {
+		compared = true
+		matched = matched || nameMatches(expected.NameEn, got.NameEn)
+	}
+	if expected.NameTh != "" && got.NameTh != "" {
+		compared = true
+		matched = matched || nameMatches(expected.NameTh, got.NameTh)
+	}
+	if compared {
+		if !matched {
+			return mismatch(confidence, MismatchName, firstNonEmpty(expected.NameEn, expected.NameTh), firstNonEmpty(got.NameEn, got.NameTh))
+		}
+		confidence += nameWeight
+	}
+
+	if confidence < m.MinConfidence {
+		return mismatch(confidence, MismatchLowConfidence, "", got.Account)
+	}
+
+	return ReceiverMatch{Matched: true, Confidence: confidence}
+}
+
+// MatchAny tries every accepted account of a group and returns the best
+// result. When none matches, the mismatch of the closest account is returned so
+// the member sees the most useful reason.
+func (m *ReceiverMatcher) MatchAny(accounts []group.PaymentAccount, got SlipReceiver) (ReceiverMatch, *group.PaymentAccount) {
+	if len(accounts) == 0 {
+		return mismatch(0, MismatchReceiverMissing, "", got.Account), nil
+	}
+
+	var (
+		best      ReceiverMatch
+		bestIndex = -1
+	)
+	for i := range accounts {
+		res := m.Match(accounts[i], got)
+		if bestIndex == -1 || betterMatch(res, best) {
+			best, bestIndex = res, i
+		}
+	}
+
+	if !best.Matched {
+		return best, nil
+	}
+	return best, &accounts[bestIndex]
+}
+
+// betterMatch prefers a match over a mismatch, then a mismatch that got past
+// the method check, then the higher confidence.
+func betterMatch(a, b ReceiverMatch) bool {
+	if a.Matched != b.Matched {
+		return a.Matched
+	}
+	aMethod := a.Mismatch != nil && a.Mismatch.Reason == MismatchMethod
+	bMethod := b.Mismatch != nil && b.Mismatch.Reason == MismatchMethod
+	if aMethod != bMethod {
+		return !aMethod
+	}
+	return a.Confidence > b.Confidence
+}
+
+func mismatch(confidence float64, reason MismatchReason, expected, actual string) ReceiverMatch {
+	return ReceiverMatch{
+		Matched:    false,
+		Confidence: confidence,
+		Mismatch: &ReceiverMismatch{
+			Reason:   reason,
+			Expected: expected,
+			Actual:   actual,
+		},
+	}
+}
+
+// normalizeMask turns a masked slip account such as "xxx-x-x1234-x" into a
+// pattern of digits and 'x' placeholders without separators.
+func normalizeMask(method group.PaymentMethod, masked string) string {
+	var b strings.Builder
+	for _, r := range masked {
+		switch {
+		case unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '-' || r == ' ' || r == '+' || r == '.':
+			// separators
+		default:
+			b.WriteByte('x')
+		}
+	}
+	pattern := b.String()
+	if method == group.PromptPay && strings.HasPrefix(pattern, "66") && len(pattern) == 11 {
+		pattern = "0" + pattern[2:]
+	}
+	return pattern
+}
+
+// matchMasked checks every visible digit of pattern against digits. When both
+// have the same length digits are compared position by position; otherwise the
+// visible runs only have to appear in order, which is reported as not aligned.
+func matchMasked(pattern, digits string) (visible int, aligned bool, ok bool) {
+	if digits == "" {
+		return 0, false, false
+	}
+
+	if len(pattern) == len(digits) {
+		for i := 0; i < len(pattern); i++ {
+			if pattern[i] == 'x' {
+				continue
+			}
+			if pattern[i] != digits[i] {
+				return 0, true, false
+			}
+			visible++
+		}
+		return visible, true, true
+	}
+
+	rest := digits
+	for _, run := range strings.FieldsFunc(pattern, func(r rune) bool { return r == 'x' }) {
+		idx := strings.Index(rest, run)
+		if idx < 0 {
+			return 0, false, false
+		}
+		rest = rest[idx+len(run):]
+		visible += len(run)
+	}
+	return visible, false, true
+}
+
+func maskAccount(digits string) string {
+	if len(digits) <= 4 {
+		return digits
+	}
+	return strings.Repeat("x", len(digits)-4) + last4(digits)
+}
+
+var nameTitles = []string{
+	"นางสาว", "น.ส.", "นาง", "นาย",
+	"mrs.", "mrs", "miss", "ms.", "ms", "mr.", "mr",
+}
+
+func normalizeName(name string) []string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, t := range nameTitles {
+		if !strings.HasPrefix(name, t) {
+			continue
+		}
+		rest := name[len(t):]
+		// latin titles without a dot must stand alone ("mr somchai", not "mrinal")
+		if last := t[len(t)-1]; last >= 'a' && last <= 'z' && rest != "" && rest[0] != ' ' {
+			continue
+		}
+		name = strings.TrimSpace(rest)
+		break
+	}
+	name = strings.ReplaceAll(name, ".", " ")
+	return strings.Fields(name)
+}
+
+// nameMatches reports whether the (possibly truncated) name on a slip fits the
+// configured name: every slip token must be a prefix of the token at the same
+// position.
+func nameMatches(expected, got string) bool {
+	want := normalizeName(expected)
+	have := normalizeName(got)
+	if len(have) == 0 || len(have) > len(want) {
+		return false
+	}
+	for i, tok := range have {
+		tok = strings.TrimRight(tok, "x*")
+		if !strings.HasPrefix(want[i], tok) {
+			return false
+		}
+	}
+	return true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package billver
+
+import (
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+func TestReceiverMatcherMatch(t *testing.T) {
+	bank := group.PaymentAccount{
+		Method:   group.BankAccount,
+		Account:  "123-4-56789-0",
+		BankCode: "004",
+		NameEn:   "Somchai Jaidee",
+	}
+	promptPay := group.PaymentAccount{Method: group.PromptPay, Account: "0812345678"}
+
+	tests := []struct {
+		name     string
+		expected group.PaymentAccount
+		got      SlipReceiver
+		matched  bool
+		reason   MismatchReason
+	}{
+		{
+			name:     "bank account with bank code and name",
+			expected: bank,
+			got:      SlipReceiver{AccountType: "BANKAC", Account: "xxx-x-x6789-x", BankID: "004", NameEn: "MR. SOMCHAI J"},
+			matched:  true,
+		},
+		{
+			name:     "wrong visible digits",
+			expected: bank,
+			got:      SlipReceiver{AccountType: "BANKAC", Account: "xxx-x-x1111-x", BankID: "004"},
+			reason:   MismatchAccount,
+		},
+		{
+			name:     "wrong bank",
+			expected: bank,
+			got:      SlipReceiver{AccountType: "BANKAC", Account: "xxx-x-x6789-x", BankID: "014"},
+			reason:   MismatchBank,
+		},
+		{
+			name:     "wrong name",
+			expected: bank,
+			got:      SlipReceiver{AccountType: "BANKAC", Account: "xxx-x-x6789-x", BankID: "004", NameEn: "MR. SOMSAK J"},
+			reason:   MismatchName,
+		},
+		{
+			name:     "too little evidence",
+			expected: bank,
+			got:      SlipReceiver{AccountType: "BANKAC", Account: "xxx-x-xxxx9-x"},
+			reason:   MismatchLowConfidence,
+		},
+		{
+			name:     "proxy slip for a bank account",
+			expected: bank,
+			got:      SlipReceiver{AccountType: "MSISDN", Account: "081-xxx-5678"},
+			reason:   MismatchMethod,
+		},
+		{
+			name:     "no receiver",
+			expected: bank,
+			got:      SlipReceiver{},
+			reason:   MismatchReceiverMissing,
+		},
+		{
+			name:     "phone number in international form",
+			expected: promptPay,
+			got:      SlipReceiver{AccountType: "MSISDN", Account: "+66-81-xxx-5678"},
+			matched:  true,
+		},
+		{
+			name:     "slip with bank account and proxy, bank group",
+			expected: bank,
+			got: SlipReceiver{
+				AccountType: "BANKAC", Account: "xxx-x-x6789-x", BankID: "004",
+				ProxyType: "MSISDN", ProxyAccount: "081-xxx-5678",
+			},
+			matched: true,
+		},
+		{
+			name:     "slip with bank account and proxy, PromptPay group",
+			expected: promptPay,
+			got: SlipReceiver{
+				AccountType: "BANKAC", Account: "xxx-x-x6789-x", BankID: "004",
+				ProxyType: "MSISDN", ProxyAccount: "081-xxx-5678",
+			},
+			matched: true,
+		},
+	}
+
+	m := NewReceiverMatcher()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := m.Match(tt.expected, tt.got)
+			if res.Matched != tt.matched {
+				t.Fatalf("Matched = %v, want %v (%+v)", res.Matched, tt.matched, res.Mismatch)
+			}
+			if tt.matched {
+				if res.Mismatch != nil {
+					t.Errorf("matched with mismatch %+v", res.Mismatch)
+				}
+				if res.Confidence < m.MinConfidence {
+					t.Errorf("Confidence = %v, below %v", res.Confidence, m.MinConfidence)
+				}
+				return
+			}
+			if res.Mismatch == nil || res.Mismatch.Reason != tt.reason {
+				t.Errorf("Mismatch = %+v, want reason %s", res.Mismatch, tt.reason)
+			}
+		})
+	}
+}
+
+func TestReceiverMatcherMatchAny(t *testing.T) {
+	accounts := []group.PaymentAccount{
+		{Method: group.PromptPay, Account: "0899999999"},
+		{Method: group.BankAccount, Account: "1234567890", BankCode: "004"},
+	}
+	m := NewReceiverMatcher()
+
+	res, account := m.MatchAny(accounts, SlipReceiver{AccountType: "BANKAC", Account: "xxx-x-x6789-x", BankID: "004"})
+	if !res.Matched || account == nil || account.Method != group.BankAccount {
+		t.Fatalf("MatchAny = %+v, %+v; want the bank account", res, account)
+	}
+
+	// the closest account explains the refusal, not the first one tried
+	res, account = m.MatchAny(accounts, SlipReceiver{AccountType: "BANKAC", Account: "xxx-x-x6789-x", BankID: "014"})
+	if res.Matched || account != nil {
+		t.Fatalf("MatchAny matched %+v", account)
+	}
+	if res.Mismatch == nil || res.Mismatch.Reason != MismatchBank {
+		t.Errorf("Mismatch = %+v, want %s", res.Mismatch, MismatchBank)
+	}
+}
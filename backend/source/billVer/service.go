@@ -4,41 +4,70 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"strconv"
 	"time"
 
-	"github.com/NoNiiEa/subShare-Discord/source/group"
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
 	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
 )
 
 type Store interface {
 	GetBillByID(ctx context.Context, id int64) (*bill.Bill, error)
 	UpdateBill(ctx context.Context, b bill.Bill) (*bill.Bill, error)
+	NextPaymentID(ctx context.Context) (int64, error)
+	SaveSlipPayment(ctx context.Context, p bill.Payment) (bool, error)
+	GetPaymentsByBillID(ctx context.Context, billID int64) ([]bill.Payment, error)
+	HasAcceptedPaymentReference(ctx context.Context, reference string) (bool, error)
+	NextBillEventID(ctx context.Context) (int64, error)
+	SaveBillEvent(ctx context.Context, e bill.BillEvent) error
 	GetGroup(ctx context.Context, id int64) (*group.Group, error)
+	// RunInTx runs fn in one transaction; store calls made with the context
+	// fn gets, by any service, join it.
+	RunInTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type Service struct {
-	store Store
-	httpClient *http.Client
+	store           Store
+	groups          *group.Service
+	httpClient      *http.Client
 	easySlipBaseURL string
-	easySlipToken string
+	easySlipToken   string
+	matcher         *ReceiverMatcher
+	datePolicy      SlipDatePolicy
+	audit           *audit.Service
 }
 
-func NewService(store Store, httpClient *http.Client, easySlipBaseURL string, easySlipToken string) *Service {
+func NewService(store Store, groups *group.Service, httpClient *http.Client, easySlipBaseURL string, easySlipToken string) *Service {
 	if httpClient == nil {
 		httpClient = &http.Client{Timeout: 10 * time.Second}
 	}
 	return &Service{
-		store: store,
-		httpClient: httpClient,
+		store:           store,
+		groups:          groups,
+		httpClient:      httpClient,
 		easySlipBaseURL: easySlipBaseURL,
-		easySlipToken: easySlipToken,
+		easySlipToken:   easySlipToken,
+		matcher:         NewReceiverMatcher(),
+		datePolicy:      DefaultSlipDatePolicy(),
 	}
 }
 
+func (s *Service) SetSlipDatePolicy(p SlipDatePolicy) {
+	s.datePolicy = p
+}
+
+// SetAuditLog makes the service record every slip it checks.
+func (s *Service) SetAuditLog(a *audit.Service) {
+	s.audit = a
+}
+
 func (s *Service) callEasySlipVerify(ctx context.Context, imageByte []byte, filename string) (*SlipVerificationResult, error) {
 	if s.easySlipBaseURL == "" || s.easySlipToken == "" {
 		return nil, ErrConfigNotSet
@@ -90,31 +119,57 @@ func (s *Service) callEasySlipVerify(ctx context.Context, imageByte []byte, file
 		return nil, err
 	}
 
-	acc := parsed.Data.Receiver.Account
-	var method group.PaymentMethod
-	var account string
+	recv := parsed.Data.Receiver
+	acc := recv.Account
+	receiver := SlipReceiver{
+		BankID:    recv.Bank.ID,
+		BankShort: recv.Bank.Short,
+		NameTh:    acc.Name.Th,
+		NameEn:    acc.Name.En,
+	}
 	switch {
 	case acc.Bank != nil:
-    // bank transfer
-		method = group.BankAccount
-		account = acc.Bank.Account
+		// bank transfer; PromptPay slips often show the account behind the
+		// proxy too, which is kept so either can be matched
+		receiver.AccountType = string(group.BankAccount)
+		receiver.Account = acc.Bank.Account
+		if acc.Proxy != nil {
+			receiver.ProxyType = acc.Proxy.Type
+			receiver.ProxyAccount = acc.Proxy.Account
+		}
 	case acc.Proxy != nil:
-    // transferred via Proxy (PromptPay / phone / NATID)
-		method = group.PromptPay
-		account = acc.Proxy.Account
+		// transferred via Proxy (PromptPay / phone / NATID / e-wallet)
+		receiver.AccountType = acc.Proxy.Type
+		receiver.Account = acc.Proxy.Account
 	default:
-    // neither present (rare, possibly invalid slip)
-		method = group.BankAccount
-		account = acc.Bank.Account
+		// neither present (rare, possibly invalid slip); left empty so the
+		// receiver matcher refuses it
 	}
 
 	// Build your internal verification result
 	res := &SlipVerificationResult{
-		IsValid:         parsed.Status == 200,
-		MatchedAmount:   parsed.Data.Amount.Amount,
-		Method: method,
-		Account: account,
-		RawResponse:     json.RawMessage(bodyBytes),
+		IsValid:       parsed.Status == 200,
+		MatchedAmount: parsed.Data.Amount.Amount,
+		Currency:      currency.Settlement,
+		TransRef:      parsed.Data.TransRef,
+		Method:        group.PaymentMethod(receiver.AccountType),
+		Account:       receiver.Account,
+		Receiver:      receiver,
+		RawResponse:   json.RawMessage(bodyBytes),
+	}
+
+	if local := parsed.Data.Amount.Local; local.Currency != "" && local.Amount > 0 {
+		if cur, err := currency.Normalize(local.Currency); err == nil {
+			res.localAmount, res.localCurrency = local.Amount, cur
+		}
+	}
+
+	if parsed.Data.Date != "" {
+		t, err := time.Parse(time.RFC3339, parsed.Data.Date)
+		if err != nil {
+			res.dateErr = fmt.Errorf("%w %q: %w", ErrSlipDateInvalid, parsed.Data.Date, err)
+		}
+		res.TransferredAt = t
 	}
 
 	return res, nil
@@ -122,16 +177,8 @@ func (s *Service) callEasySlipVerify(ctx context.Context, imageByte []byte, file
 }
 
 func (s *Service) SubmitBillProof(ctx context.Context, req SubmitBillProofRequest) (*bill.Bill, *SlipVerificationResult, error) {
-	if req.BillID <= 0 {
-		return nil, nil, bill.ErrInvalidBillID
-	}
-
-	if req.MemberID == "" {
-		return nil, nil, group.ErrInvalidMemberID
-	}
-
-	if len(req.ImageBytes) == 0 {
-		return nil, nil, ErrSlipTooSmall
+	if err := req.Validate(); err != nil {
+		return nil, nil, err
 	}
 
 	b, err := s.store.GetBillByID(ctx, req.BillID)
@@ -143,49 +190,177 @@ func (s *Service) SubmitBillProof(ctx context.Context, req SubmitBillProofReques
 		return nil, nil, ErrBillMemberMismatch
 	}
 
-	if b.Status == bill.BillStatusVerified || b.Status == bill.BillStatusCanceled {
+	if b.IsFinal() {
 		return nil, nil, ErrBillAlreadyVerified
 	}
 
-	verResult, err := s.callEasySlipVerify(ctx, req.ImageBytes, req.FileName)
+	// checked before calling EasySlip, e.g. rejected bills must be reopened
+	if err := b.CheckTransition(bill.BillStatusSubmitted); err != nil {
+		return nil, nil, err
+	}
+
+	g, err := s.store.GetGroup(ctx, b.GroupID)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	amountFromSlip := verResult.MatchedAmount
+	// slips are checked against the owner's accounts and booked on the
+	// member's ledger
+	if !group.OwedToOwner(g, b) {
+		return nil, nil, group.ErrNotOwedToOwner
+	}
+	if !hasMember(g, b.MemberID) {
+		return nil, nil, group.ErrMemberNotFound
+	}
+
+	verResult, err := s.callEasySlipVerify(ctx, req.ImageBytes, req.FileName)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	now := time.Now().UTC()
-	b.AmountPaid = amountFromSlip
-	b.UpdatedAt = now
-	b.SubmittedAt = &now
 
-	if len(verResult.RawResponse) > 0 {
-		b.ProofJSON = string(verResult.RawResponse)
+	accounts := g.PaymentAccounts
+	if len(accounts) == 0 {
+		accounts = []group.PaymentAccount{g.Payment}
+	}
+	match, _ := s.matcher.MatchAny(accounts, verResult.Receiver)
+	verResult.ReceiverMatch = &match
+	if !match.Matched {
+		return nil, verResult, &ReceiverMismatchError{Match: match}
 	}
 
-	g, err := s.store.GetGroup(ctx, b.GroupID)
+	billAmount, err := slipAmountInBillCurrency(b, verResult)
 	if err != nil {
-		return nil, nil, err
+		return nil, verResult, err
+	}
+	verResult.BillAmount = billAmount
+
+	if verResult.IsValid {
+		if verResult.dateErr != nil {
+			return nil, verResult, verResult.dateErr
+		}
+		if err := s.datePolicy.Check(*b, verResult.TransferredAt, now); err != nil {
+			return nil, verResult, err
+		}
+
+		if verResult.TransRef != "" {
+			used, err := s.store.HasAcceptedPaymentReference(ctx, verResult.TransRef)
+			if err != nil {
+				return nil, nil, err
+			}
+			if used {
+				return nil, verResult, ErrDuplicateSlip
+			}
+		}
 	}
 
-	accStr := extractNumericCharacters(verResult.Account)
-
-	if g.Payment.Method != verResult.Method || last4(g.Payment.Account) != last4(accStr) {
-		return nil, nil, ErrWrongReciever
+	paymentID, err := s.store.NextPaymentID(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if verResult.IsValid && amountFromSlip+0.001 >= b.AmountDue {
-		b.Status = bill.BillStatusVerified
-		b.VerifiedAt = &now
-	} else if !verResult.IsValid {
-		b.Status = bill.BillStatusRejected
-		b.RejectedAt = &now
+	p := bill.Payment{
+		ID:        paymentID,
+		BillID:    b.ID,
+		GroupID:   b.GroupID,
+		MemberID:  b.MemberID,
+		Amount:    verResult.BillAmount,
+		Currency:  b.Currency,
+		Source:    bill.PaymentSourceSlip,
+		Status:    bill.PaymentStatusAccepted,
+		Reference: verResult.TransRef,
+		Method:    string(verResult.Method),
+		ProofJSON: string(verResult.RawResponse),
+		CreatedAt: now,
+	}
+	if verResult.Currency != b.Currency {
+		p.OriginalAmount = verResult.MatchedAmount
+		p.OriginalCurrency = verResult.Currency
+	}
+	if !verResult.IsValid {
+		p.Status = bill.PaymentStatusRejected
 	} else {
-		// valid slip but underpaid
-		b.Status = bill.BillStatusSubmitted
+		paidAt := verResult.TransferredAt
+		p.PaidAt = &paidAt
 	}
 
-	updated, err := s.store.UpdateBill(ctx, *b)
+	// the payment, the bill and the ledger change together or not at all
+	var updated *bill.Bill
+	err = s.store.RunInTx(ctx, func(ctx context.Context) error {
+		// the bill may have moved on while EasySlip answered
+		b, err := s.store.GetBillByID(ctx, req.BillID)
+		if err != nil {
+			return err
+		}
+		if b.IsFinal() {
+			return ErrBillAlreadyVerified
+		}
+		if err := b.CheckTransition(bill.BillStatusSubmitted); err != nil {
+			return err
+		}
+		before := *b
+
+		saved, err := s.store.SaveSlipPayment(ctx, p)
+		if err != nil {
+			return err
+		}
+		if !saved {
+			// counted by a submission that ran at the same time
+			return ErrDuplicateSlip
+		}
+
+		payments, err := s.store.GetPaymentsByBillID(ctx, b.ID)
+		if err != nil {
+			return err
+		}
+
+		if len(verResult.RawResponse) > 0 {
+			b.ProofJSON = string(verResult.RawResponse)
+		}
+
+		if err := bill.Transition(ctx, s.store, b, bill.BillStatusSubmitted, req.MemberID, "slip submitted", now); err != nil {
+			return err
+		}
+
+		if verResult.IsValid {
+			// sums every accepted slip, so top-ups add up instead of replacing
+			err = bill.Settle(ctx, s.store, b, payments, bill.ActorSystem, "slip verified", now)
+		} else if b.AmountPaid > 0 {
+			// earlier payments still stand
+			err = bill.Transition(ctx, s.store, b, bill.BillStatusPartiallyPaid, bill.ActorSystem, "slip rejected by EasySlip", now)
+		} else {
+			err = bill.Transition(ctx, s.store, b, bill.BillStatusRejected, bill.ActorSystem, "slip rejected by EasySlip", now)
+		}
+		if err != nil {
+			return err
+		}
+
+		updated, err = s.store.UpdateBill(ctx, *b)
+		if err != nil {
+			return err
+		}
+
+		if verResult.IsValid {
+			// only this slip; earlier payments on the bill are on the ledger already
+			if err := s.groups.CreditBillPayment(ctx, b, p.Amount, req.MemberID, p.Reference, now); err != nil {
+				return err
+			}
+		}
+
+		return s.audit.Record(ctx, audit.Entry{
+			GroupID:    b.GroupID,
+			ActorID:    req.MemberID,
+			Action:     audit.ActionSlipSubmit,
+			EntityType: audit.EntityBill,
+			EntityID:   strconv.FormatInt(b.ID, 10),
+			Before:     map[string]any{"status": before.Status, "amount_paid": before.AmountPaid},
+			After:      map[string]any{"status": updated.Status, "amount_paid": updated.AmountPaid, "payment_id": p.ID, "slip_valid": verResult.IsValid},
+		})
+	})
+	if errors.Is(err, ErrDuplicateSlip) || errors.Is(err, ErrBillAlreadyVerified) {
+		return nil, verResult, err
+	}
 	if err != nil {
 		return nil, nil, err
 	}
@@ -194,68 +369,24 @@ func (s *Service) SubmitBillProof(ctx context.Context, req SubmitBillProofReques
 		return updated, verResult, ErrVerificationFailed
 	}
 
-	return updated, verResult, nil	
+	return updated, verResult, nil
 }
 
-// func (s *Service) SubmitBillProof(ctx context.Context, req SubmitBillProofRequest) (*Bill, *SlipVerificationResult, error) {
-// 	if req.BillID <= 0 {
-// 		return nil, nil, errors.New("invalid bill_id")
-// 	}
-// 	if req.MemberID == "" {
-// 		return nil, nil, ErrInvalidMemberID
-// 	}
-// 	if len(req.ImageBytes) == 0 {
-// 		return nil, nil, ErrSlipTooSmall
-// 	}
-
-// 	// 1) Load bill
-// 	b, err := s.store.GetBillByID(ctx, req.BillID)
-// 	if err != nil {
-// 		return nil, nil, ErrBillNotFound
-// 	}
-// 	if b.MemberID != req.MemberID {
-// 		return nil, nil, ErrBillMemberMismatch
-// 	}
-// 	if b.Status == BillStatusVerified || b.Status == BillStatusRejected || b.Status == BillStatusCanceled {
-// 		return nil, nil, ErrBillAlreadyVerified
-// 	}
-
-// 	verResult, err := s.callEasySlipVerify(ctx, req.ImageBytes, req.FileName)
-// 	if err != nil {
-// 		return nil, nil, err
-// 	}
-
-// 	amountFromSlip := verResult.MatchedAmount
-
-// 	now := time.Now().UTC()
-// 	b.AmountPaid = amountFromSlip
-// 	b.UpdatedAt = now
-// 	b.SubmittedAt = &now
-
-// 	if len(verResult.RawResponse) > 0 {
-// 		b.ProofJSON = string(verResult.RawResponse)
-// 	}
-
-// 	if verResult.IsValid && amountFromSlip+0.001 >= b.AmountDue {
-// 		b.Status = BillStatusVerified
-// 		b.VerifiedAt = &now
-// 	} else if !verResult.IsValid {
-// 		b.Status = BillStatusRejected
-// 		b.RejectedAt = &now
-// 	} else {
-// 		// valid slip but underpaid
-// 		b.Status = BillStatusSubmitted
-// 	}
-
-// 	updated, err := s.store.UpdateBill(ctx, *b)
-// 	if err != nil {
-// 		return nil, nil, err
-// 	}
-
-// 	if !verResult.IsValid {
-// 		return updated, verResult, ErrVerificationFailed
-// 	}
-
-// 	return updated, verResult, nil
-// }
+func hasMember(g *group.Group, memberID string) bool {
+	for _, m := range g.Members {
+		if m.MemberID == memberID {
+			return true
+		}
+	}
+	return false
+}
 
+// slipAmountInBillCurrency converts the transferred amount using the rate
+// snapshot on the bill. When the slip already reports the amount in the bill
+// currency (cross-border transfers), that figure is taken as is.
+func slipAmountInBillCurrency(b *bill.Bill, res *SlipVerificationResult) (float64, error) {
+	if res.localCurrency != "" && res.localCurrency == b.Currency {
+		return res.localAmount, nil
+	}
+	return b.ToBillCurrency(res.MatchedAmount, res.Currency)
+}
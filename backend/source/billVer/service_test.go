@@ -0,0 +1,43 @@
+package billver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCallEasySlipVerifyDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		date    string
+		wantErr error
+	}{
+		{"valid", "2026-03-15T12:00:00+07:00", nil},
+		{"missing", "", nil},
+		{"unreadable", "15/03/2026 12:00", ErrSlipDateInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"status":200,"data":{"transRef":"REF1","date":"` + tt.date + `","amount":{"amount":100}}}`))
+			}))
+			defer srv.Close()
+
+			s := NewService(nil, nil, srv.Client(), srv.URL, "token")
+			res, err := s.callEasySlipVerify(context.Background(), []byte("slip"), "slip.jpg")
+			if err != nil {
+				t.Fatalf("callEasySlipVerify: %v", err)
+			}
+			if !errors.Is(res.dateErr, tt.wantErr) || (tt.wantErr == nil) != (res.dateErr == nil) {
+				t.Fatalf("dateErr = %v, want %v", res.dateErr, tt.wantErr)
+			}
+			if tt.date != "" && tt.wantErr == nil && res.TransferredAt.IsZero() {
+				t.Errorf("TransferredAt not set")
+			}
+		})
+	}
+}
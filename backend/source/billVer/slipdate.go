@@ -0,0 +1,82 @@
+package billver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+)
+
+// slips come from Thai banks, so bill cycles are counted in Bangkok time
+var cycleLocation = time.FixedZone("ICT", 7*60*60)
+
+// SlipDatePolicy decides which transfer dates are accepted for a bill.
+type SlipDatePolicy struct {
+	GraceBefore time.Duration // how early before the cycle starts a transfer may be
+	GraceAfter  time.Duration // how late after the cycle ends a transfer may be
+	FutureSkew  time.Duration // tolerated clock difference with the bank
+}
+
+func DefaultSlipDatePolicy() SlipDatePolicy {
+	return SlipDatePolicy{
+		GraceBefore: 7 * 24 * time.Hour,
+		GraceAfter:  60 * 24 * time.Hour,
+		FutureSkew:  10 * time.Minute,
+	}
+}
+
+// SlipDateError tells why a transfer date was refused. It unwraps to
+// ErrSlipPredatesBill, ErrSlipInFuture or ErrSlipOutsideCycle.
+type SlipDateError struct {
+	Err           error     `json:"-"`
+	TransferredAt time.Time `json:"transferred_at"`
+	WindowStart   time.Time `json:"window_start"`
+	WindowEnd     time.Time `json:"window_end"`
+}
+
+func (e *SlipDateError) Error() string {
+	return fmt.Sprintf("%s: transferred %s, accepted %s to %s",
+		e.Err, e.TransferredAt.Format(time.RFC3339),
+		e.WindowStart.Format(time.RFC3339), e.WindowEnd.Format(time.RFC3339))
+}
+
+func (e *SlipDateError) Unwrap() error {
+	return e.Err
+}
+
+// CycleWindow returns the calendar month a bill is issued for.
+func CycleWindow(b bill.Bill) (start, end time.Time) {
+	start = time.Date(b.Year, time.Month(b.Month), 1, 0, 0, 0, 0, cycleLocation)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// Check returns nil when transferredAt falls inside the bill cycle widened by
+// the policy grace periods and is not in the future.
+func (p SlipDatePolicy) Check(b bill.Bill, transferredAt, now time.Time) error {
+	if transferredAt.IsZero() {
+		return ErrSlipDateMissing
+	}
+
+	start, end := CycleWindow(b)
+	start = start.Add(-p.GraceBefore)
+	end = end.Add(p.GraceAfter)
+
+	dateErr := &SlipDateError{
+		TransferredAt: transferredAt,
+		WindowStart:   start,
+		WindowEnd:     end,
+	}
+
+	switch {
+	case transferredAt.After(now.Add(p.FutureSkew)):
+		dateErr.Err = ErrSlipInFuture
+	case transferredAt.Before(start):
+		dateErr.Err = ErrSlipPredatesBill
+	case !transferredAt.Before(end):
+		dateErr.Err = ErrSlipOutsideCycle
+	default:
+		return nil
+	}
+
+	return dateErr
+}
@@ -0,0 +1,66 @@
+package billver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+)
+
+func TestSlipDatePolicyCheck(t *testing.T) {
+	policy := SlipDatePolicy{
+		GraceBefore: 7 * 24 * time.Hour,
+		GraceAfter:  10 * 24 * time.Hour,
+		FutureSkew:  10 * time.Minute,
+	}
+	b := bill.Bill{Year: 2026, Month: 3}
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, cycleLocation)
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, cycleLocation)
+	}
+
+	tests := []struct {
+		name          string
+		transferredAt time.Time
+		now           time.Time
+		want          error
+	}{
+		{"inside the cycle", at(time.March, 15, 12), now, nil},
+		{"first moment of the cycle", at(time.March, 1, 0), now, nil},
+		{"within grace before", at(time.February, 23, 0), now, nil},
+		{"before grace", at(time.February, 21, 23), now, ErrSlipPredatesBill},
+		{"within grace after", at(time.April, 10, 23), now, nil},
+		{"after grace", at(time.April, 11, 0), now, ErrSlipOutsideCycle},
+		{"within clock skew", at(time.March, 15, 12).Add(5 * time.Minute), at(time.March, 15, 12), nil},
+		{"in the future", at(time.March, 15, 13), at(time.March, 15, 12), ErrSlipInFuture},
+		{"missing", time.Time{}, now, ErrSlipDateMissing},
+		// cycles are Bangkok months; 17:30 UTC on the last day is already April there
+		{"cycle boundary in UTC", time.Date(2026, 3, 31, 17, 30, 0, 0, time.UTC), now, nil},
+		{"previous month in Bangkok", time.Date(2026, 2, 28, 16, 59, 0, 0, time.UTC), now, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(b, tt.transferredAt, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Check = %v, want %v", err, tt.want)
+			}
+
+			var dateErr *SlipDateError
+			if tt.want != nil && tt.want != ErrSlipDateMissing && !errors.As(err, &dateErr) {
+				t.Errorf("Check = %T, want *SlipDateError", err)
+			}
+		})
+	}
+}
+
+func TestCycleWindow(t *testing.T) {
+	start, end := CycleWindow(bill.Bill{Year: 2026, Month: 12})
+	if want := time.Date(2026, 12, 1, 0, 0, 0, 0, cycleLocation); !start.Equal(want) {
+		t.Errorf("start = %s, want %s", start, want)
+	}
+	if want := time.Date(2027, 1, 1, 0, 0, 0, 0, cycleLocation); !end.Equal(want) {
+		t.Errorf("end = %s, want %s", end, want)
+	}
+}
@@ -0,0 +1,15 @@
+package billver
+
+import (
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+)
+
+func (req SubmitBillProofRequest) Validate() error {
+	var v validate.Validator
+	v.Check(req.BillID > 0, "bill_id", bill.ErrInvalidBillID)
+	v.Snowflake("member_id", req.MemberID)
+	v.Check(req.AmountPaid >= 0, "amount_paid", bill.ErrInvalidAmount)
+	v.Check(len(req.ImageBytes) > 0, "file", ErrSlipTooSmall)
+	return v.Err()
+}
@@ -0,0 +1,141 @@
+// Package client is a Go client for the subShare API. The methods in
+// client_gen.go are generated from the API's endpoint table; this file holds
+// the transport they share.
+package client
+
+//go:generate go run ../../cmd/subShare-api openapi -client client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// actorHeader names the caller in the audit log, like the bot does.
+const actorHeader = "X-Actor-ID"
+
+type actorKey struct{}
+
+// WithActor makes requests sent with ctx carry the Discord ID of the user
+// acting.
+func WithActor(ctx context.Context, discordID string) context.Context {
+	return context.WithValue(ctx, actorKey{}, discordID)
+}
+
+type versionKey struct{}
+
+// WithVersion makes group updates sent with ctx carry If-Match for version,
+// so they fail with 412 version_mismatch when the group changed since it was
+// read.
+func WithVersion(ctx context.Context, version int64) context.Context {
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey makes requests sent with ctx carry key, so the server
+// runs a retried request once and answers repeats with the first response.
+// Use a new key for every logical request.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// Client calls the API at BaseURL, e.g. http://localhost:8080.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Error is a non-2xx answer, decoded from the API's error envelope.
+type Error struct {
+	Status  int
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("subShare API: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// doJSON sends body as JSON.
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, path, query, bytes.NewReader(data), "application/json", out)
+}
+
+// do sends the request and decodes a JSON answer into out. A *[]byte out
+// gets the raw body instead, for downloads.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string, out any) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		req.Header.Set(actorHeader, actor)
+	}
+	if version, ok := ctx.Value(versionKey{}).(int64); ok {
+		req.Header.Set("If-Match", strconv.Quote(strconv.FormatInt(version, 10)))
+	}
+	if key, ok := ctx.Value(idempotencyKey{}).(string); ok && key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode >= 300 {
+		apiErr := &Error{Status: res.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Code == "" {
+			apiErr.Code = "unknown"
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+
+	switch out := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*out = data
+		return nil
+	default:
+		return json.Unmarshal(data, out)
+	}
+}
@@ -0,0 +1,499 @@
+// Code generated by subShare-api openapi; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/expense"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+	"github.com/NoNiiEa/subShare-Discord/source/portable"
+	"github.com/NoNiiEa/subShare-Discord/source/settlement"
+)
+
+// Health calls GET /health.
+//
+// Report that the server is up.
+func (c *Client) Health(ctx context.Context) (map[string]string, error) {
+	path := "/health"
+	var out map[string]string
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OpenAPI calls GET /openapi.json.
+//
+// This document.
+func (c *Client) OpenAPI(ctx context.Context) (map[string]any, error) {
+	path := "/openapi.json"
+	var out map[string]any
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateGroup calls POST /groups.
+//
+// Create a group.
+func (c *Client) CreateGroup(ctx context.Context, body group.CreateGroupRequest) (*group.Group, error) {
+	path := "/groups"
+	var out group.Group
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ImportGroup calls POST /groups/import.
+//
+// Recreate a group from an export document.
+// Query parameters: guild_id.
+func (c *Client) ImportGroup(ctx context.Context, query url.Values, body portable.Document) (*portable.ImportResult, error) {
+	path := "/groups/import"
+	var out portable.ImportResult
+	if err := c.doJSON(ctx, "POST", path, query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetGroup calls GET /groups/{id}.
+//
+// Get a group with its members' balances. The ETag is the group's version.
+func (c *Client) GetGroup(ctx context.Context, id int64) (*group.Group, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10)
+	var out group.Group
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteGroup calls DELETE /groups/{id}.
+//
+// Delete a group.
+func (c *Client) DeleteGroup(ctx context.Context, id int64) error {
+	path := "/groups/" + strconv.FormatInt(id, 10)
+	return c.do(ctx, "DELETE", path, nil, nil, "", nil)
+}
+
+// UpdateGroup calls PUT /groups/{id}.
+//
+// Replace a group's settings. Members change through invites only.
+func (c *Client) UpdateGroup(ctx context.Context, id int64, body group.UpdateGroupRequest) (*group.Group, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10)
+	var out group.Group
+	if err := c.doJSON(ctx, "PUT", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PatchGroup calls PATCH /groups/{id}.
+//
+// Change some of a group's settings with a JSON merge patch (application/merge-patch+json) of UpdateGroupRequest.
+func (c *Client) PatchGroup(ctx context.Context, id int64, body map[string]any) (*group.Group, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10)
+	var out group.Group
+	if err := c.doJSON(ctx, "PATCH", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// InviteGroup calls POST /groups/{id}/invite.
+//
+// Invite members to a group.
+func (c *Client) InviteGroup(ctx context.Context, id int64, body group.InviteGroupRequest) (*group.Group, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/invite"
+	var out group.Group
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AcceptInvite calls POST /groups/{id}/accept-invite.
+//
+// Accept an invite to a group.
+func (c *Client) AcceptInvite(ctx context.Context, id int64, body group.AcceptInviteRequest) (*group.Group, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/accept-invite"
+	var out group.Group
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MarkAsPaid calls POST /groups/{GroupID}/member/{MemberID}/pay.
+//
+// Credit a payment to a member's ledger.
+func (c *Client) MarkAsPaid(ctx context.Context, groupID int64, memberID string, body group.MarkAsPaidRequest) (*group.GroupMember, error) {
+	path := "/groups/" + strconv.FormatInt(groupID, 10) + "/member/" + url.PathEscape(memberID) + "/pay"
+	var out group.GroupMember
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMemberCredit calls GET /groups/{GroupID}/member/{MemberID}/credit.
+//
+// Get a member's credit in a group.
+func (c *Client) GetMemberCredit(ctx context.Context, groupID int64, memberID string) (*group.MemberCredit, error) {
+	path := "/groups/" + strconv.FormatInt(groupID, 10) + "/member/" + url.PathEscape(memberID) + "/credit"
+	var out group.MemberCredit
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RefundCredit calls POST /groups/{GroupID}/member/{MemberID}/credit/refund.
+//
+// Pay back part of a member's credit.
+func (c *Client) RefundCredit(ctx context.Context, groupID int64, memberID string, body group.RefundCreditRequest) (*group.MemberCredit, error) {
+	path := "/groups/" + strconv.FormatInt(groupID, 10) + "/member/" + url.PathEscape(memberID) + "/credit/refund"
+	var out group.MemberCredit
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListGroupBills calls GET /groups/{id}/bill.
+//
+// List a group's bills, newest first.
+// Query parameters: member, status, kind, from, to, cursor, limit, include.
+func (c *Client) ListGroupBills(ctx context.Context, id int64, query url.Values) (*bill.BillPage, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/bill"
+	var out bill.BillPage
+	if err := c.do(ctx, "GET", path, query, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMemberLedger calls GET /groups/{id}/members/{memberID}/ledger.
+//
+// Get a member's ledger entries and balance.
+func (c *Client) GetMemberLedger(ctx context.Context, id int64, memberID string) (*group.MemberLedger, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/members/" + url.PathEscape(memberID) + "/ledger"
+	var out group.MemberLedger
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetGroupReport calls GET /groups/{id}/report.
+//
+// Collection report per month and per member, as JSON or CSV.
+// Query parameters: from, to, format, view.
+func (c *Client) GetGroupReport(ctx context.Context, id int64, query url.Values) (*group.GroupReport, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/report"
+	var out group.GroupReport
+	if err := c.do(ctx, "GET", path, query, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAuditLog calls GET /groups/{id}/audit.
+//
+// List a group's audit events, newest first. Owner only.
+// Query parameters: owner_id, action, cursor, limit.
+func (c *Client) GetAuditLog(ctx context.Context, id int64, query url.Values) (*audit.Page, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/audit"
+	var out audit.Page
+	if err := c.do(ctx, "GET", path, query, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExportGroup calls GET /groups/{id}/export.
+//
+// Export a group with its bills, payments and ledger.
+func (c *Client) ExportGroup(ctx context.Context, id int64) (*portable.Document, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/export"
+	var out portable.Document
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExportGroupBills calls GET /groups/{id}/export/bills.
+//
+// Export a group's bills as CSV or XLSX.
+// Query parameters: format, from, to.
+func (c *Client) ExportGroupBills(ctx context.Context, id int64, query url.Values) ([]byte, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/export/bills"
+	var out []byte
+	if err := c.do(ctx, "GET", path, query, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExportGroupLedger calls GET /groups/{id}/export/ledger.
+//
+// Export a group's ledger as CSV or XLSX.
+// Query parameters: format, member.
+func (c *Client) ExportGroupLedger(ctx context.Context, id int64, query url.Values) ([]byte, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/export/ledger"
+	var out []byte
+	if err := c.do(ctx, "GET", path, query, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImportPayments calls POST /groups/{id}/import/payments.
+//
+// Import historical payments from CSV. Owner only. Answers 422 with the row errors.
+// Query parameters: owner_id, dry_run.
+func (c *Client) ImportPayments(ctx context.Context, id int64, query url.Values, body io.Reader, contentType string) (*bill.ImportResult, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/import/payments"
+	var out bill.ImportResult
+	if err := c.do(ctx, "POST", path, query, body, contentType, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateExpense calls POST /groups/{id}/expenses.
+//
+// Record a one-off expense and bill the participants.
+func (c *Client) CreateExpense(ctx context.Context, id int64, body expense.CreateExpenseRequest) (*expense.Expense, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/expenses"
+	var out expense.Expense
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListExpenses calls GET /groups/{id}/expenses.
+//
+// List a group's expenses.
+func (c *Client) ListExpenses(ctx context.Context, id int64) ([]expense.Expense, error) {
+	path := "/groups/" + strconv.FormatInt(id, 10) + "/expenses"
+	var out []expense.Expense
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListMemberBills calls GET /member/{id}/bill.
+//
+// List a member's bills, newest first.
+// Query parameters: group, status, kind, from, to, cursor, limit, include.
+func (c *Client) ListMemberBills(ctx context.Context, id string, query url.Values) (*bill.BillPage, error) {
+	path := "/member/" + url.PathEscape(id) + "/bill"
+	var out bill.BillPage
+	if err := c.do(ctx, "GET", path, query, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListMemberGroups calls GET /member/{id}/groups.
+//
+// List the groups of a member.
+// Query parameters: guild, status, owner, due_day, sort, cursor, limit.
+func (c *Client) ListMemberGroups(ctx context.Context, id string, query url.Values) (*group.GroupPage, error) {
+	path := "/member/" + url.PathEscape(id) + "/groups"
+	var out group.GroupPage
+	if err := c.do(ctx, "GET", path, query, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMemberSummary calls GET /member/{id}/summary.
+//
+// What a member owes and is owed across groups.
+func (c *Client) GetMemberSummary(ctx context.Context, id string) (*group.MemberSummary, error) {
+	path := "/member/" + url.PathEscape(id) + "/summary"
+	var out group.MemberSummary
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListGuildGroups calls GET /guilds/{guildID}/groups.
+//
+// List the groups of a guild.
+// Query parameters: member, status, owner, due_day, sort, cursor, limit.
+func (c *Client) ListGuildGroups(ctx context.Context, guildID string, query url.Values) (*group.GroupPage, error) {
+	path := "/guilds/" + url.PathEscape(guildID) + "/groups"
+	var out group.GroupPage
+	if err := c.do(ctx, "GET", path, query, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SettleUp calls GET /guilds/{guildID}/settle-up.
+//
+// Net the guild's outstanding bills into transfers.
+func (c *Client) SettleUp(ctx context.Context, guildID string) (*settlement.Plan, error) {
+	path := "/guilds/" + url.PathEscape(guildID) + "/settle-up"
+	var out settlement.Plan
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RecordSettlement calls POST /guilds/{guildID}/settlements.
+//
+// Confirm the settle-up transfers; the bills close once every payee has.
+func (c *Client) RecordSettlement(ctx context.Context, guildID string, body settlement.RecordSettlementRequest) (*settlement.Settlement, error) {
+	path := "/guilds/" + url.PathEscape(guildID) + "/settlements"
+	var out settlement.Settlement
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSettlements calls GET /guilds/{guildID}/settlements.
+//
+// List a guild's settlements.
+func (c *Client) ListSettlements(ctx context.Context, guildID string) ([]settlement.Settlement, error) {
+	path := "/guilds/" + url.PathEscape(guildID) + "/settlements"
+	var out []settlement.Settlement
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ResetPayment calls POST /test/due-day/{DueDay}.
+//
+// Issue the bills of groups due on a day, as the daily job does.
+func (c *Client) ResetPayment(ctx context.Context, dueDay int) error {
+	path := "/test/due-day/" + strconv.FormatInt(int64(dueDay), 10)
+	return c.do(ctx, "POST", path, nil, nil, "", nil)
+}
+
+// SubmitBill calls POST /bill/{id}/pay.
+//
+// Pay a bill with a bank slip.
+func (c *Client) SubmitBill(ctx context.Context, id int64, body io.Reader, contentType string) (*bill.Bill, error) {
+	path := "/bill/" + strconv.FormatInt(id, 10) + "/pay"
+	var out bill.Bill
+	if err := c.do(ctx, "POST", path, nil, body, contentType, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListBillPayments calls GET /bill/{id}/payments.
+//
+// List the payments on a bill.
+func (c *Client) ListBillPayments(ctx context.Context, id int64) ([]bill.Payment, error) {
+	path := "/bill/" + strconv.FormatInt(id, 10) + "/payments"
+	var out []bill.Payment
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPaymentAttachment calls GET /bill/{id}/payments/{paymentID}/attachment.
+//
+// Download the receipt attached to a manual payment.
+func (c *Client) GetPaymentAttachment(ctx context.Context, id int64, paymentID int64) ([]byte, error) {
+	path := "/bill/" + strconv.FormatInt(id, 10) + "/payments/" + strconv.FormatInt(paymentID, 10) + "/attachment"
+	var out []byte
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RecordManualPayment calls POST /bill/{id}/manual-payment.
+//
+// Record a cash or other off-slip payment. Owner only. Send multipart to attach a receipt.
+func (c *Client) RecordManualPayment(ctx context.Context, id int64, body group.RecordPaymentRequest) (*group.RecordPaymentResult, error) {
+	path := "/bill/" + strconv.FormatInt(id, 10) + "/manual-payment"
+	var out group.RecordPaymentResult
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// TransitionBill calls POST /bill/{id}/transition.
+//
+// Move a bill to another status. Owner only.
+func (c *Client) TransitionBill(ctx context.Context, id int64, body bill.TransitionBillRequest) (*bill.Bill, error) {
+	path := "/bill/" + strconv.FormatInt(id, 10) + "/transition"
+	var out bill.Bill
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBillHistory calls GET /bill/{id}/history.
+//
+// List a bill's status changes.
+func (c *Client) GetBillHistory(ctx context.Context, id int64) ([]bill.BillEvent, error) {
+	path := "/bill/" + strconv.FormatInt(id, 10) + "/history"
+	var out []bill.BillEvent
+	if err := c.do(ctx, "GET", path, nil, nil, "", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CancelBill calls POST /bill/{id}/cancel.
+//
+// Cancel a bill. Owner only.
+func (c *Client) CancelBill(ctx context.Context, id int64, body group.BillActionRequest) (*bill.Bill, error) {
+	path := "/bill/" + strconv.FormatInt(id, 10) + "/cancel"
+	var out bill.Bill
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// WaiveBill calls POST /bill/{id}/waive.
+//
+// Forgive what is left on a bill. Owner only.
+func (c *Client) WaiveBill(ctx context.Context, id int64, body group.BillActionRequest) (*bill.Bill, error) {
+	path := "/bill/" + strconv.FormatInt(id, 10) + "/waive"
+	var out bill.Bill
+	if err := c.doJSON(ctx, "POST", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AmendBill calls PATCH /bill/{id}.
+//
+// Change the amount or description of an open bill. Owner only.
+func (c *Client) AmendBill(ctx context.Context, id int64, body group.AmendBillRequest) (*bill.Bill, error) {
+	path := "/bill/" + strconv.FormatInt(id, 10)
+	var out bill.Bill
+	if err := c.doJSON(ctx, "PATCH", path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,10 @@
+package currency
+
+import "errors"
+
+var (
+	ErrInvalidCurrency     = errors.New("currency must be a 3-letter ISO 4217 code")
+	ErrUnsupportedCurrency = errors.New("no exchange rate for currency")
+	ErrNoRateProvider      = errors.New("no exchange rate provider configured")
+	ErrInvalidRatesFile    = errors.New("invalid rates file")
+)
@@ -0,0 +1,69 @@
+package currency
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Settlement is the currency slips are paid in; Thai bank transfers are
+// always THB.
+const Settlement = "THB"
+
+// Rate converts one unit of From into Value units of To.
+type Rate struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Value  float64   `json:"value"`
+	Source string    `json:"source"`
+	AsOf   time.Time `json:"as_of"`
+}
+
+// ratesFile is the format read by StaticFileProvider: every rate is the price
+// of one unit of the currency in Base.
+//
+//	{"base": "THB", "as_of": "2026-10-01T00:00:00Z", "rates": {"USD": 35.2}}
+type ratesFile struct {
+	Base  string             `json:"base"`
+	AsOf  time.Time          `json:"as_of"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Minor is an amount in hundredths of a currency unit, i.e. satang or cents,
+// so ledgers add up exactly. Currencies without a minor unit just carry
+// zeros. In JSON it is the plain decimal amount, e.g. 33.34.
+type Minor int64
+
+// ToMinor rounds an amount to the nearest hundredth.
+func ToMinor(amount float64) Minor {
+	return Minor(math.Round(amount * 100))
+}
+
+// Major is the amount in whole units, e.g. 33.34.
+func (m Minor) Major() float64 {
+	return float64(m) / 100
+}
+
+// String formats the amount with two decimals, e.g. "33.30".
+func (m Minor) String() string {
+	return strconv.FormatFloat(m.Major(), 'f', 2, 64)
+}
+
+func (m Minor) MarshalJSON() ([]byte, error) {
+	return strconv.AppendFloat(nil, m.Major(), 'f', -1, 64), nil
+}
+
+func (m *Minor) UnmarshalJSON(data []byte) error {
+	var amount float64
+	if err := json.Unmarshal(data, &amount); err != nil {
+		return err
+	}
+	*m = ToMinor(amount)
+	return nil
+}
+
+// OpenAPIType tells the spec that Minor is sent as a number, not an integer.
+func (Minor) OpenAPIType() (typ, format string) {
+	return "number", "double"
+}
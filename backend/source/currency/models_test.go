@@ -0,0 +1,34 @@
+package currency
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMinorJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Minor
+		out  string
+	}{
+		{in: `120`, want: 12000, out: `120`},
+		{in: `4.99`, want: 499, out: `4.99`},
+		{in: `0.1`, want: 10, out: `0.1`},
+		{in: `-2.5`, want: -250, out: `-2.5`},
+		{in: `1.006`, want: 101, out: `1.01`},
+	}
+
+	for _, tt := range tests {
+		var m Minor
+		if err := json.Unmarshal([]byte(tt.in), &m); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tt.in, err)
+		}
+		if m != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, m, tt.want)
+		}
+		out, err := json.Marshal(m)
+		if err != nil || string(out) != tt.out {
+			t.Errorf("Marshal(%d) = %s, %v; want %s", m, out, err, tt.out)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// RateProvider looks up exchange rates. Implementations may call out to a
+// rates API; StaticFileProvider works offline.
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string, at time.Time) (Rate, error)
+}
+
+// Normalize upper-cases a currency code and checks its shape.
+func Normalize(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", ErrInvalidCurrency
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", ErrInvalidCurrency
+		}
+	}
+	return code, nil
+}
+
+// Lookup returns the rate from -> to, answering same-currency lookups without
+// a provider.
+func Lookup(ctx context.Context, p RateProvider, from, to string, at time.Time) (Rate, error) {
+	if from == to {
+		return Rate{From: from, To: to, Value: 1, Source: "identity", AsOf: at}, nil
+	}
+	if p == nil {
+		return Rate{}, ErrNoRateProvider
+	}
+	return p.Rate(ctx, from, to, at)
+}
+
+type StaticFileProvider struct {
+	path  string
+	rates ratesFile
+}
+
+// NewStaticFileProvider loads a JSON rates file once; see ratesFile for the
+// format.
+func NewStaticFileProvider(path string) (*StaticFileProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rf ratesFile
+	if err := json.Unmarshal(data, &rf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRatesFile, err)
+	}
+
+	base, err := Normalize(rf.Base)
+	if err != nil {
+		return nil, fmt.Errorf("%w: base: %v", ErrInvalidRatesFile, err)
+	}
+	rf.Base = base
+
+	rates := make(map[string]float64, len(rf.Rates)+1)
+	for code, v := range rf.Rates {
+		c, err := Normalize(code)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("%w: rate for %q", ErrInvalidRatesFile, code)
+		}
+		rates[c] = v
+	}
+	rates[base] = 1
+	rf.Rates = rates
+
+	return &StaticFileProvider{path: path, rates: rf}, nil
+}
+
+func (p *StaticFileProvider) Rate(ctx context.Context, from, to string, at time.Time) (Rate, error) {
+	fromRate, ok := p.rates.Rates[from]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, from)
+	}
+	toRate, ok := p.rates.Rates[to]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, to)
+	}
+
+	asOf := p.rates.AsOf
+	if asOf.IsZero() {
+		asOf = at
+	}
+
+	return Rate{
+		From:   from,
+		To:     to,
+		Value:  fromRate / toRate,
+		Source: "file:" + p.path,
+		AsOf:   asOf,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+)
+
+const createAuditEventsTable = `
+CREATE TABLE IF NOT EXISTS audit_events (
+    id           INTEGER PRIMARY KEY,
+    group_id     INTEGER,               -- NULL for actions outside a group
+    actor_id     TEXT NOT NULL,
+    action       TEXT NOT NULL,
+    entity_type  TEXT NOT NULL,
+    entity_id    TEXT NOT NULL,
+    changes_json TEXT,                  -- {"field": {"before": ..., "after": ...}}
+    request_id   TEXT,
+    created_at   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_group_id ON audit_events(group_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+`
+
+func (s *SQLiteStore) NextAuditEventID(ctx context.Context) (int64, error) {
+	const q = `SELECT COALESCE(MAX(id), 0) + 1 FROM audit_events;`
+
+	var nextID int64
+	if err := s.conn(ctx).QueryRowContext(ctx, q).Scan(&nextID); err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
+
+func (s *SQLiteStore) SaveAuditEvent(ctx context.Context, e audit.Event) error {
+	const q = `
+INSERT INTO audit_events (id, group_id, actor_id, action, entity_type, entity_id, changes_json, request_id, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+`
+
+	var groupID any
+	if e.GroupID != 0 {
+		groupID = e.GroupID
+	}
+
+	_, err := s.conn(ctx).ExecContext(ctx, q,
+		e.ID,
+		groupID,
+		e.ActorID,
+		string(e.Action),
+		e.EntityType,
+		e.EntityID,
+		nullableString(string(e.Changes)),
+		nullableString(e.RequestID),
+		e.CreatedAt.Format(time.RFC3339),
+	)
+	return err
+}
+
+// ListAuditEvents returns up to q.Limit+1 events, newest first.
+func (s *SQLiteStore) ListAuditEvents(ctx context.Context, q audit.Query) ([]audit.Event, error) {
+	var (
+		where []string
+		args  []any
+	)
+
+	if q.GroupID != 0 {
+		where = append(where, "group_id = ?")
+		args = append(args, q.GroupID)
+	}
+	if q.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, string(q.Action))
+	}
+	if q.BeforeID != 0 {
+		where = append(where, "id < ?")
+		args = append(args, q.BeforeID)
+	}
+
+	query := `
+SELECT id, group_id, actor_id, action, entity_type, entity_id, changes_json, request_id, created_at
+FROM audit_events`
+	if len(where) > 0 {
+		query += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	query += "\nORDER BY id DESC\nLIMIT ?;"
+	args = append(args, q.Limit+1)
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []audit.Event{}
+	for rows.Next() {
+		var (
+			e         audit.Event
+			groupID   *int64
+			changes   *string
+			requestID *string
+			createdAt string
+		)
+		if err := rows.Scan(&e.ID, &groupID, &e.ActorID, &e.Action, &e.EntityType, &e.EntityID, &changes, &requestID, &createdAt); err != nil {
+			return nil, err
+		}
+		if groupID != nil {
+			e.GroupID = *groupID
+		}
+		if changes != nil {
+			e.Changes = []byte(*changes)
+		}
+		e.RequestID = derefString(requestID)
+		e.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		result = append(result, e)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteAuditEventsBefore(ctx context.Context, t time.Time) (int64, error) {
+	const q = `DELETE FROM audit_events WHERE created_at < ?;`
+
+	res, err := s.conn(ctx).ExecContext(ctx, q, t.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,286 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	backupPrefix     = "subshare-"
+	backupExt        = ".db"
+	backupTimeLayout = "20060102T150405Z"
+
+	// a step copies backupStepPages pages, then waits backupStepPause for
+	// the server's writes
+	backupStepPages = 256
+	backupStepPause = 10 * time.Millisecond
+)
+
+var (
+	ErrNotSQLite          = errors.New("connection is not a sqlite3 connection")
+	ErrNotSubShareBackup  = errors.New("file is not a subShare database")
+	ErrNewerSchema        = errors.New("backup was written by a newer version of subShare")
+	ErrCorruptBackup      = errors.New("backup failed the integrity check")
+	ErrBackupTargetExists = errors.New("backup file already exists")
+)
+
+// BackupFileName is the timestamped name backups are written under.
+func BackupFileName(now time.Time) string {
+	return backupPrefix + now.UTC().Format(backupTimeLayout) + backupExt
+}
+
+// Backup copies the database at srcPath to destPath with SQLite's online
+// backup API, so the server can keep running. It reads through a connection of
+// its own, backupStepPages at a time, so the server's connection stays free
+// and its writes get in between steps. destPath must not exist yet.
+func Backup(ctx context.Context, srcPath, destPath string) error {
+	if _, err := os.Stat(destPath); err == nil {
+		return ErrBackupTargetExists
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return err
+	}
+
+	src, err := sql.Open("sqlite3", "file:"+srcPath+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dest, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	err = destConn.Raw(func(destRaw any) error {
+		return srcConn.Raw(func(srcRaw any) error {
+			d, ok := destRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return ErrNotSQLite
+			}
+			s, ok := srcRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return ErrNotSQLite
+			}
+
+			b, err := d.Backup("main", s, "main")
+			if err != nil {
+				return err
+			}
+
+			// the source is only locked during a step; a write in between
+			// makes SQLite start the copy over
+			for {
+				done, err := b.Step(backupStepPages)
+				if err != nil {
+					b.Close()
+					return err
+				}
+				if done {
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					b.Close()
+					return ctx.Err()
+				case <-time.After(backupStepPause):
+				}
+			}
+			return b.Finish()
+		})
+	})
+	if err != nil {
+		destConn.Close()
+		dest.Close()
+		os.Remove(destPath)
+		return fmt.Errorf("backup to %s: %w", destPath, err)
+	}
+
+	return nil
+}
+
+// BackupToDir writes a timestamped backup of the database at srcPath into dir
+// and returns its path.
+func BackupToDir(ctx context.Context, srcPath, dir string, now time.Time) (string, error) {
+	path := filepath.Join(dir, BackupFileName(now))
+	if err := Backup(ctx, srcPath, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// PruneBackups deletes the oldest backups in dir so that at most keep remain.
+// Only files named like BackupFileName are considered.
+func PruneBackups(dir string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupExt) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupExt)
+		if _, err := time.Parse(backupTimeLayout, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, name)
+	}
+
+	// the timestamp layout sorts chronologically
+	sort.Strings(backups)
+
+	var removed []string
+	for len(backups) > keep {
+		path := filepath.Join(dir, backups[0])
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+		backups = backups[1:]
+	}
+
+	return removed, nil
+}
+
+// CheckBackup opens a backup read-only, runs an integrity check and returns
+// its schema version. Backups from older versions are accepted: InitSchema
+// upgrades them on the next start.
+func CheckBackup(ctx context.Context, path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRowContext(ctx, `PRAGMA integrity_check;`).Scan(&result); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrNotSubShareBackup, err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("%w: %s", ErrCorruptBackup, result)
+	}
+
+	var version int
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version;`).Scan(&version); err != nil {
+		return 0, err
+	}
+	if version > SchemaVersion {
+		return version, fmt.Errorf("%w (schema %d, this build supports %d)", ErrNewerSchema, version, SchemaVersion)
+	}
+
+	var tables int
+	const q = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('groups', 'bills');`
+	if err := db.QueryRowContext(ctx, q).Scan(&tables); err != nil {
+		return 0, err
+	}
+	if tables != 2 {
+		return version, ErrNotSubShareBackup
+	}
+
+	return version, nil
+}
+
+// Restore replaces the database at dbPath with a checked copy of backupPath.
+// The server must not be running. The replaced database is kept next to it
+// and its path returned, empty when there was none.
+func Restore(ctx context.Context, backupPath, dbPath string, now time.Time) (string, error) {
+	_, err := CheckBackup(ctx, backupPath)
+	if err != nil {
+		return "", err
+	}
+
+	// copy next to the target first so the final swap is a rename on the
+	// same filesystem
+	tmpPath := dbPath + ".restoring"
+	if err = copyFile(backupPath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+
+	var previous string
+	if _, err := os.Stat(dbPath); err == nil {
+		previous = dbPath + ".pre-restore-" + now.UTC().Format(backupTimeLayout)
+		if err := os.Rename(dbPath, previous); err != nil {
+			os.Remove(tmpPath)
+			return "", err
+		}
+	}
+
+	// a journal left by the replaced database goes with it; it must not be
+	// applied to the restored one
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if _, err := os.Stat(dbPath + suffix); err != nil {
+			continue
+		}
+		if previous == "" {
+			err = os.Remove(dbPath + suffix)
+		} else {
+			err = os.Rename(dbPath+suffix, previous+suffix)
+		}
+		if err != nil {
+			return previous, err
+		}
+	}
+
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		return previous, err
+	}
+
+	return previous, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+func TestBackupLeavesTheServerConnectionFree(t *testing.T) {
+	s, db, path := openTestStore(t)
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	// enough pages for several steps
+	for i := int64(1); i <= 400; i++ {
+		err := s.SaveLedgerEntry(ctx, group.LedgerEntry{
+			ID: i, GroupID: 1, MemberID: "a", Kind: group.LedgerCharge, Debit: 100,
+			Note: strings.Repeat("x", 4000), CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the server holds its only connection throughout
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	dest := filepath.Join(t.TempDir(), BackupFileName(time.Now()))
+	if err := Backup(ctx, path, dest); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, `DELETE FROM ledger_entries WHERE id = 1;`); err != nil {
+		t.Fatalf("writing after the backup: %v", err)
+	}
+
+	if _, err := CheckBackup(ctx, dest); err != nil {
+		t.Fatalf("CheckBackup: %v", err)
+	}
+	if err := Backup(ctx, path, dest); err != ErrBackupTargetExists {
+		t.Errorf("second Backup to the same file = %v, want ErrBackupTargetExists", err)
+	}
+}
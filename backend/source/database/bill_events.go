@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+)
+
+const createBillEventsTable = `
+CREATE TABLE IF NOT EXISTS bill_events (
+    id          INTEGER PRIMARY KEY,
+    bill_id     INTEGER NOT NULL,
+    from_status TEXT,                   -- NULL for the event that issued the bill
+    to_status   TEXT NOT NULL,
+    actor_id    TEXT NOT NULL,          -- Discord user ID or "system"
+    reason      TEXT,
+    created_at  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_bill_events_bill_id ON bill_events(bill_id);
+`
+
+func (s *SQLiteStore) NextBillEventID(ctx context.Context) (int64, error) {
+	const q = `SELECT COALESCE(MAX(id), 0) + 1 FROM bill_events;`
+
+	var nextID int64
+	if err := s.conn(ctx).QueryRowContext(ctx, q).Scan(&nextID); err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
+
+func (s *SQLiteStore) SaveBillEvent(ctx context.Context, e bill.BillEvent) error {
+	const q = `
+INSERT INTO bill_events (id, bill_id, from_status, to_status, actor_id, reason, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+`
+
+	_, err := s.conn(ctx).ExecContext(ctx, q,
+		e.ID,
+		e.BillID,
+		nullableString(string(e.From)),
+		string(e.To),
+		e.ActorID,
+		nullableString(e.Reason),
+		e.CreatedAt.Format(time.RFC3339),
+	)
+	return err
+}
+
+func (s *SQLiteStore) GetBillEvents(ctx context.Context, billID int64) ([]bill.BillEvent, error) {
+	const q = `
+SELECT id, bill_id, from_status, to_status, actor_id, reason, created_at
+FROM bill_events
+WHERE bill_id = ?
+ORDER BY id ASC;
+`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, q, billID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []bill.BillEvent{}
+	for rows.Next() {
+		var (
+			e         bill.BillEvent
+			from      *string
+			reason    *string
+			createdAt string
+		)
+		if err := rows.Scan(&e.ID, &e.BillID, &from, &e.To, &e.ActorID, &reason, &createdAt); err != nil {
+			return nil, err
+		}
+		e.From = bill.BillStatus(derefString(from))
+		e.Reason = derefString(reason)
+		e.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		result = append(result, e)
+	}
+
+	return result, rows.Err()
+}
+
+// GetGroupOwnerID is used by the bill service, which can't load a whole group.
+func (s *SQLiteStore) GetGroupOwnerID(ctx context.Context, groupID int64) (string, error) {
+	const q = `SELECT owner_discord_id FROM groups WHERE id = ?;`
+
+	var ownerID string
+	if err := s.conn(ctx).QueryRowContext(ctx, q, groupID).Scan(&ownerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return ownerID, nil
+}
+
+// CountRejectedSlips counts, per member, how many times a bill of the group
+// was moved to rejected. Periods are year*100+month; zero means unbounded.
+func (s *SQLiteStore) CountRejectedSlips(ctx context.Context, groupID int64, fromPeriod, toPeriod int) (map[string]int, error) {
+	q := `
+SELECT b.member_id, COUNT(*)
+FROM bill_events e
+JOIN bills b ON b.id = e.bill_id
+WHERE b.group_id = ?
+  AND e.to_status = ?`
+	args := []any{groupID, string(bill.BillStatusRejected)}
+	if fromPeriod != 0 {
+		q += "\n  AND b.year * 100 + b.month >= ?"
+		args = append(args, fromPeriod)
+	}
+	if toPeriod != 0 {
+		q += "\n  AND b.year * 100 + b.month <= ?"
+		args = append(args, toPeriod)
+	}
+	q += "\nGROUP BY b.member_id;"
+
+	rows, err := s.conn(ctx).QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var (
+			memberID string
+			n        int
+		)
+		if err := rows.Scan(&memberID, &n); err != nil {
+			return nil, err
+		}
+		counts[memberID] = n
+	}
+	return counts, rows.Err()
+}
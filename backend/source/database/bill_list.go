@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"strings"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+)
+
+const createBillIndexes = `
+CREATE INDEX IF NOT EXISTS idx_bills_group_id ON bills(group_id, year, month);
+CREATE INDEX IF NOT EXISTS idx_bills_member_id ON bills(member_id, year, month);
+`
+
+// billColumnsCompact is billColumns without the slip payload, for listings.
+var billColumnsCompact = strings.Replace(billColumns, "proof_json", "'' AS proof_json", 1)
+
+// ListBills runs a filtered, keyset-paginated bill query, newest cycle first;
+// it returns up to q.Limit+1 rows.
+func (s *SQLiteStore) ListBills(ctx context.Context, q bill.BillQuery) ([]bill.Bill, error) {
+	var (
+		where []string
+		args  []any
+	)
+
+	if q.GroupID != 0 {
+		where = append(where, "group_id = ?")
+		args = append(args, q.GroupID)
+	}
+	if q.MemberID != "" {
+		where = append(where, "member_id = ?")
+		args = append(args, q.MemberID)
+	}
+	if len(q.Statuses) > 0 {
+		where = append(where, "status IN (?"+strings.Repeat(", ?", len(q.Statuses)-1)+")")
+		for _, st := range q.Statuses {
+			args = append(args, string(st))
+		}
+	}
+	if q.Kind != "" {
+		where = append(where, "kind = ?")
+		args = append(args, string(q.Kind))
+	}
+	if q.FromPeriod != 0 {
+		where = append(where, "year * 100 + month >= ?")
+		args = append(args, q.FromPeriod)
+	}
+	if q.ToPeriod != 0 {
+		where = append(where, "year * 100 + month <= ?")
+		args = append(args, q.ToPeriod)
+	}
+	if q.After != nil {
+		where = append(where, "(year * 100 + month < ? OR (year * 100 + month = ? AND id < ?))")
+		args = append(args, q.After.Period, q.After.Period, q.After.ID)
+	}
+
+	columns := billColumnsCompact
+	if q.IncludeProof {
+		columns = billColumns
+	}
+
+	query := `SELECT` + columns + `
+FROM bills`
+	if len(where) > 0 {
+		query += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	query += "\nORDER BY year DESC, month DESC, id DESC\nLIMIT ?;"
+	args = append(args, q.Limit+1)
+
+	bills, err := s.queryBills(ctx, query, args...)
+	if err == ErrNotFound {
+		return []bill.Bill{}, nil
+	}
+	return bills, err
+}
+
+// GetOpenBillsByMemberID returns the member's bills that still expect a
+// payment, across all groups, without slip payloads.
+func (s *SQLiteStore) GetOpenBillsByMemberID(ctx context.Context, memberID string) ([]bill.Bill, error) {
+	q := `SELECT` + billColumnsCompact + `
+FROM bills
+WHERE member_id = ? AND status IN ('pending', 'submitted', 'partially_paid', 'rejected')
+ORDER BY year ASC, month ASC, id ASC;
+`
+
+	bills, err := s.queryBills(ctx, q, memberID)
+	if err == ErrNotFound {
+		return []bill.Bill{}, nil
+	}
+	return bills, err
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+)
+
+func TestListBillsPagesByKeyset(t *testing.T) {
+	s, _, _ := openTestStore(t)
+	ctx := context.Background()
+
+	// several bills share a period, so pages must break ties on id
+	bills := []struct {
+		id          int64
+		year, month int
+		status      bill.BillStatus
+	}{
+		{1, 2024, 1, bill.BillStatusVerified},
+		{2, 2024, 1, bill.BillStatusPending},
+		{3, 2024, 2, bill.BillStatusVerified},
+		{4, 2024, 2, bill.BillStatusPending},
+		{5, 2024, 2, bill.BillStatusPending},
+		{6, 2023, 12, bill.BillStatusPending},
+		{7, 2024, 3, bill.BillStatusRejected},
+	}
+	for _, b := range bills {
+		err := s.SaveBill(ctx, bill.Bill{
+			ID:        b.id,
+			Kind:      bill.BillKindRecurring,
+			GroupID:   1,
+			MemberID:  "100000000000000002",
+			Year:      b.year,
+			Month:     b.month,
+			AmountDue: 100,
+			Currency:  "THB",
+			Status:    b.status,
+			CreatedAt: time.Date(b.year, time.Month(b.month), 5, 0, 0, 0, 0, time.UTC),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := bill.NewService(s)
+
+	tests := []struct {
+		name string
+		req  bill.ListBillsRequest
+		want []int64
+	}{
+		{name: "newest first", want: []int64{7, 5, 4, 3, 2, 1, 6}},
+		{name: "by status", req: bill.ListBillsRequest{Statuses: []bill.BillStatus{bill.BillStatusPending}}, want: []int64{5, 4, 2, 6}},
+		{name: "within periods", req: bill.ListBillsRequest{From: "2024-01", To: "2024-02"}, want: []int64{5, 4, 3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.GroupID = 1
+			req.Limit = 2
+
+			var got []int64
+			for pages := 0; ; pages++ {
+				if pages > len(bills) {
+					t.Fatal("the cursor never runs out")
+				}
+				page, err := svc.ListBills(ctx, req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, b := range page.Bills {
+					got = append(got, b.ID)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				req.Cursor = page.NextCursor
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/expense"
+)
+
+const createExpensesTable = `
+CREATE TABLE IF NOT EXISTS expenses (
+    id                INTEGER PRIMARY KEY,
+    group_id          INTEGER NOT NULL,
+    payer_id          TEXT NOT NULL,
+    amount            REAL NOT NULL,
+    currency          TEXT NOT NULL,
+    description       TEXT NOT NULL,
+    participants_json TEXT NOT NULL,
+    created_by        TEXT NOT NULL,
+    created_at        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_expenses_group_id ON expenses(group_id);
+CREATE INDEX IF NOT EXISTS idx_bills_expense_id ON bills(expense_id);
+`
+
+func (s *SQLiteStore) NextExpenseID(ctx context.Context) (int64, error) {
+	const q = `SELECT COALESCE(MAX(id), 0) + 1 FROM expenses;`
+
+	var nextID int64
+	if err := s.conn(ctx).QueryRowContext(ctx, q).Scan(&nextID); err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
+
+func (s *SQLiteStore) SaveExpense(ctx context.Context, e expense.Expense) error {
+	participantsJSON, err := json.Marshal(e.Participants)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+INSERT INTO expenses (id, group_id, payer_id, amount, currency, description, participants_json, created_by, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+`
+
+	_, err = s.conn(ctx).ExecContext(ctx, q,
+		e.ID,
+		e.GroupID,
+		e.PayerID,
+		e.Amount,
+		e.Currency,
+		e.Description,
+		string(participantsJSON),
+		e.CreatedBy,
+		e.CreatedAt.Format(time.RFC3339),
+	)
+	return err
+}
+
+func (s *SQLiteStore) GetExpensesByGroupID(ctx context.Context, groupID int64) ([]expense.Expense, error) {
+	const q = `
+SELECT id, group_id, payer_id, amount, currency, description, participants_json, created_by, created_at
+FROM expenses
+WHERE group_id = ?
+ORDER BY created_at DESC, id DESC;
+`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, q, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []expense.Expense{}
+	for rows.Next() {
+		var (
+			e                expense.Expense
+			participantsJSON string
+			createdAt        string
+		)
+		if err := rows.Scan(&e.ID, &e.GroupID, &e.PayerID, &e.Amount, &e.Currency, &e.Description, &participantsJSON, &e.CreatedBy, &createdAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(participantsJSON), &e.Participants); err != nil {
+			return nil, err
+		}
+		e.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		result = append(result, e)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *SQLiteStore) GetBillsByExpenseID(ctx context.Context, expenseID int64) ([]bill.Bill, error) {
+	const q = `SELECT` + billColumns + `
+FROM bills
+WHERE expense_id = ?
+ORDER BY member_id ASC;
+`
+
+	bills, err := s.queryBills(ctx, q, expenseID)
+	if err == ErrNotFound {
+		return []bill.Bill{}, nil
+	}
+	return bills, err
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+// group_members mirrors members_json so groups can be looked up by member
+// without scanning every group. It is rewritten whenever a group is saved.
+const createGroupMembersTable = `
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id  INTEGER NOT NULL,
+    member_id TEXT NOT NULL,
+    status    TEXT NOT NULL,
+    PRIMARY KEY (group_id, member_id)
+);
+CREATE INDEX IF NOT EXISTS idx_group_members_member_id ON group_members(member_id, status);
+CREATE INDEX IF NOT EXISTS idx_groups_guild_id ON groups(discord_guild_id);
+CREATE INDEX IF NOT EXISTS idx_groups_owner_id ON groups(owner_discord_id);
+CREATE INDEX IF NOT EXISTS idx_groups_due_day ON groups(due_day);
+`
+
+// syncGroupMembers rewrites a group's rows in group_members. Callers run it in
+// the transaction that writes the groups row, so the two never disagree.
+func (s *SQLiteStore) syncGroupMembers(ctx context.Context, groupID int64, members []group.GroupMember) error {
+	if _, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM group_members WHERE group_id = ?;`, groupID); err != nil {
+		return err
+	}
+
+	// a member who left and was invited again appears twice; the later entry wins
+	const q = `INSERT OR REPLACE INTO group_members (group_id, member_id, status) VALUES (?, ?, ?);`
+	for _, m := range members {
+		if _, err := s.conn(ctx).ExecContext(ctx, q, groupID, m.MemberID, string(m.Status)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillGroupMembers fills group_members for groups saved before it existed.
+func (s *SQLiteStore) backfillGroupMembers(ctx context.Context) error {
+	const q = `
+SELECT id, members_json FROM groups
+WHERE NOT EXISTS (SELECT 1 FROM group_members gm WHERE gm.group_id = groups.id);`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	type pending struct {
+		id      int64
+		members []group.GroupMember
+	}
+	var todo []pending
+	for rows.Next() {
+		var (
+			p           pending
+			membersJSON string
+		)
+		if err := rows.Scan(&p.id, &membersJSON); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal([]byte(membersJSON), &p.members); err != nil {
+			rows.Close()
+			return err
+		}
+		todo = append(todo, p)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	return s.RunInTx(ctx, func(ctx context.Context) error {
+		for _, p := range todo {
+			if err := s.syncGroupMembers(ctx, p.id, p.members); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListGroups runs a filtered, keyset-paginated group query; it returns up to
+// q.Limit+1 rows.
+func (s *SQLiteStore) ListGroups(ctx context.Context, q group.GroupQuery) ([]group.Group, error) {
+	var (
+		where []string
+		args  []any
+	)
+
+	if q.GuildID != "" {
+		where = append(where, "discord_guild_id = ?")
+		args = append(args, q.GuildID)
+	}
+	if q.OwnerID != "" {
+		where = append(where, "owner_discord_id = ?")
+		args = append(args, q.OwnerID)
+	}
+	if q.DueDay != 0 {
+		where = append(where, "due_day = ?")
+		args = append(args, q.DueDay)
+	}
+
+	switch {
+	case q.MemberID != "" && q.Status != "":
+		where = append(where, "EXISTS (SELECT 1 FROM group_members gm WHERE gm.group_id = groups.id AND gm.member_id = ? AND gm.status = ?)")
+		args = append(args, q.MemberID, string(q.Status))
+	case q.MemberID != "":
+		where = append(where, "EXISTS (SELECT 1 FROM group_members gm WHERE gm.group_id = groups.id AND gm.member_id = ?)")
+		args = append(args, q.MemberID)
+	case q.Status != "":
+		where = append(where, "EXISTS (SELECT 1 FROM group_members gm WHERE gm.group_id = groups.id AND gm.status = ?)")
+		args = append(args, string(q.Status))
+	}
+
+	column := string(q.SortBy)
+	cmp, dir := ">", "ASC"
+	if q.Desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	if q.After != nil {
+		var value any = q.After.Value
+		if q.SortBy == group.SortByID || q.SortBy == group.SortByDueDay {
+			n, err := strconv.ParseInt(q.After.Value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			value = n
+		}
+		where = append(where, "("+column+" "+cmp+" ? OR ("+column+" = ? AND id "+cmp+" ?))")
+		args = append(args, value, value, q.After.ID)
+	}
+
+	query := `SELECT` + groupColumns + `
+FROM groups`
+	if len(where) > 0 {
+		query += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	query += "\nORDER BY " + column + " " + dir + ", id " + dir + "\nLIMIT ?;"
+	args = append(args, q.Limit+1)
+
+	groups, err := s.queryGroups(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+func testGroup(id int64, name string, dueDay int) group.Group {
+	return group.Group{
+		ID:             id,
+		Name:           name,
+		Amount:         300,
+		DueDay:         dueDay,
+		DiscordGuildID: "200000000000000001",
+		OwnerDiscordID: "100000000000000001",
+		Members: []group.GroupMember{
+			{MemberID: "100000000000000001", Status: group.MemberStatusActive},
+			{MemberID: fmt.Sprintf("1000000000000001%02d", id), Status: group.MemberStatusInvited},
+		},
+		Currency: "THB",
+		CreateAt: time.Date(2024, 1, int(id), 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestGroupMembersFollowTheGroupRow(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(s *SQLiteStore, ctx context.Context) error
+	}{
+		{name: "save", write: func(s *SQLiteStore, ctx context.Context) error {
+			return s.SaveGroup(ctx, testGroup(2, "Spotify", 5))
+		}},
+		{name: "update", write: func(s *SQLiteStore, ctx context.Context) error {
+			g := testGroup(1, "Renamed", 5)
+			_, err := s.UpdateGroupVersion(ctx, 1, 1, g)
+			return err
+		}},
+		{name: "delete", write: func(s *SQLiteStore, ctx context.Context) error {
+			return s.DeleteGroup(ctx, 1)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db, _ := openTestStore(t)
+			ctx := context.Background()
+			if err := s.SaveGroup(ctx, testGroup(1, "Netflix", 5)); err != nil {
+				t.Fatal(err)
+			}
+
+			// writing the mirror now fails, so the groups row must not change
+			if _, err := db.Exec(`ALTER TABLE group_members RENAME TO group_members_gone;`); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.write(s, ctx); err == nil {
+				t.Fatal("write succeeded without group_members")
+			}
+
+			var rows []string
+			r, err := db.Query(`SELECT name || '@' || version FROM groups ORDER BY id;`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			for r.Next() {
+				var row string
+				r.Scan(&row)
+				rows = append(rows, row)
+			}
+			if want := []string{"Netflix@1"}; !slices.Equal(rows, want) {
+				t.Errorf("groups = %v, want %v", rows, want)
+			}
+		})
+	}
+}
+
+func TestListGroupsPagesByKeyset(t *testing.T) {
+	s, _, _ := openTestStore(t)
+	ctx := context.Background()
+
+	// names and due days repeat, so pages must break ties on id
+	groups := []group.Group{
+		testGroup(1, "Netflix", 5),
+		testGroup(2, "Disney", 1),
+		testGroup(3, "Netflix", 1),
+		testGroup(4, "Apple", 5),
+		testGroup(5, "Disney", 28),
+		testGroup(6, "Netflix", 5),
+		testGroup(7, "YouTube", 1),
+	}
+	for _, g := range groups {
+		if err := s.SaveGroup(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := group.NewService(s)
+
+	tests := []struct {
+		sort string
+		want []int64
+	}{
+		{sort: "", want: []int64{1, 2, 3, 4, 5, 6, 7}},
+		{sort: "-id", want: []int64{7, 6, 5, 4, 3, 2, 1}},
+		{sort: "name", want: []int64{4, 2, 5, 1, 3, 6, 7}},
+		{sort: "-name", want: []int64{7, 6, 3, 1, 5, 2, 4}},
+		{sort: "due_day", want: []int64{2, 3, 7, 1, 4, 6, 5}},
+		{sort: "-due_day", want: []int64{5, 6, 4, 1, 7, 3, 2}},
+		{sort: "-created_at", want: []int64{7, 6, 5, 4, 3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run("sort="+tt.sort, func(t *testing.T) {
+			req := group.ListGroupsRequest{GuildID: "200000000000000001", Sort: tt.sort, Limit: 2}
+
+			var got []int64
+			for pages := 0; ; pages++ {
+				if pages > len(groups) {
+					t.Fatal("the cursor never runs out")
+				}
+				page, err := svc.ListGroups(ctx, req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, g := range page.Groups {
+					got = append(got, g.ID)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				req.Cursor = page.NextCursor
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/idempotency"
+)
+
+const createIdempotencyKeysTable = `
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key          TEXT PRIMARY KEY,
+    fingerprint  TEXT NOT NULL,         -- hash of method, path, actor and body
+    status       INTEGER,               -- NULL while the request runs
+    headers_json TEXT,
+    body         BLOB,
+    created_at   TEXT NOT NULL,
+    completed_at TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+`
+
+// ClaimIdempotencyKey inserts a running request; it returns false when the key
+// is already taken.
+func (s *SQLiteStore) ClaimIdempotencyKey(ctx context.Context, r idempotency.Record) (bool, error) {
+	const q = `
+INSERT OR IGNORE INTO idempotency_keys (key, fingerprint, created_at)
+VALUES (?, ?, ?);
+`
+
+	res, err := s.conn(ctx).ExecContext(ctx, q, r.Key, r.Fingerprint, r.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// GetIdempotencyKey returns nil, nil when key isn't stored, e.g. because its
+// request released it after the claim failed.
+func (s *SQLiteStore) GetIdempotencyKey(ctx context.Context, key string) (*idempotency.Record, error) {
+	const q = `
+SELECT key, fingerprint, status, headers_json, body, created_at, completed_at
+FROM idempotency_keys
+WHERE key = ?;
+`
+
+	var (
+		r           idempotency.Record
+		status      *int
+		headersJSON *string
+		createdAt   string
+		completedAt *string
+	)
+	err := s.conn(ctx).QueryRowContext(ctx, q, key).Scan(&r.Key, &r.Fingerprint, &status, &headersJSON, &r.Body, &createdAt, &completedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if status != nil {
+		r.Status = *status
+	}
+	if headersJSON != nil {
+		if err := json.Unmarshal([]byte(*headersJSON), &r.Header); err != nil {
+			return nil, err
+		}
+	}
+	if r.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+		return nil, err
+	}
+	if completedAt != nil {
+		t, err := time.Parse(time.RFC3339, *completedAt)
+		if err != nil {
+			return nil, err
+		}
+		r.CompletedAt = &t
+	}
+
+	return &r, nil
+}
+
+func (s *SQLiteStore) CompleteIdempotencyKey(ctx context.Context, r idempotency.Record) error {
+	const q = `
+UPDATE idempotency_keys
+SET status = ?, headers_json = ?, body = ?, completed_at = ?
+WHERE key = ?;
+`
+
+	headersJSON, err := json.Marshal(r.Header)
+	if err != nil {
+		return err
+	}
+
+	var completedAt any
+	if r.CompletedAt != nil {
+		completedAt = r.CompletedAt.UTC().Format(time.RFC3339)
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, q, r.Status, string(headersJSON), r.Body, completedAt, r.Key)
+	return err
+}
+
+func (s *SQLiteStore) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = ?;`, key)
+	return err
+}
+
+func (s *SQLiteStore) DeleteIdempotencyKeysBefore(ctx context.Context, t time.Time) (int64, error) {
+	const q = `DELETE FROM idempotency_keys WHERE created_at < ?;`
+
+	res, err := s.conn(ctx).ExecContext(ctx, q, t.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
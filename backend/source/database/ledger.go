@@ -0,0 +1,262 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+const createLedgerEntriesTable = `
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id           INTEGER PRIMARY KEY,
+    group_id     INTEGER NOT NULL,
+    member_id    TEXT NOT NULL,
+    kind         TEXT NOT NULL,          -- opening/charge/payment/credit/adjustment/write_off/refund
+    debit_minor  INTEGER NOT NULL DEFAULT 0, -- hundredths of the group currency
+    credit_minor INTEGER NOT NULL DEFAULT 0,
+    bill_id      INTEGER,
+    actor_id     TEXT,
+    note         TEXT,
+    created_at   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_member ON ledger_entries(group_id, member_id);
+`
+
+// migrateLedgerUnits moves ledgers written before schema 7, which kept whole
+// units in debit/credit, to hundredths in debit_minor/credit_minor.
+func (s *SQLiteStore) migrateLedgerUnits(ctx context.Context) error {
+	old, err := s.hasColumn(ctx, "ledger_entries", "debit")
+	if err != nil || !old {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, q := range []string{
+		`UPDATE ledger_entries SET debit = debit * 100, credit = credit * 100;`,
+		`ALTER TABLE ledger_entries RENAME COLUMN debit TO debit_minor;`,
+		`ALTER TABLE ledger_entries RENAME COLUMN credit TO credit_minor;`,
+	} {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// backfillLedger opens the ledger of every member that still carries a dept or
+// credit in members_json but has no ledger entries yet. It runs on every start
+// and is a no-op once all balances have moved to the ledger.
+func (s *SQLiteStore) backfillLedger(ctx context.Context) error {
+	const q = `
+SELECT id, members_json
+FROM groups
+WHERE NOT EXISTS (SELECT 1 FROM ledger_entries l WHERE l.group_id = groups.id);`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	type opening struct {
+		groupID int64
+		member  group.GroupMember
+	}
+	var openings []opening
+
+	for rows.Next() {
+		var (
+			groupID     int64
+			membersJSON string
+			members     []group.GroupMember
+		)
+		if err := rows.Scan(&groupID, &membersJSON); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal([]byte(membersJSON), &members); err != nil {
+			rows.Close()
+			return err
+		}
+		for _, m := range members {
+			if m.Dept != 0 || m.Credit != 0 {
+				openings = append(openings, opening{groupID: groupID, member: m})
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	now := time.Now().UTC()
+	for _, o := range openings {
+		id, err := s.NextLedgerEntryID(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.SaveLedgerEntry(ctx, group.LedgerEntry{
+			ID:        id,
+			GroupID:   o.groupID,
+			MemberID:  o.member.MemberID,
+			Kind:      group.LedgerOpening,
+			Debit:     o.member.Dept,
+			Credit:    o.member.Credit,
+			Note:      "opening balance",
+			CreatedAt: now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) NextLedgerEntryID(ctx context.Context) (int64, error) {
+	const q = `SELECT COALESCE(MAX(id), 0) + 1 FROM ledger_entries;`
+
+	var nextID int64
+	if err := s.conn(ctx).QueryRowContext(ctx, q).Scan(&nextID); err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
+
+func (s *SQLiteStore) SaveLedgerEntry(ctx context.Context, e group.LedgerEntry) error {
+	const q = `
+INSERT INTO ledger_entries (
+    id,
+    group_id,
+    member_id,
+    kind,
+    debit_minor,
+    credit_minor,
+    bill_id,
+    actor_id,
+    note,
+    created_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	_, err := s.conn(ctx).ExecContext(ctx, q,
+		e.ID,
+		e.GroupID,
+		e.MemberID,
+		string(e.Kind),
+		e.Debit,
+		e.Credit,
+		e.BillID,
+		nullableString(e.ActorID),
+		nullableString(e.Note),
+		e.CreatedAt.Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetLedgerEntries returns a member's entries oldest first.
+func (s *SQLiteStore) GetLedgerEntries(ctx context.Context, groupID int64, memberID string) ([]group.LedgerEntry, error) {
+	const q = `
+SELECT
+    id,
+    group_id,
+    member_id,
+    kind,
+    debit_minor,
+    credit_minor,
+    bill_id,
+    actor_id,
+    note,
+    created_at
+FROM ledger_entries
+WHERE group_id = ? AND member_id = ?
+ORDER BY id ASC;`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, q, groupID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []group.LedgerEntry{}
+	for rows.Next() {
+		var (
+			e             group.LedgerEntry
+			actorID, note *string
+			createdAt     string
+		)
+		if err := rows.Scan(
+			&e.ID,
+			&e.GroupID,
+			&e.MemberID,
+			&e.Kind,
+			&e.Debit,
+			&e.Credit,
+			&e.BillID,
+			&actorID,
+			&note,
+			&createdAt,
+		); err != nil {
+			return nil, err
+		}
+		if actorID != nil {
+			e.ActorID = *actorID
+		}
+		if note != nil {
+			e.Note = *note
+		}
+		e.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		result = append(result, e)
+	}
+
+	return result, rows.Err()
+}
+
+// HasBillsOrLedger reports whether a group has any bill or ledger entry.
+func (s *SQLiteStore) HasBillsOrLedger(ctx context.Context, groupID int64) (bool, error) {
+	const q = `
+SELECT EXISTS (SELECT 1 FROM bills WHERE group_id = ?)
+    OR EXISTS (SELECT 1 FROM ledger_entries WHERE group_id = ?);`
+
+	var used bool
+	if err := s.conn(ctx).QueryRowContext(ctx, q, groupID, groupID).Scan(&used); err != nil {
+		return false, err
+	}
+	return used, nil
+}
+
+// GetLedgerBalances returns debit - credit per member of a group.
+func (s *SQLiteStore) GetLedgerBalances(ctx context.Context, groupID int64) (map[string]currency.Minor, error) {
+	const q = `
+SELECT member_id, COALESCE(SUM(debit_minor), 0) - COALESCE(SUM(credit_minor), 0)
+FROM ledger_entries
+WHERE group_id = ?
+GROUP BY member_id;`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, q, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := map[string]currency.Minor{}
+	for rows.Next() {
+		var (
+			memberID string
+			balance  currency.Minor
+		)
+		if err := rows.Scan(&memberID, &balance); err != nil {
+			return nil, err
+		}
+		balances[memberID] = balance
+	}
+
+	return balances, rows.Err()
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+func TestLedgerMovesToMinorUnits(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "old.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// the ledger as schema 6 wrote it, in whole units
+	for _, q := range []string{
+		`CREATE TABLE ledger_entries (
+    id          INTEGER PRIMARY KEY,
+    group_id    INTEGER NOT NULL,
+    member_id   TEXT NOT NULL,
+    kind        TEXT NOT NULL,
+    debit       INTEGER NOT NULL DEFAULT 0,
+    credit      INTEGER NOT NULL DEFAULT 0,
+    bill_id     INTEGER,
+    actor_id    TEXT,
+    note        TEXT,
+    created_at  TEXT NOT NULL
+);`,
+		`INSERT INTO ledger_entries (id, group_id, member_id, kind, debit, credit, created_at)
+VALUES (1, 1, 'a', 'charge', 100, 0, '2024-01-05T00:00:00Z'),
+       (2, 1, 'a', 'payment', 0, 40, '2024-01-06T00:00:00Z'),
+       (3, 1, 'b', 'payment', 0, 7, '2024-01-06T00:00:00Z');`,
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewSQLiteStore(db)
+	ctx := context.Background()
+	// the second run finds the table already migrated
+	for i := 0; i < 2; i++ {
+		if err := s.InitSchema(ctx); err != nil {
+			t.Fatalf("InitSchema: %v", err)
+		}
+	}
+
+	if err := s.SaveLedgerEntry(ctx, group.LedgerEntry{
+		ID: 4, GroupID: 1, MemberID: "b", Kind: group.LedgerCharge, Debit: currency.ToMinor(7.25), CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	balances, err := s.GetLedgerBalances(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]currency.Minor{"a": currency.ToMinor(60), "b": currency.ToMinor(0.25)}
+	for member, balance := range want {
+		if balances[member] != balance {
+			t.Errorf("balance of %s = %v, want %v", member, balances[member], balance)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is stored in PRAGMA user_version by InitSchema. Bump it
+// whenever InitSchema changes the schema; restore refuses backups written by
+// a newer version.
+const SchemaVersion = 7
+
+func (s *SQLiteStore) setSchemaVersion(ctx context.Context) error {
+	_, err := s.conn(ctx).ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d;`, SchemaVersion))
+	return err
+}
+
+// ensureColumn adds a column to an existing table when it is missing, so
+// databases created by older versions pick up new fields on startup.
+func (s *SQLiteStore) ensureColumn(ctx context.Context, table, column, definition string) error {
+	ok, err := s.hasColumn(ctx, table, column)
+	if err != nil || ok {
+		return err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, `ALTER TABLE `+table+` ADD COLUMN `+column+` `+definition+`;`)
+	return err
+}
+
+// hasColumn reports whether a table has a column.
+func (s *SQLiteStore) hasColumn(ctx context.Context, table, column string) (bool, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `PRAGMA table_info(`+table+`);`)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue *string
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if strings.EqualFold(name, column) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format(time.RFC3339)
+}
+
+func parseNullableTime(s *string) *time.Time {
+	if s == nil {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, *s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// ensureBillRateColumns adds the exchange-rate snapshot to bills created before
+// multi-currency groups; those were all THB, so the snapshot is the identity.
+func (s *SQLiteStore) ensureBillRateColumns(ctx context.Context) error {
+	columns := []struct{ name, definition string }{
+		{"settlement_currency", "TEXT"},
+		{"settlement_amount_due", "REAL"},
+		{"exchange_rate", "REAL"},
+		{"rate_source", "TEXT"},
+		{"rate_as_of", "TEXT"},
+	}
+	for _, c := range columns {
+		if err := s.ensureColumn(ctx, "bills", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+
+	const backfill = `
+UPDATE bills
+SET settlement_currency   = currency,
+    settlement_amount_due = amount_due,
+    exchange_rate         = 1,
+    rate_source           = 'identity'
+WHERE settlement_currency IS NULL;`
+
+	_, err := s.conn(ctx).ExecContext(ctx, backfill)
+	return err
+}
+
+// ensureBillKindColumns upgrades bills from before one-off expenses. Every
+// such bill came from the monthly cycle and is owed to the group owner.
+func (s *SQLiteStore) ensureBillKindColumns(ctx context.Context) error {
+	if err := s.ensureColumn(ctx, "bills", "kind", "TEXT NOT NULL DEFAULT 'recurring'"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn(ctx, "bills", "expense_id", "INTEGER"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn(ctx, "bills", "payee_id", "TEXT"); err != nil {
+		return err
+	}
+
+	const backfill = `
+UPDATE bills
+SET payee_id = (SELECT g.owner_discord_id FROM groups g WHERE g.id = bills.group_id)
+WHERE payee_id IS NULL AND kind = 'recurring';`
+
+	_, err := s.conn(ctx).ExecContext(ctx, backfill)
+	return err
+}
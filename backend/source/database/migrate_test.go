@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// schemaFingerprints records the schema InitSchema creates at each
+// SchemaVersion. When TestSchemaVersion fails, bump SchemaVersion and add the
+// new fingerprint; never change a recorded one.
+var schemaFingerprints = map[int]string{
+	4: "f072a0df9b846fda2b0a5c2bbf0c445af4f584e2e63600fecdf95817898e74ea",
+	5: "e464805d1196d0b9af5f0b17b7fc8a1f5cec79e122f1292b5d1594f7460b043a",
+	6: "95b10081b7d20aceeb5a55aee9788ecf4bfc885a7cbd8748a0afb3b846ce1f53",
+	7: "07347959a7df88c9c674dd97354b5b4299f9936094fbf47a8acb9f6229669f7f",
+}
+
+func openTestStore(t *testing.T) (*SQLiteStore, *sql.DB, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s := NewSQLiteStore(db)
+	if err := s.InitSchema(context.Background()); err != nil {
+		t.Fatalf("InitSchema: %v", err)
+	}
+	return s, db, path
+}
+
+// schemaFingerprint hashes every table and index definition, ignoring
+// whitespace.
+func schemaFingerprint(t *testing.T, db *sql.DB) string {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT type, name, COALESCE(sql, '') FROM sqlite_master ORDER BY type, name;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	h := sha256.New()
+	for rows.Next() {
+		var typ, name, def string
+		if err := rows.Scan(&typ, &name, &def); err != nil {
+			t.Fatal(err)
+		}
+		h.Write([]byte(typ + " " + name + " " + strings.Join(strings.Fields(def), " ") + "\n"))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestSchemaVersion(t *testing.T) {
+	s, db, _ := openTestStore(t)
+
+	// a second run, as on every start, must leave the schema alone
+	if err := s.InitSchema(context.Background()); err != nil {
+		t.Fatalf("InitSchema again: %v", err)
+	}
+
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version;`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != SchemaVersion {
+		t.Fatalf("user_version = %d, want %d", version, SchemaVersion)
+	}
+
+	got := schemaFingerprint(t, db)
+	for v, fp := range schemaFingerprints {
+		if fp == got && v != SchemaVersion {
+			t.Fatalf("schema is the one of version %d, but SchemaVersion is %d", v, SchemaVersion)
+		}
+	}
+	want, ok := schemaFingerprints[SchemaVersion]
+	if !ok {
+		t.Fatalf("no fingerprint recorded for SchemaVersion %d; add %q", SchemaVersion, got)
+	}
+	if got != want {
+		t.Fatalf("schema changed without a SchemaVersion bump: set SchemaVersion to %d and record fingerprint %q", SchemaVersion+1, got)
+	}
+}
+
+func TestCheckBackupRefusesNewerSchema(t *testing.T) {
+	_, db, path := openTestStore(t)
+
+	version, err := CheckBackup(context.Background(), path)
+	if err != nil || version != SchemaVersion {
+		t.Fatalf("CheckBackup = %d, %v; want %d", version, err, SchemaVersion)
+	}
+
+	if _, err := db.Exec(`PRAGMA user_version = ` + strconv.Itoa(SchemaVersion+1) + `;`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckBackup(context.Background(), path); !errors.Is(err, ErrNewerSchema) {
+		t.Fatalf("CheckBackup = %v, want ErrNewerSchema", err)
+	}
+}
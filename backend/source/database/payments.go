@@ -0,0 +1,351 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var ErrDuplicateSlipReferences = errors.New("payments: slip references are counted more than once")
+
+const createPaymentsTable = `
+CREATE TABLE IF NOT EXISTS payments (
+    id          INTEGER PRIMARY KEY,
+    bill_id     INTEGER NOT NULL,
+    group_id    INTEGER NOT NULL,
+    member_id   TEXT NOT NULL,
+
+    amount      REAL NOT NULL,          -- in the bill currency
+    currency    TEXT NOT NULL,
+    original_amount   REAL,             -- as transferred, when converted
+    original_currency TEXT,
+    source      TEXT NOT NULL,          -- slip/manual
+    status      TEXT NOT NULL,          -- accepted/rejected
+    reference   TEXT,                   -- slip transRef
+    method      TEXT,                   -- BANKAC/MSISDN/CASH/...
+    note        TEXT,
+    recorded_by TEXT,
+
+    proof_json  TEXT,
+
+    attachment_name TEXT,
+    attachment_type TEXT,
+    attachment      BLOB,
+
+    paid_at     TEXT,
+    created_at  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_payments_bill_id ON payments(bill_id);
+CREATE INDEX IF NOT EXISTS idx_payments_reference ON payments(reference);
+`
+
+// createSlipReferenceIndex lets a slip count towards one bill only; the index
+// rather than a lookup decides, so two concurrent submissions can't both pass.
+const createSlipReferenceIndex = `
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_slip_reference ON payments(reference)
+WHERE source = 'slip' AND status = 'accepted' AND reference IS NOT NULL;
+`
+
+// backfillPayments gives bills paid before the payments table existed a single
+// payment row, so AmountPaid stays equal to the sum of accepted payments.
+const backfillPayments = `
+INSERT INTO payments (bill_id, group_id, member_id, amount, currency, source, status, proof_json, paid_at, created_at)
+SELECT b.id, b.group_id, b.member_id, b.amount_paid, b.currency, 'slip', 'accepted', b.proof_json,
+       COALESCE(b.paid_at, b.submitted_at), COALESCE(b.submitted_at, b.updated_at)
+FROM bills b
+WHERE b.amount_paid > 0
+  AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.bill_id = b.id);
+`
+
+// paymentColumns leaves out the attachment blob, see GetPaymentAttachment.
+const paymentColumns = `
+    id,
+    bill_id,
+    group_id,
+    member_id,
+    amount,
+    currency,
+    original_amount,
+    original_currency,
+    source,
+    status,
+    reference,
+    method,
+    note,
+    recorded_by,
+    proof_json,
+    attachment_name,
+    attachment_type,
+    paid_at,
+    created_at`
+
+// ensurePaymentColumns upgrades payments tables created before manual payments.
+func (s *SQLiteStore) ensurePaymentColumns(ctx context.Context) error {
+	for _, c := range []string{"method", "note", "recorded_by", "attachment_name", "attachment_type"} {
+		if err := s.ensureColumn(ctx, "payments", c, "TEXT"); err != nil {
+			return err
+		}
+	}
+	if err := s.ensureColumn(ctx, "payments", "attachment", "BLOB"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn(ctx, "payments", "original_amount", "REAL"); err != nil {
+		return err
+	}
+	return s.ensureColumn(ctx, "payments", "original_currency", "TEXT")
+}
+
+// ensureSlipReferenceIndex creates createSlipReferenceIndex. Databases that
+// already counted a slip twice can't get the index; the references are
+// reported so they can be sorted out by hand.
+func (s *SQLiteStore) ensureSlipReferenceIndex(ctx context.Context) error {
+	const q = `
+SELECT reference FROM payments
+WHERE source = 'slip' AND status = 'accepted' AND reference IS NOT NULL
+GROUP BY reference
+HAVING COUNT(*) > 1
+ORDER BY reference;`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var dups []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return err
+		}
+		dups = append(dups, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(dups) > 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateSlipReferences, strings.Join(dups, ", "))
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, createSlipReferenceIndex)
+	return err
+}
+
+func scanPayment(row rowScanner) (*bill.Payment, error) {
+	var p bill.Payment
+	var reference, method, note, recordedBy, proofJSON, attachmentName, attachmentType, paidAt *string
+	var createdAt string
+	var originalAmount *float64
+	var originalCurrency *string
+
+	if err := row.Scan(
+		&p.ID,
+		&p.BillID,
+		&p.GroupID,
+		&p.MemberID,
+		&p.Amount,
+		&p.Currency,
+		&originalAmount,
+		&originalCurrency,
+		&p.Source,
+		&p.Status,
+		&reference,
+		&method,
+		&note,
+		&recordedBy,
+		&proofJSON,
+		&attachmentName,
+		&attachmentType,
+		&paidAt,
+		&createdAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if originalAmount != nil {
+		p.OriginalAmount = *originalAmount
+	}
+	p.OriginalCurrency = derefString(originalCurrency)
+	p.Reference = derefString(reference)
+	p.Method = derefString(method)
+	p.Note = derefString(note)
+	p.RecordedBy = derefString(recordedBy)
+	p.ProofJSON = derefString(proofJSON)
+	p.AttachmentName = derefString(attachmentName)
+	p.AttachmentType = derefString(attachmentType)
+	p.PaidAt = parseNullableTime(paidAt)
+	p.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+
+	return &p, nil
+}
+
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullableFloat(f float64) interface{} {
+	if f == 0 {
+		return nil
+	}
+	return f
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (s *SQLiteStore) NextPaymentID(ctx context.Context) (int64, error) {
+	const q = `SELECT COALESCE(MAX(id), 0) + 1 FROM payments;`
+
+	var nextID int64
+	if err := s.conn(ctx).QueryRowContext(ctx, q).Scan(&nextID); err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
+
+func (s *SQLiteStore) SavePayment(ctx context.Context, p bill.Payment) error {
+	const q = `
+INSERT INTO payments (` + paymentColumns + `,
+    attachment
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+`
+
+	_, err := s.conn(ctx).ExecContext(ctx, q,
+		p.ID,
+		p.BillID,
+		p.GroupID,
+		p.MemberID,
+		p.Amount,
+		p.Currency,
+		nullableFloat(p.OriginalAmount),
+		nullableString(p.OriginalCurrency),
+		string(p.Source),
+		string(p.Status),
+		nullableString(p.Reference),
+		nullableString(p.Method),
+		nullableString(p.Note),
+		nullableString(p.RecordedBy),
+		nullableString(p.ProofJSON),
+		nullableString(p.AttachmentName),
+		nullableString(p.AttachmentType),
+		nullableTime(p.PaidAt),
+		p.CreatedAt.Format(time.RFC3339),
+		p.Attachment,
+	)
+	return err
+}
+
+// SaveSlipPayment saves a slip payment and reports false, saving nothing,
+// when an accepted payment already counts the same slip reference.
+func (s *SQLiteStore) SaveSlipPayment(ctx context.Context, p bill.Payment) (bool, error) {
+	err := s.SavePayment(ctx, p)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *SQLiteStore) GetPaymentsByBillID(ctx context.Context, billID int64) ([]bill.Payment, error) {
+	const q = `SELECT` + paymentColumns + `
+FROM payments
+WHERE bill_id = ?
+ORDER BY created_at ASC, id ASC;
+`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, q, billID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []bill.Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *p)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *SQLiteStore) GetPaymentByID(ctx context.Context, id int64) (*bill.Payment, error) {
+	const q = `SELECT` + paymentColumns + `
+FROM payments
+WHERE id = ?;
+`
+
+	p, err := scanPayment(s.conn(ctx).QueryRowContext(ctx, q, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetPaymentAttachment loads a payment together with its attachment bytes.
+func (s *SQLiteStore) GetPaymentAttachment(ctx context.Context, id int64) (*bill.Payment, error) {
+	p, err := s.GetPaymentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `SELECT attachment FROM payments WHERE id = ?;`
+	if err := s.conn(ctx).QueryRowContext(ctx, q, id).Scan(&p.Attachment); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// HasAcceptedPaymentReference reports whether a slip reference was already
+// counted towards some bill.
+func (s *SQLiteStore) HasAcceptedPaymentReference(ctx context.Context, reference string) (bool, error) {
+	const q = `SELECT EXISTS (SELECT 1 FROM payments WHERE reference = ? AND status = 'accepted');`
+
+	var exists bool
+	if err := s.conn(ctx).QueryRowContext(ctx, q, reference).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// GetLatestPayment returns the member's most recent accepted payment in a
+// group, or nil when they never paid.
+func (s *SQLiteStore) GetLatestPayment(ctx context.Context, groupID int64, memberID string) (*bill.Payment, error) {
+	const q = `SELECT` + paymentColumns + `
+FROM payments
+WHERE group_id = ? AND member_id = ? AND status = 'accepted'
+ORDER BY COALESCE(paid_at, created_at) DESC, id DESC
+LIMIT 1;
+`
+
+	p, err := scanPayment(s.conn(ctx).QueryRowContext(ctx, q, groupID, memberID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
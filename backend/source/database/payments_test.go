@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+)
+
+func TestSaveSlipPaymentCountsReferenceOnce(t *testing.T) {
+	s, _, _ := openTestStore(t)
+	ctx := context.Background()
+
+	payment := func(id, billID int64, status bill.PaymentStatus) bill.Payment {
+		return bill.Payment{
+			ID:        id,
+			BillID:    billID,
+			GroupID:   1,
+			MemberID:  "111111111111111111",
+			Amount:    100,
+			Currency:  "THB",
+			Source:    bill.PaymentSourceSlip,
+			Status:    status,
+			Reference: "REF1",
+			CreatedAt: time.Now(),
+		}
+	}
+
+	tests := []struct {
+		name string
+		p    bill.Payment
+		want bool
+	}{
+		{"first use", payment(1, 1, bill.PaymentStatusAccepted), true},
+		{"same slip on another bill", payment(2, 2, bill.PaymentStatusAccepted), false},
+		{"rejected attempts don't count", payment(3, 2, bill.PaymentStatusRejected), true},
+	}
+	for _, tt := range tests {
+		saved, err := s.SaveSlipPayment(ctx, tt.p)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if saved != tt.want {
+			t.Errorf("%s: saved = %v, want %v", tt.name, saved, tt.want)
+		}
+	}
+
+	// manual and imported payments may repeat a reference
+	manual := payment(4, 3, bill.PaymentStatusAccepted)
+	manual.Source = bill.PaymentSourceManual
+	if err := s.SavePayment(ctx, manual); err != nil {
+		t.Fatalf("SavePayment: %v", err)
+	}
+}
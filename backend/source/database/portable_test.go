@@ -0,0 +1,114 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+	"github.com/NoNiiEa/subShare-Discord/source/portable"
+)
+
+func TestPortableRoundTrip(t *testing.T) {
+	const (
+		owner  = "100000000000000001"
+		member = "100000000000000002"
+		guild  = "200000000000000002"
+	)
+	ctx := context.Background()
+
+	src, _, _ := openTestStore(t)
+	groups := group.NewService(src)
+	g, err := groups.CreateGroup(ctx, group.CreateGroupRequest{
+		Name: "Netflix", Amount: 419, DueDay: 5,
+		DiscordGuildID: "200000000000000001", OwnerDiscordID: owner,
+		Payment: group.PaymentAccount{Method: group.PromptPay, Account: "0812345678"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := groups.InviteGroup(ctx, group.InviteGroupRequest{OwnerID: owner, MemberIDs: []string{member}}, g.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := groups.AcceptInvite(ctx, group.AcceptInviteRequest{UserID: member}, g.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := groups.ResetPaymentForDueday(ctx, g.DueDay); err != nil {
+		t.Fatal(err)
+	}
+
+	bills, err := src.GetBillsByGroupID(ctx, g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var billID int64
+	for _, b := range bills {
+		if b.MemberID == member {
+			billID = b.ID
+		}
+	}
+	receipt := []byte("receipt bytes")
+	if _, _, err := groups.RecordManualPayment(ctx, group.RecordPaymentRequest{
+		OwnerID: owner, Method: group.Cash, Amount: 100.25,
+		AttachmentName: "receipt.jpg", AttachmentType: "image/jpeg", Attachment: receipt,
+	}, billID); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := portable.NewService(src, groups).Export(ctx, g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var moved portable.Document
+	if err := json.Unmarshal(data, &moved); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, _, _ := openTestStore(t)
+	dstGroups := group.NewService(dst)
+	result, err := portable.NewService(dst, dstGroups).Import(ctx, portable.ImportRequest{Document: moved, GuildID: guild})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported := result.Group
+	if imported.Name != g.Name || imported.DiscordGuildID != guild || len(imported.Members) != 2 {
+		t.Errorf("imported group = %+v", imported)
+	}
+	if len(result.BillIDs) != len(bills) || result.Payments != 1 || result.Ledger != len(doc.Ledger) {
+		t.Errorf("imported %d bills, %d payments, %d ledger entries; exported %d, 1, %d",
+			len(result.BillIDs), result.Payments, result.Ledger, len(bills), len(doc.Ledger))
+	}
+
+	before, err := groups.GetMemberLedger(ctx, g.ID, member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Balance <= 0 {
+		t.Fatalf("balance before the move = %v, want the unpaid rest of the bill", before.Balance)
+	}
+	after, err := dstGroups.GetMemberLedger(ctx, imported.ID, member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Balance != before.Balance || after.Dept != before.Dept || len(after.Entries) != len(before.Entries) {
+		t.Errorf("ledger after the move = %v over %d entries, want %v over %d", after.Balance, len(after.Entries), before.Balance, len(before.Entries))
+	}
+
+	payments, err := dst.GetPaymentsByBillID(ctx, result.BillIDs[billID])
+	if err != nil || len(payments) != 1 {
+		t.Fatalf("payments of the moved bill = %v, %v", payments, err)
+	}
+	attachment, err := dst.GetPaymentAttachment(ctx, payments[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(attachment.Attachment, receipt) {
+		t.Errorf("attachment = %q, want %q", attachment.Attachment, receipt)
+	}
+}
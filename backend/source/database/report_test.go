@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+func TestGroupReport(t *testing.T) {
+	const (
+		a = "100000000000000002"
+		b = "100000000000000003"
+	)
+	s, _, _ := openTestStore(t)
+	ctx := context.Background()
+
+	g := testGroup(1, "Netflix", 5)
+	g.Members = []group.GroupMember{
+		{MemberID: g.OwnerDiscordID, Status: group.MemberStatusActive},
+		{MemberID: a, Status: group.MemberStatusActive},
+		{MemberID: b, Status: group.MemberStatusActive},
+	}
+	if err := s.SaveGroup(ctx, g); err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(month, d int) time.Time { return time.Date(2024, time.Month(month), d, 0, 0, 0, 0, time.UTC) }
+	at := func(month, d int) *time.Time { t := day(month, d); return &t }
+	bills := []bill.Bill{
+		{ID: 1, MemberID: a, Month: 1, AmountDue: 100, AmountPaid: 100, Status: bill.BillStatusVerified, VerifiedAt: at(1, 3)},  // on time
+		{ID: 2, MemberID: a, Month: 2, AmountDue: 100, AmountPaid: 100, Status: bill.BillStatusVerified, VerifiedAt: at(2, 10)}, // late
+		{ID: 3, MemberID: b, Month: 1, AmountDue: 100, AmountPaid: 40, Status: bill.BillStatusPartiallyPaid},                    // open, past due
+		{ID: 4, MemberID: b, Month: 2, AmountDue: 100, Status: bill.BillStatusWaived},
+		{ID: 5, MemberID: b, Month: 3, AmountDue: 100, Status: bill.BillStatusCanceled},
+	}
+	for _, bl := range bills {
+		bl.Kind = bill.BillKindRecurring
+		bl.GroupID = g.ID
+		bl.Year = 2024
+		bl.Currency = g.Currency
+		bl.CreatedAt = day(bl.Month, 1)
+		bl.UpdatedAt = bl.CreatedAt
+		if err := s.SaveBill(ctx, bl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := group.NewService(s)
+
+	rate := func(v float64) *float64 { return &v }
+	type member struct {
+		bills, paid, onTime, late int
+		rate, avgDays             *float64
+		outstanding               float64
+	}
+	tests := []struct {
+		name    string
+		from    string
+		totals  group.ReportTotals
+		months  []string
+		members map[string]member
+	}{
+		{
+			name:   "every period",
+			totals: group.ReportTotals{Bills: 4, Due: 400, Collected: 240, Outstanding: 60, Waived: 100},
+			months: []string{"2024-01", "2024-02"},
+			members: map[string]member{
+				g.OwnerDiscordID: {},
+				a:                {bills: 2, paid: 2, onTime: 1, late: 1, rate: rate(0.5), avgDays: rate(5.5)},
+				b:                {bills: 2, late: 1, rate: rate(0), outstanding: 60},
+			},
+		},
+		{
+			name:   "from a period on",
+			from:   "2024-02",
+			totals: group.ReportTotals{Bills: 2, Due: 200, Collected: 100, Waived: 100},
+			months: []string{"2024-02"},
+			members: map[string]member{
+				g.OwnerDiscordID: {},
+				a:                {bills: 1, paid: 1, late: 1, rate: rate(0), avgDays: rate(9)},
+				b:                {bills: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := svc.GetGroupReport(ctx, group.ReportRequest{GroupID: g.ID, From: tt.from})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r.Totals != tt.totals {
+				t.Errorf("totals = %+v, want %+v", r.Totals, tt.totals)
+			}
+			var months []string
+			for _, m := range r.Months {
+				months = append(months, m.Period)
+			}
+			if !slices.Equal(months, tt.months) {
+				t.Errorf("months = %v, want %v", months, tt.months)
+			}
+
+			if len(r.Members) != len(tt.members) {
+				t.Fatalf("%d members, want %d", len(r.Members), len(tt.members))
+			}
+			for _, m := range r.Members {
+				want := tt.members[m.MemberID]
+				got := member{m.Bills, m.Paid, m.OnTime, m.Late, m.OnTimeRate, m.AvgDaysToPay, m.Outstanding}
+				if got.bills != want.bills || got.paid != want.paid || got.onTime != want.onTime || got.late != want.late ||
+					!sameRate(got.rate, want.rate) || !sameRate(got.avgDays, want.avgDays) || got.outstanding != want.outstanding {
+					t.Errorf("member %s = %+v, want %+v", m.MemberID, m, want)
+				}
+			}
+		})
+	}
+}
+
+func sameRate(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/settlement"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const createSettlementsTable = `
+CREATE TABLE IF NOT EXISTS settlements (
+    id             INTEGER PRIMARY KEY,
+    guild_id       TEXT NOT NULL,
+    currency       TEXT NOT NULL,
+    transfers_json TEXT NOT NULL,
+    bill_ids_json  TEXT NOT NULL,
+    status         TEXT NOT NULL DEFAULT 'completed', -- pending/completed/superseded
+    confirmed_by_json TEXT NOT NULL DEFAULT '[]',     -- payees who confirmed
+    actor_id       TEXT NOT NULL,
+    note           TEXT,
+    created_at     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_settlements_guild_id ON settlements(guild_id);
+`
+
+// createPendingSettlementIndex keeps one pending settlement per guild, so two
+// payees confirming at once can't each start their own.
+const createPendingSettlementIndex = `
+CREATE UNIQUE INDEX IF NOT EXISTS idx_settlements_pending ON settlements(guild_id)
+WHERE status = 'pending';
+`
+
+// ensureSettlementColumns upgrades settlements recorded before payees had to
+// confirm them; those all closed their bills.
+func (s *SQLiteStore) ensureSettlementColumns(ctx context.Context) error {
+	if err := s.ensureColumn(ctx, "settlements", "status", "TEXT NOT NULL DEFAULT 'completed'"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn(ctx, "settlements", "confirmed_by_json", "TEXT NOT NULL DEFAULT '[]'"); err != nil {
+		return err
+	}
+	_, err := s.conn(ctx).ExecContext(ctx, createPendingSettlementIndex)
+	return err
+}
+
+// GetOutstandingBillsByGuild returns the open bills of every group in a guild.
+func (s *SQLiteStore) GetOutstandingBillsByGuild(ctx context.Context, guildID string) ([]bill.Bill, error) {
+	const q = `SELECT` + billColumns + `
+FROM bills
+WHERE status IN ('pending', 'submitted', 'partially_paid', 'rejected')
+  AND group_id IN (SELECT id FROM groups WHERE discord_guild_id = ?)
+ORDER BY id ASC;
+`
+
+	bills, err := s.queryBills(ctx, q, guildID)
+	if err == ErrNotFound {
+		return []bill.Bill{}, nil
+	}
+	return bills, err
+}
+
+func (s *SQLiteStore) NextSettlementID(ctx context.Context) (int64, error) {
+	const q = `SELECT COALESCE(MAX(id), 0) + 1 FROM settlements;`
+
+	var nextID int64
+	if err := s.conn(ctx).QueryRowContext(ctx, q).Scan(&nextID); err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
+
+func (s *SQLiteStore) SaveSettlement(ctx context.Context, st settlement.Settlement) (bool, error) {
+	transfersJSON, err := json.Marshal(st.Transfers)
+	if err != nil {
+		return false, err
+	}
+	billIDsJSON, err := json.Marshal(st.BillIDs)
+	if err != nil {
+		return false, err
+	}
+	confirmedJSON, err := json.Marshal(st.ConfirmedBy)
+	if err != nil {
+		return false, err
+	}
+
+	const q = `
+INSERT INTO settlements (id, guild_id, currency, transfers_json, bill_ids_json, status, confirmed_by_json, actor_id, note, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+`
+
+	_, err = s.conn(ctx).ExecContext(ctx, q,
+		st.ID,
+		st.GuildID,
+		st.Currency,
+		string(transfersJSON),
+		string(billIDsJSON),
+		string(st.Status),
+		string(confirmedJSON),
+		st.ActorID,
+		nullableString(st.Note),
+		st.CreatedAt.Format(time.RFC3339),
+	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *SQLiteStore) UpdateSettlement(ctx context.Context, st settlement.Settlement, prev []string) (bool, error) {
+	confirmedJSON, err := json.Marshal(st.ConfirmedBy)
+	if err != nil {
+		return false, err
+	}
+	prevJSON, err := json.Marshal(prev)
+	if err != nil {
+		return false, err
+	}
+
+	const q = `
+UPDATE settlements
+SET status = ?, confirmed_by_json = ?
+WHERE id = ? AND status = 'pending' AND confirmed_by_json = ?;
+`
+
+	res, err := s.conn(ctx).ExecContext(ctx, q, string(st.Status), string(confirmedJSON), st.ID, string(prevJSON))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *SQLiteStore) GetSettlementsByGuild(ctx context.Context, guildID string) ([]settlement.Settlement, error) {
+	const q = `
+SELECT id, guild_id, currency, transfers_json, bill_ids_json, status, confirmed_by_json, actor_id, note, created_at
+FROM settlements
+WHERE guild_id = ?
+ORDER BY id DESC;
+`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, q, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []settlement.Settlement{}
+	for rows.Next() {
+		var (
+			st                                        settlement.Settlement
+			transfersJSON, billIDsJSON, confirmedJSON string
+			note                                      *string
+			createdAt                                 string
+		)
+		if err := rows.Scan(&st.ID, &st.GuildID, &st.Currency, &transfersJSON, &billIDsJSON, &st.Status, &confirmedJSON, &st.ActorID, &note, &createdAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(transfersJSON), &st.Transfers); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(billIDsJSON), &st.BillIDs); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(confirmedJSON), &st.ConfirmedBy); err != nil {
+			return nil, err
+		}
+		st.Note = derefString(note)
+		st.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		result = append(result, st)
+	}
+
+	return result, rows.Err()
+}
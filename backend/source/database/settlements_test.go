@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/settlement"
+)
+
+func TestSettlementConfirmations(t *testing.T) {
+	s, _, _ := openTestStore(t)
+	ctx := context.Background()
+
+	pending := func(id int64, by ...string) settlement.Settlement {
+		return settlement.Settlement{
+			ID:          id,
+			GuildID:     "guild",
+			Currency:    "THB",
+			Transfers:   []settlement.Transfer{{From: "a", To: "c", Amount: 10}},
+			BillIDs:     []int64{1},
+			Status:      settlement.StatusPending,
+			ConfirmedBy: by,
+			ActorID:     by[0],
+			CreatedAt:   time.Now(),
+		}
+	}
+
+	st := pending(1, "c")
+	if saved, err := s.SaveSettlement(ctx, st); err != nil || !saved {
+		t.Fatalf("SaveSettlement = %v, %v", saved, err)
+	}
+	if saved, err := s.SaveSettlement(ctx, pending(2, "d")); err != nil || saved {
+		t.Fatalf("second pending settlement: SaveSettlement = %v, %v; want false", saved, err)
+	}
+
+	confirmed := st
+	confirmed.ConfirmedBy = []string{"c", "d"}
+	confirmed.Status = settlement.StatusCompleted
+
+	// a confirmation based on a stale list loses
+	if ok, err := s.UpdateSettlement(ctx, confirmed, []string{}); err != nil || ok {
+		t.Fatalf("stale UpdateSettlement = %v, %v; want false", ok, err)
+	}
+	if ok, err := s.UpdateSettlement(ctx, confirmed, st.ConfirmedBy); err != nil || !ok {
+		t.Fatalf("UpdateSettlement = %v, %v", ok, err)
+	}
+	if ok, err := s.UpdateSettlement(ctx, confirmed, confirmed.ConfirmedBy); err != nil || ok {
+		t.Fatalf("completed settlement updated again: %v, %v", ok, err)
+	}
+
+	got, err := s.GetSettlementsByGuild(ctx, "guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Status != settlement.StatusCompleted || len(got[0].ConfirmedBy) != 2 {
+		t.Fatalf("settlements = %+v", got)
+	}
+
+	// with nothing pending a new plan can start
+	if saved, err := s.SaveSettlement(ctx, pending(2, "d")); err != nil || !saved {
+		t.Fatalf("SaveSettlement after completion = %v, %v", saved, err)
+	}
+}
@@ -7,8 +7,9 @@ import (
 	"errors"
 	"time"
 
-	"github.com/NoNiiEa/subShare-Discord/source/group"
 	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
 )
 
 type SQLiteStore struct {
@@ -31,16 +32,34 @@ CREATE TABLE IF NOT EXISTS groups (
     discord_guild_id  TEXT NOT NULL,
     owner_discord_id  TEXT NOT NULL,
 	payment			  TEXT NOT NULL,
-    created_at        TEXT NOT NULL
+    payment_accounts  TEXT,             -- JSON list, payment holds the preferred one
+    currency          TEXT NOT NULL DEFAULT 'THB',
+    created_at        TEXT NOT NULL,
+    version           INTEGER NOT NULL DEFAULT 1 -- bumped by every update
 );`
-	_, err := s.db.ExecContext(ctx, createGroupsTable)
+	_, err := s.conn(ctx).ExecContext(ctx, createGroupsTable)
 	if err != nil {
 		return err
 	}
 
+	if err := s.ensureColumn(ctx, "groups", "payment_accounts", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := s.ensureColumn(ctx, "groups", "currency", "TEXT NOT NULL DEFAULT 'THB'"); err != nil {
+		return err
+	}
+
+	if err := s.ensureColumn(ctx, "groups", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+
 	const createBillsTable = `
 	CREATE TABLE IF NOT EXISTS bills (
 		id               INTEGER PRIMARY KEY,
+    	kind             TEXT NOT NULL DEFAULT 'recurring', -- recurring/expense
+    	expense_id       INTEGER,
+    	payee_id         TEXT,                  -- Discord user ID the member owes
     	group_id         INTEGER NOT NULL,
  		member_id        TEXT NOT NULL,         -- Discord user ID
     	year             INTEGER NOT NULL,      -- e.g. 2026
@@ -49,6 +68,13 @@ CREATE TABLE IF NOT EXISTS groups (
     	amount_due       REAL NOT NULL,
     	amount_paid      REAL NOT NULL DEFAULT 0,
     	currency         TEXT NOT NULL,         -- e.g. "THB"
+
+    	settlement_currency   TEXT,             -- rate snapshot taken at issue time
+    	settlement_amount_due REAL,
+    	exchange_rate         REAL,
+    	rate_source           TEXT,
+    	rate_as_of            TEXT,
+
     	status           TEXT NOT NULL,         -- pending/submitted/verified/rejected/canceled
 
     	description      TEXT,
@@ -59,112 +85,151 @@ CREATE TABLE IF NOT EXISTS groups (
     	updated_at       TEXT NOT NULL,
     	submitted_at     TEXT,
     	verified_at      TEXT,
-    	rejected_at      TEXT
+    	rejected_at      TEXT,
+    	paid_at          TEXT                   -- transfer time taken from the slip
 
 	);
 	`
 
-	_, err = s.db.ExecContext(ctx, createBillsTable)
+	_, err = s.conn(ctx).ExecContext(ctx, createBillsTable)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	if err := s.ensureColumn(ctx, "bills", "paid_at", "TEXT"); err != nil {
+		return err
+	}
 
-func (s *SQLiteStore) NextGroupID(ctx context.Context) (int64, error) {
-	const q = `SELECT COALESCE(MAX(id), 0) + 1 FROM groups;`
+	if err := s.ensureBillRateColumns(ctx); err != nil {
+		return err
+	}
 
-	var nextID int64
-	if err := s.db.QueryRowContext(ctx, q).Scan(&nextID); err != nil {
-		return 0, err
+	if err := s.ensureBillKindColumns(ctx); err != nil {
+		return err
 	}
-	return nextID, nil
-}
 
-func (s *SQLiteStore) SaveGroup(ctx context.Context, g group.Group) error {
-	membersJSON, err := json.Marshal(g.Members)
-	if err != nil {
+	if _, err := s.conn(ctx).ExecContext(ctx, createBillIndexes); err != nil {
 		return err
 	}
 
-	paymentJSON, err := json.Marshal(g.Payment)
-	if err != nil {
+	if _, err := s.conn(ctx).ExecContext(ctx, createPaymentsTable); err != nil {
 		return err
 	}
 
-	const q = `
-INSERT INTO groups (
-    id,
-    name,
-    amount,
-	amount_per_member,
-    due_day,
-    members_json,
-    discord_guild_id,
-    owner_discord_id,
-	payment,
-    created_at
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
-
-	_, err = s.db.ExecContext(ctx, q,
-		g.ID,
-		g.Name,
-		g.Amount,
-		g.AmountPerMember,
-		g.DueDay,
-		string(membersJSON),
-		g.DiscordGuildID,
-		g.OwnerDiscordID,
-		string(paymentJSON),
-		g.CreateAt.Format(time.RFC3339),
-	)
-	return err
+	if err := s.ensurePaymentColumns(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.conn(ctx).ExecContext(ctx, backfillPayments); err != nil {
+		return err
+	}
+
+	if err := s.ensureSlipReferenceIndex(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.conn(ctx).ExecContext(ctx, createLedgerEntriesTable); err != nil {
+		return err
+	}
+
+	if err := s.migrateLedgerUnits(ctx); err != nil {
+		return err
+	}
+
+	if err := s.backfillLedger(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.conn(ctx).ExecContext(ctx, createBillEventsTable); err != nil {
+		return err
+	}
+
+	if _, err := s.conn(ctx).ExecContext(ctx, createExpensesTable); err != nil {
+		return err
+	}
+
+	if _, err := s.conn(ctx).ExecContext(ctx, createSettlementsTable); err != nil {
+		return err
+	}
+
+	if err := s.ensureSettlementColumns(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.conn(ctx).ExecContext(ctx, createGroupMembersTable); err != nil {
+		return err
+	}
+
+	if err := s.backfillGroupMembers(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.conn(ctx).ExecContext(ctx, createAuditEventsTable); err != nil {
+		return err
+	}
+
+	if _, err := s.conn(ctx).ExecContext(ctx, createIdempotencyKeysTable); err != nil {
+		return err
+	}
+
+	return s.setSchemaVersion(ctx)
 }
 
-var ErrNotFound = errors.New("store: not found")
+func (s *SQLiteStore) NextGroupID(ctx context.Context) (int64, error) {
+	const q = `SELECT COALESCE(MAX(id), 0) + 1 FROM groups;`
 
-func (s *SQLiteStore) GetGroup(ctx context.Context, id int64) (*group.Group, error) {
-	const q = `
-SELECT
+	var nextID int64
+	if err := s.conn(ctx).QueryRowContext(ctx, q).Scan(&nextID); err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
+
+// groupColumns is the column list shared by every group query; scanGroup reads
+// rows in exactly this order.
+const groupColumns = `
     id,
     name,
     amount,
-	amount_per_member,
+    amount_per_member,
     due_day,
     members_json,
     discord_guild_id,
     owner_discord_id,
-	payment,
-    created_at
-FROM groups
-WHERE id = ?;`
+    payment,
+    payment_accounts,
+    currency,
+    created_at,
+    version`
 
-	row := s.db.QueryRowContext(ctx, q, id)
+func scanGroup(row rowScanner) (*group.Group, error) {
 	var (
-		g group.Group
-		membersJSON string
-		paymentJSON string
-		createdAtStr string
+		g               group.Group
+		amountPerMember float64
+		membersJSON     string
+		paymentJSON     string
+		accountsJSON    *string
+		createdAtStr    string
 	)
 
 	if err := row.Scan(
 		&g.ID,
 		&g.Name,
 		&g.Amount,
-		&g.AmountPerMember,
+		&amountPerMember,
 		&g.DueDay,
 		&membersJSON,
 		&g.DiscordGuildID,
 		&g.OwnerDiscordID,
 		&paymentJSON,
+		&accountsJSON,
+		&g.Currency,
 		&createdAtStr,
+		&g.Version,
 	); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
-		}
 		return nil, err
 	}
+	g.AmountPerMember = currency.ToMinor(amountPerMember)
 
 	if err := json.Unmarshal([]byte(membersJSON), &g.Members); err != nil {
 		return nil, err
@@ -174,6 +239,17 @@ WHERE id = ?;`
 		return nil, err
 	}
 
+	if accountsJSON != nil && *accountsJSON != "" {
+		if err := json.Unmarshal([]byte(*accountsJSON), &g.PaymentAccounts); err != nil {
+			return nil, err
+		}
+	}
+	if len(g.PaymentAccounts) == 0 && g.Payment.Account != "" {
+		// groups created before payment_accounts existed
+		g.Payment.Preferred = true
+		g.PaymentAccounts = []group.PaymentAccount{g.Payment}
+	}
+
 	t, err := time.Parse(time.RFC3339, createdAtStr)
 	if err != nil {
 		return nil, err
@@ -183,193 +259,212 @@ WHERE id = ?;`
 	return &g, nil
 }
 
-func (s *SQLiteStore) DeleteGroup(ctx context.Context, id int64) error {
-	const q = `DELETE FROM groups
-	WHERE id = ?`
+func (s *SQLiteStore) queryGroups(ctx context.Context, q string, args ...any) ([]group.Group, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	_, err := s.db.ExecContext(ctx, q, id)
-	return err
+	var result []group.Group
+
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *g)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-func (s *SQLiteStore) UpdateGroup(ctx context.Context, id int64, g group.Group) error {
-	membersJSON, err := json.Marshal(g.Members)
+func (s *SQLiteStore) SaveGroup(ctx context.Context, g group.Group) error {
+	membersJSON, err := json.Marshal(membersForStorage(g.Members))
 	if err != nil {
 		return err
 	}
+
 	paymentJSON, err := json.Marshal(g.Payment)
 	if err != nil {
 		return err
 	}
 
+	accountsJSON, err := json.Marshal(g.PaymentAccounts)
+	if err != nil {
+		return err
+	}
+
 	const q = `
-	UPDATE groups
-	SET 
-    name = ?,
-    amount = ?,
-	amount_per_member = ?,
-    due_day = ?,
-    members_json = ?,
-    discord_guild_id = ?,
-    owner_discord_id = ?,
-	payment = ?
-	WHERE id = ?
-	`
+INSERT INTO groups (` + groupColumns + `
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	version := g.Version
+	if version < 1 {
+		version = 1
+	}
+
+	// the row and its group_members mirror are written together
+	return s.RunInTx(ctx, func(ctx context.Context) error {
+		_, err := s.conn(ctx).ExecContext(ctx, q,
+			g.ID,
+			g.Name,
+			g.Amount,
+			g.AmountPerMember.Major(),
+			g.DueDay,
+			string(membersJSON),
+			g.DiscordGuildID,
+			g.OwnerDiscordID,
+			string(paymentJSON),
+			string(accountsJSON),
+			g.Currency,
+			g.CreateAt.Format(time.RFC3339),
+			version,
+		)
+		if err != nil {
+			return err
+		}
 
-	_, err = s.db.ExecContext(ctx, q, g.Name, g.Amount, g.AmountPerMember, g.DueDay, string(membersJSON), g.DiscordGuildID, g.OwnerDiscordID, string(paymentJSON), id)
-	return err
+		return s.syncGroupMembers(ctx, g.ID, g.Members)
+	})
 }
 
-func (s *SQLiteStore) GetGroupByDueday(ctx context.Context, dueDay int) ([]group.Group, error){
-	const q = `
-	SELECT
-    id,
-    name,
-    amount,
-	amount_per_member,
-    due_day,
-    members_json,
-    discord_guild_id,
-    owner_discord_id,
-	payment,
-    created_at
-	FROM groups
-	WHERE due_day = ?;
-	`
+var ErrNotFound = errors.New("store: not found")
 
-	rows, err := s.db.QueryContext(ctx, q, dueDay)
-	if err != nil {
-		return nil, err
+// membersForStorage drops the ledger-derived fields before members are written
+// to members_json; dept and credit live in ledger_entries.
+func membersForStorage(members []group.GroupMember) []group.GroupMember {
+	out := make([]group.GroupMember, len(members))
+	for i, m := range members {
+		m.Dept = 0
+		m.Credit = 0
+		out[i] = m
 	}
-	defer rows.Close()
-
-	var result []group.Group
+	return out
+}
 
-	for rows.Next() {
-		var (
-			g group.Group
-			membersJSON string
-			paymentJSON string
-			createAtStr string
-		)
+func (s *SQLiteStore) GetGroup(ctx context.Context, id int64) (*group.Group, error) {
+	const q = `SELECT` + groupColumns + `
+FROM groups
+WHERE id = ?;`
 
-		if err := rows.Scan(
-			&g.ID,
-			&g.Name,
-			&g.Amount,
-			&g.AmountPerMember,
-			&g.DueDay,
-			&membersJSON,
-			&g.DiscordGuildID,
-			&g.OwnerDiscordID,
-			&paymentJSON,
-			&createAtStr,
-		); err != nil {
-			return nil, err
+	g, err := scanGroup(s.conn(ctx).QueryRowContext(ctx, q, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
 		}
+		return nil, err
+	}
 
-		if err := json.Unmarshal([]byte(membersJSON), &g.Members); err != nil {
-			return nil, err
-		}
+	return g, nil
+}
 
-		if err := json.Unmarshal([]byte(paymentJSON), &g.Payment); err != nil {
-			return nil, err
-		}
+func (s *SQLiteStore) DeleteGroup(ctx context.Context, id int64) error {
+	const q = `DELETE FROM groups
+	WHERE id = ?`
 
-		t, err := time.Parse(time.RFC3339, createAtStr)
-		if err != nil {
-			return nil, err
+	return s.RunInTx(ctx, func(ctx context.Context) error {
+		if _, err := s.conn(ctx).ExecContext(ctx, q, id); err != nil {
+			return err
 		}
-		g.CreateAt = t
-
-		result = append(result, g)
-	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return result, nil
+		_, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM group_members WHERE group_id = ?;`, id)
+		return err
+	})
 }
 
-func (s *SQLiteStore) SaveBill(ctx context.Context, b bill.Bill) error {
-	const q = `
-INSERT INTO bills (
-    id,
-    group_id,
-    member_id,
-    year,
-    month,
-    amount_due,
-    amount_paid,
-    currency,
-    status,
-    description,
-    proof_json,
-    created_at,
-    updated_at,
-    submitted_at,
-    verified_at,
-    rejected_at
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
-`
+func (s *SQLiteStore) UpdateGroup(ctx context.Context, id int64, g group.Group) error {
+	_, err := s.updateGroup(ctx, id, g, nil)
+	return err
+}
 
-	// handle nullable times
-	var submittedAt, verifiedAt, rejectedAt interface{}
+// UpdateGroupVersion writes g only while the group is still at version. It
+// returns false when another update got there first.
+func (s *SQLiteStore) UpdateGroupVersion(ctx context.Context, id, version int64, g group.Group) (bool, error) {
+	return s.updateGroup(ctx, id, g, &version)
+}
 
-	if b.SubmittedAt != nil {
-		submittedAt = b.SubmittedAt.Format(time.RFC3339)
-	} else {
-		submittedAt = nil
+// updateGroup writes g and bumps the group's version; with version set the
+// write only happens at that version.
+func (s *SQLiteStore) updateGroup(ctx context.Context, id int64, g group.Group, version *int64) (bool, error) {
+	membersJSON, err := json.Marshal(membersForStorage(g.Members))
+	if err != nil {
+		return false, err
 	}
-
-	if b.VerifiedAt != nil {
-		verifiedAt = b.VerifiedAt.Format(time.RFC3339)
-	} else {
-		verifiedAt = nil
+	paymentJSON, err := json.Marshal(g.Payment)
+	if err != nil {
+		return false, err
+	}
+	accountsJSON, err := json.Marshal(g.PaymentAccounts)
+	if err != nil {
+		return false, err
 	}
 
-	if b.RejectedAt != nil {
-		rejectedAt = b.RejectedAt.Format(time.RFC3339)
-	} else {
-		rejectedAt = nil
+	q := `
+	UPDATE groups
+	SET 
+    name = ?,
+    amount = ?,
+	amount_per_member = ?,
+    due_day = ?,
+    members_json = ?,
+    discord_guild_id = ?,
+    owner_discord_id = ?,
+	payment = ?,
+	payment_accounts = ?,
+	currency = ?,
+	version = version + 1
+	WHERE id = ?
+	`
+	args := []any{g.Name, g.Amount, g.AmountPerMember.Major(), g.DueDay, string(membersJSON), g.DiscordGuildID, g.OwnerDiscordID, string(paymentJSON), string(accountsJSON), g.Currency, id}
+	if version != nil {
+		q += "AND version = ?\n"
+		args = append(args, *version)
 	}
 
-	_, err := s.db.ExecContext(ctx, q,
-		b.ID,
-		b.GroupID,
-		b.MemberID,
-		b.Year,
-		b.Month,
-		b.AmountDue,
-		b.AmountPaid,
-		b.Currency,
-		string(b.Status), // or b.Status if it's already string
-		b.Description,
-		b.ProofJSON,
-		b.CreatedAt.Format(time.RFC3339),
-		b.UpdatedAt.Format(time.RFC3339),
-		submittedAt,
-		verifiedAt,
-		rejectedAt,
-	)
+	// the row and its group_members mirror are written together
+	var updated bool
+	err = s.RunInTx(ctx, func(ctx context.Context) error {
+		res, err := s.conn(ctx).ExecContext(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		if version != nil {
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rows == 0 {
+				return nil
+			}
+		}
 
-	return err
+		updated = true
+		return s.syncGroupMembers(ctx, id, g.Members)
+	})
+	return updated, err
 }
 
-func (s *SQLiteStore) NextBillID(ctx context.Context) (int64, error) {
-	const q = `SELECT COALESCE(MAX(id), 0) + 1 FROM bills;`
+func (s *SQLiteStore) GetGroupByDueday(ctx context.Context, dueDay int) ([]group.Group, error) {
+	const q = `SELECT` + groupColumns + `
+	FROM groups
+	WHERE due_day = ?;
+	`
 
-	var nextID int64
-	if err := s.db.QueryRowContext(ctx, q).Scan(&nextID); err != nil {
-		return 0, err
-	}
-	return nextID, nil
+	return s.queryGroups(ctx, q, dueDay)
 }
 
-func (s *SQLiteStore) GetBillByID(ctx context.Context, id int64) (*bill.Bill, error) {
-	const q = `
-SELECT
+// billColumns is the column list shared by every bill query; scanBill reads
+// rows in exactly this order.
+const billColumns = `
     id,
+    kind,
+    expense_id,
+    payee_id,
     group_id,
     member_id,
     year,
@@ -377,6 +472,11 @@ SELECT
     amount_due,
     amount_paid,
     currency,
+    settlement_currency,
+    settlement_amount_due,
+    exchange_rate,
+    rate_source,
+    rate_as_of,
     status,
     description,
     proof_json,
@@ -384,19 +484,25 @@ SELECT
     updated_at,
     submitted_at,
     verified_at,
-    rejected_at
-FROM bills
-WHERE id = ?;
-`
+    rejected_at,
+    paid_at`
 
-	row := s.db.QueryRowContext(ctx, q, id)
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
+func scanBill(row rowScanner) (*bill.Bill, error) {
 	var b bill.Bill
 	var createdAt, updatedAt string
-	var submittedAt, verifiedAt, rejectedAt *string
+	var submittedAt, verifiedAt, rejectedAt, paidAt *string
+	var rateSource, rateAsOf *string
+	var payeeID *string
 
-	err := row.Scan(
+	if err := row.Scan(
 		&b.ID,
+		&b.Kind,
+		&b.ExpenseID,
+		&payeeID,
 		&b.GroupID,
 		&b.MemberID,
 		&b.Year,
@@ -404,6 +510,11 @@ WHERE id = ?;
 		&b.AmountDue,
 		&b.AmountPaid,
 		&b.Currency,
+		&b.SettlementCurrency,
+		&b.SettlementAmountDue,
+		&b.ExchangeRate,
+		&rateSource,
+		&rateAsOf,
 		&b.Status,
 		&b.Description,
 		&b.ProofJSON,
@@ -412,59 +523,26 @@ WHERE id = ?;
 		&submittedAt,
 		&verifiedAt,
 		&rejectedAt,
-	)
-	if err == sql.ErrNoRows {
-		return nil, ErrNotFound
-	}
-	if err != nil {
+		&paidAt,
+	); err != nil {
 		return nil, err
 	}
 
-	// Convert timestamps
 	b.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
 	b.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-
-	if submittedAt != nil {
-		t, _ := time.Parse(time.RFC3339, *submittedAt)
-		b.SubmittedAt = &t
-	}
-	if verifiedAt != nil {
-		t, _ := time.Parse(time.RFC3339, *verifiedAt)
-		b.VerifiedAt = &t
-	}
-	if rejectedAt != nil {
-		t, _ := time.Parse(time.RFC3339, *rejectedAt)
-		b.RejectedAt = &t
-	}
+	b.SubmittedAt = parseNullableTime(submittedAt)
+	b.VerifiedAt = parseNullableTime(verifiedAt)
+	b.RejectedAt = parseNullableTime(rejectedAt)
+	b.PaidAt = parseNullableTime(paidAt)
+	b.PayeeID = derefString(payeeID)
+	b.RateSource = derefString(rateSource)
+	b.RateAsOf = parseNullableTime(rateAsOf)
 
 	return &b, nil
 }
 
-func (s *SQLiteStore) GetBillsByGroupAndMember(ctx context.Context, groupID int64, memberID string) ([]bill.Bill, error) {
-	const q = `
-SELECT
-    id,
-    group_id,
-    member_id,
-    year,
-    month,
-    amount_due,
-    amount_paid,
-    currency,
-    status,
-    description,
-    proof_json,
-    created_at,
-    updated_at,
-    submitted_at,
-    verified_at,
-    rejected_at
-FROM bills
-WHERE group_id = ? AND member_id = ?
-ORDER BY year DESC, month DESC;
-`
-
-	rows, err := s.db.QueryContext(ctx, q, groupID, memberID)
+func (s *SQLiteStore) queryBills(ctx context.Context, q string, args ...any) ([]bill.Bill, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -473,48 +551,15 @@ ORDER BY year DESC, month DESC;
 	var result []bill.Bill
 
 	for rows.Next() {
-		var b bill.Bill
-		var createdAt, updatedAt string
-		var submittedAt, verifiedAt, rejectedAt *string
-
-		if err := rows.Scan(
-			&b.ID,
-			&b.GroupID,
-			&b.MemberID,
-			&b.Year,
-			&b.Month,
-			&b.AmountDue,
-			&b.AmountPaid,
-			&b.Currency,
-			&b.Status,
-			&b.Description,
-			&b.ProofJSON,
-			&createdAt,
-			&updatedAt,
-			&submittedAt,
-			&verifiedAt,
-			&rejectedAt,
-		); err != nil {
+		b, err := scanBill(rows)
+		if err != nil {
 			return nil, err
 		}
+		result = append(result, *b)
+	}
 
-		b.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-		b.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-
-		if submittedAt != nil {
-			t, _ := time.Parse(time.RFC3339, *submittedAt)
-			b.SubmittedAt = &t
-		}
-		if verifiedAt != nil {
-			t, _ := time.Parse(time.RFC3339, *verifiedAt)
-			b.VerifiedAt = &t
-		}
-		if rejectedAt != nil {
-			t, _ := time.Parse(time.RFC3339, *rejectedAt)
-			b.RejectedAt = &t
-		}
-
-		result = append(result, b)
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
 	if len(result) == 0 {
@@ -524,55 +569,60 @@ ORDER BY year DESC, month DESC;
 	return result, nil
 }
 
-func (s *SQLiteStore) GetBillByGroupMemberCycle(ctx context.Context, groupID int64, memberID string, year, month int) (*bill.Bill, error) {
+func (s *SQLiteStore) SaveBill(ctx context.Context, b bill.Bill) error {
 	const q = `
-SELECT
-    id,
-    group_id,
-    member_id,
-    year,
-    month,
-    amount_due,
-    amount_paid,
-    currency,
-    status,
-    description,
-    proof_json,
-    created_at,
-    updated_at,
-    submitted_at,
-    verified_at,
-    rejected_at
-FROM bills
-WHERE group_id = ? AND member_id = ? AND year = ? AND month = ?
-LIMIT 1;
+INSERT INTO bills (` + billColumns + `
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
 `
 
-	row := s.db.QueryRowContext(ctx, q, groupID, memberID, year, month)
+	_, err := s.conn(ctx).ExecContext(ctx, q,
+		b.ID,
+		string(b.Kind),
+		b.ExpenseID,
+		nullableString(b.PayeeID),
+		b.GroupID,
+		b.MemberID,
+		b.Year,
+		b.Month,
+		b.AmountDue,
+		b.AmountPaid,
+		b.Currency,
+		b.SettlementCurrency,
+		b.SettlementAmountDue,
+		b.ExchangeRate,
+		nullableString(b.RateSource),
+		nullableTime(b.RateAsOf),
+		string(b.Status),
+		b.Description,
+		b.ProofJSON,
+		b.CreatedAt.Format(time.RFC3339),
+		b.UpdatedAt.Format(time.RFC3339),
+		nullableTime(b.SubmittedAt),
+		nullableTime(b.VerifiedAt),
+		nullableTime(b.RejectedAt),
+		nullableTime(b.PaidAt),
+	)
 
-	var b bill.Bill
-	var createdAt, updatedAt string
-	var submittedAt, verifiedAt, rejectedAt *string
+	return err
+}
 
-	err := row.Scan(
-		&b.ID,
-		&b.GroupID,
-		&b.MemberID,
-		&b.Year,
-		&b.Month,
-		&b.AmountDue,
-		&b.AmountPaid,
-		&b.Currency,
-		&b.Status,
-		&b.Description,
-		&b.ProofJSON,
-		&createdAt,
-		&updatedAt,
-		&submittedAt,
-		&verifiedAt,
-		&rejectedAt,
-	)
+func (s *SQLiteStore) NextBillID(ctx context.Context) (int64, error) {
+	const q = `SELECT COALESCE(MAX(id), 0) + 1 FROM bills;`
+
+	var nextID int64
+	if err := s.conn(ctx).QueryRowContext(ctx, q).Scan(&nextID); err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
+
+func (s *SQLiteStore) GetBillByID(ctx context.Context, id int64) (*bill.Bill, error) {
+	const q = `SELECT` + billColumns + `
+FROM bills
+WHERE id = ?;
+`
 
+	b, err := scanBill(s.conn(ctx).QueryRowContext(ctx, q, id))
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	}
@@ -580,191 +630,55 @@ LIMIT 1;
 		return nil, err
 	}
 
-	b.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-	b.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return b, nil
+}
 
-	if submittedAt != nil {
-		t, _ := time.Parse(time.RFC3339, *submittedAt)
-		b.SubmittedAt = &t
-	}
-	if verifiedAt != nil {
-		t, _ := time.Parse(time.RFC3339, *verifiedAt)
-		b.VerifiedAt = &t
-	}
-	if rejectedAt != nil {
-		t, _ := time.Parse(time.RFC3339, *rejectedAt)
-		b.RejectedAt = &t
-	}
+func (s *SQLiteStore) GetBillsByGroupAndMember(ctx context.Context, groupID int64, memberID string) ([]bill.Bill, error) {
+	const q = `SELECT` + billColumns + `
+FROM bills
+WHERE group_id = ? AND member_id = ?
+ORDER BY year DESC, month DESC;
+`
 
-	return &b, nil
+	return s.queryBills(ctx, q, groupID, memberID)
 }
 
-func (s *SQLiteStore) GetBillsByMemberID(ctx context.Context, memberID string) ([]bill.Bill, error) {
-	const q = `
-SELECT
-    id,
-    group_id,
-    member_id,
-    year,
-    month,
-    amount_due,
-    amount_paid,
-    currency,
-    status,
-    description,
-    proof_json,
-    created_at,
-    updated_at,
-    submitted_at,
-    verified_at,
-    rejected_at
+func (s *SQLiteStore) GetBillByGroupMemberCycle(ctx context.Context, groupID int64, memberID string, year, month int) (*bill.Bill, error) {
+	const q = `SELECT` + billColumns + `
 FROM bills
-WHERE member_id = ?
-ORDER BY year DESC, month DESC;
+WHERE group_id = ? AND member_id = ? AND year = ? AND month = ? AND kind = 'recurring'
+LIMIT 1;
 `
 
-	rows, err := s.db.QueryContext(ctx, q, memberID)
+	b, err := scanBill(s.conn(ctx).QueryRowContext(ctx, q, groupID, memberID, year, month))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var result []bill.Bill
 
-	for rows.Next() {
-		var b bill.Bill
-		var createdAt, updatedAt string
-		var submittedAt, verifiedAt, rejectedAt *string
-
-		if err := rows.Scan(
-			&b.ID,
-			&b.GroupID,
-			&b.MemberID,
-			&b.Year,
-			&b.Month,
-			&b.AmountDue,
-			&b.AmountPaid,
-			&b.Currency,
-			&b.Status,
-			&b.Description,
-			&b.ProofJSON,
-			&createdAt,
-			&updatedAt,
-			&submittedAt,
-			&verifiedAt,
-			&rejectedAt,
-		); err != nil {
-			return nil, err
-		}
-
-		b.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-		b.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-
-		if submittedAt != nil {
-			t, _ := time.Parse(time.RFC3339, *submittedAt)
-			b.SubmittedAt = &t
-		}
-		if verifiedAt != nil {
-			t, _ := time.Parse(time.RFC3339, *verifiedAt)
-			b.VerifiedAt = &t
-		}
-		if rejectedAt != nil {
-			t, _ := time.Parse(time.RFC3339, *rejectedAt)
-			b.RejectedAt = &t
-		}
-
-		result = append(result, b)
-	}
+	return b, nil
+}
 
-	if len(result) == 0 {
-		return nil, ErrNotFound
-	}
+func (s *SQLiteStore) GetBillsByMemberID(ctx context.Context, memberID string) ([]bill.Bill, error) {
+	const q = `SELECT` + billColumns + `
+FROM bills
+WHERE member_id = ?
+ORDER BY year DESC, month DESC;
+`
 
-	return result, nil
+	return s.queryBills(ctx, q, memberID)
 }
 
 func (s *SQLiteStore) GetBillsByGroupID(ctx context.Context, groupID int64) ([]bill.Bill, error) {
-	const q = `
-SELECT
-    id,
-    group_id,
-    member_id,
-    year,
-    month,
-    amount_due,
-    amount_paid,
-    currency,
-    status,
-    description,
-    proof_json,
-    created_at,
-    updated_at,
-    submitted_at,
-    verified_at,
-    rejected_at
+	const q = `SELECT` + billColumns + `
 FROM bills
 WHERE group_id = ?
 ORDER BY year DESC, month DESC, member_id ASC;
 `
 
-	rows, err := s.db.QueryContext(ctx, q, groupID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var result []bill.Bill
-
-	for rows.Next() {
-		var b bill.Bill
-		var createdAt, updatedAt string
-		var submittedAt, verifiedAt, rejectedAt *string
-
-		if err := rows.Scan(
-			&b.ID,
-			&b.GroupID,
-			&b.MemberID,
-			&b.Year,
-			&b.Month,
-			&b.AmountDue,
-			&b.AmountPaid,
-			&b.Currency,
-			&b.Status,
-			&b.Description,
-			&b.ProofJSON,
-			&createdAt,
-			&updatedAt,
-			&submittedAt,
-			&verifiedAt,
-			&rejectedAt,
-		); err != nil {
-			return nil, err
-		}
-
-		b.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-		b.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-
-		if submittedAt != nil {
-			t, _ := time.Parse(time.RFC3339, *submittedAt)
-			b.SubmittedAt = &t
-		}
-		if verifiedAt != nil {
-			t, _ := time.Parse(time.RFC3339, *verifiedAt)
-			b.VerifiedAt = &t
-		}
-		if rejectedAt != nil {
-			t, _ := time.Parse(time.RFC3339, *rejectedAt)
-			b.RejectedAt = &t
-		}
-
-		result = append(result, b)
-	}
-
-	if len(result) == 0 {
-		return nil, ErrNotFound
-	}
-
-	return result, nil
+	return s.queryBills(ctx, q, groupID)
 }
 
 func (s *SQLiteStore) UpdateBill(ctx context.Context, b bill.Bill) (*bill.Bill, error) {
@@ -776,6 +690,9 @@ func (s *SQLiteStore) UpdateBill(ctx context.Context, b bill.Bill) (*bill.Bill,
 	const q = `
 UPDATE bills
 SET
+    kind        = ?,
+    expense_id  = ?,
+    payee_id    = ?,
     group_id    = ?,
     member_id   = ?,
     year        = ?,
@@ -783,6 +700,11 @@ SET
     amount_due  = ?,
     amount_paid = ?,
     currency    = ?,
+    settlement_currency   = ?,
+    settlement_amount_due = ?,
+    exchange_rate         = ?,
+    rate_source           = ?,
+    rate_as_of            = ?,
     status      = ?,
     description = ?,
     proof_json  = ?,
@@ -790,32 +712,15 @@ SET
     updated_at  = ?,
     submitted_at = ?,
     verified_at  = ?,
-    rejected_at  = ?
+    rejected_at  = ?,
+    paid_at      = ?
 WHERE id = ?;
 `
 
-	// nullable timestamps
-	var submittedAt, verifiedAt, rejectedAt interface{}
-
-	if b.SubmittedAt != nil {
-		submittedAt = b.SubmittedAt.Format(time.RFC3339)
-	} else {
-		submittedAt = nil
-	}
-
-	if b.VerifiedAt != nil {
-		verifiedAt = b.VerifiedAt.Format(time.RFC3339)
-	} else {
-		verifiedAt = nil
-	}
-
-	if b.RejectedAt != nil {
-		rejectedAt = b.RejectedAt.Format(time.RFC3339)
-	} else {
-		rejectedAt = nil
-	}
-
-	res, err := s.db.ExecContext(ctx, q,
+	res, err := s.conn(ctx).ExecContext(ctx, q,
+		string(b.Kind),
+		b.ExpenseID,
+		nullableString(b.PayeeID),
 		b.GroupID,
 		b.MemberID,
 		b.Year,
@@ -823,14 +728,20 @@ WHERE id = ?;
 		b.AmountDue,
 		b.AmountPaid,
 		b.Currency,
+		b.SettlementCurrency,
+		b.SettlementAmountDue,
+		b.ExchangeRate,
+		nullableString(b.RateSource),
+		nullableTime(b.RateAsOf),
 		string(b.Status),
 		b.Description,
 		b.ProofJSON,
 		b.CreatedAt.Format(time.RFC3339),
 		b.UpdatedAt.Format(time.RFC3339),
-		submittedAt,
-		verifiedAt,
-		rejectedAt,
+		nullableTime(b.SubmittedAt),
+		nullableTime(b.VerifiedAt),
+		nullableTime(b.RejectedAt),
+		nullableTime(b.PaidAt),
 		b.ID,
 	)
 	if err != nil {
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+func TestMemberSummary(t *testing.T) {
+	const (
+		a = "100000000000000002"
+		b = "100000000000000003"
+	)
+	s, _, _ := openTestStore(t)
+	ctx := context.Background()
+
+	netflix := testGroup(1, "Netflix", 5)
+	netflix.Members = []group.GroupMember{
+		{MemberID: netflix.OwnerDiscordID, Status: group.MemberStatusActive},
+		{MemberID: a, Status: group.MemberStatusActive},
+		{MemberID: b, Status: group.MemberStatusActive},
+	}
+	spotify := testGroup(2, "Spotify", 10)
+	spotify.Currency = "USD"
+	spotify.Members = []group.GroupMember{
+		{MemberID: spotify.OwnerDiscordID, Status: group.MemberStatusActive},
+		{MemberID: a, Status: group.MemberStatusActive},
+	}
+	other := testGroup(3, "YouTube", 1) // a isn't in this one
+	for _, g := range []group.Group{netflix, spotify, other} {
+		if err := s.SaveGroup(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now().UTC()
+	day := func(month, d int) time.Time { return time.Date(2024, time.Month(month), d, 0, 0, 0, 0, time.UTC) }
+	bills := []bill.Bill{
+		{ID: 1, GroupID: netflix.ID, MemberID: a, Currency: "THB", AmountDue: 100, AmountPaid: 30, Status: bill.BillStatusPartiallyPaid, CreatedAt: day(1, 1)}, // past due
+		{ID: 2, GroupID: netflix.ID, MemberID: a, Currency: "THB", AmountDue: 50, Status: bill.BillStatusSubmitted, CreatedAt: now},                            // not due yet
+		{ID: 3, GroupID: netflix.ID, MemberID: a, Currency: "THB", AmountDue: 100, AmountPaid: 100, Status: bill.BillStatusVerified, CreatedAt: day(2, 1)},
+		{ID: 4, GroupID: netflix.ID, MemberID: b, Currency: "THB", AmountDue: 100, Status: bill.BillStatusPending, CreatedAt: day(1, 1)},
+		{ID: 5, GroupID: spotify.ID, MemberID: a, Currency: "USD", AmountDue: 10, AmountPaid: 10, Status: bill.BillStatusVerified, CreatedAt: day(1, 1)},
+		{ID: 6, GroupID: other.ID, MemberID: a, Currency: "THB", AmountDue: 80, Status: bill.BillStatusCanceled, CreatedAt: day(1, 1)},
+	}
+	for _, bl := range bills {
+		bl.Kind = bill.BillKindRecurring
+		bl.Year, bl.Month = bl.CreatedAt.Year(), int(bl.CreatedAt.Month())
+		bl.UpdatedAt = bl.CreatedAt
+		if err := s.SaveBill(ctx, bl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paidAt := day(1, 3)
+	payments := []bill.Payment{
+		{ID: 1, BillID: 1, Amount: 30, Status: bill.PaymentStatusAccepted, PaidAt: &paidAt, CreatedAt: paidAt},
+		{ID: 2, BillID: 1, Amount: 70, Status: bill.PaymentStatusRejected, CreatedAt: day(1, 20)},
+	}
+	for _, p := range payments {
+		p.GroupID, p.MemberID, p.Currency, p.Source = netflix.ID, a, "THB", bill.PaymentSourceManual
+		if err := s.SavePayment(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.SaveLedgerEntry(ctx, group.LedgerEntry{
+		ID: 1, GroupID: spotify.ID, MemberID: a, Kind: group.LedgerCredit, Credit: 500, CreatedAt: day(1, 1),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := group.NewService(s).GetMemberSummary(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summary.Groups) != 2 {
+		t.Fatalf("groups = %+v, want Netflix and Spotify", summary.Groups)
+	}
+	n, sp := summary.Groups[0], summary.Groups[1]
+	if n.GroupID != netflix.ID || sp.GroupID != spotify.ID {
+		t.Fatalf("groups = %d, %d, want %d, %d", n.GroupID, sp.GroupID, netflix.ID, spotify.ID)
+	}
+
+	if n.OpenBills != 2 || n.Outstanding != 120 || n.Credit != 0 {
+		t.Errorf("Netflix open bills/outstanding/credit = %d/%v/%v, want 2/120/0", n.OpenBills, n.Outstanding, n.Credit)
+	}
+	if len(n.OverdueBills) != 1 || n.OverdueBills[0].ID != 1 {
+		t.Errorf("Netflix overdue bills = %+v, want only bill 1", n.OverdueBills)
+	}
+	if n.LastPayment == nil || n.LastPayment.ID != 1 {
+		t.Errorf("Netflix last payment = %+v, want the accepted payment 1", n.LastPayment)
+	}
+	if !n.NextDueDate.After(now) || n.NextDueDate.Day() != 5 {
+		t.Errorf("Netflix next due date = %s, want the next 5th after now", n.NextDueDate)
+	}
+
+	if sp.OpenBills != 0 || sp.Outstanding != 0 || sp.Credit != 500 || len(sp.OverdueBills) != 0 {
+		t.Errorf("Spotify = %+v, want nothing open and 5.00 credit", sp)
+	}
+	if sp.LastPayment != nil {
+		t.Errorf("Spotify last payment = %+v, want none", sp.LastPayment)
+	}
+
+	want := []group.CurrencyTotal{
+		{Currency: "THB", Outstanding: 120, Overdue: 70},
+		{Currency: "USD", Credit: 500},
+	}
+	if len(summary.Totals) != len(want) {
+		t.Fatalf("totals = %+v, want %+v", summary.Totals, want)
+	}
+	for i := range want {
+		if summary.Totals[i] != want[i] {
+			t.Errorf("totals[%d] = %+v, want %+v", i, summary.Totals[i], want[i])
+		}
+	}
+
+	if _, err := group.NewService(s).GetMemberSummary(ctx, ""); err != group.ErrNoUserID {
+		t.Errorf("empty member id: err = %v, want %v", err, group.ErrNoUserID)
+	}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// dbtx is what the store needs to run statements; both *sql.DB and *sql.Tx
+// provide it.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// RunInTx runs fn in one transaction: every store call made with the context
+// fn gets joins it, whichever service makes it. The transaction commits when
+// fn returns nil and rolls back otherwise. Called inside fn, it just joins the
+// transaction already open.
+func (s *SQLiteStore) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// conn is the transaction ctx runs in, if any, or the pool. The pool holds a
+// single connection, so nothing inside a transaction may bypass it.
+func (s *SQLiteStore) conn(ctx context.Context) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return s.db
+}
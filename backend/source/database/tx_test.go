@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+func TestRunInTx(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		fail    bool
+		nested  bool
+		entries int
+	}{
+		{name: "commits", entries: 2},
+		{name: "rolls back every write", fail: true},
+		{name: "nested calls join the transaction", nested: true, fail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, _ := openTestStore(t)
+			ctx := context.Background()
+
+			save := func(ctx context.Context, id int64) error {
+				return s.SaveLedgerEntry(ctx, group.LedgerEntry{
+					ID: id, GroupID: 1, MemberID: "a", Kind: group.LedgerCharge, Debit: 100, CreatedAt: time.Now(),
+				})
+			}
+
+			err := s.RunInTx(ctx, func(ctx context.Context) error {
+				if err := save(ctx, 1); err != nil {
+					return err
+				}
+				if tt.nested {
+					if err := s.RunInTx(ctx, func(ctx context.Context) error { return save(ctx, 2) }); err != nil {
+						return err
+					}
+				} else if err := save(ctx, 2); err != nil {
+					return err
+				}
+				if tt.fail {
+					return errFailed
+				}
+				return nil
+			})
+			if tt.fail != errors.Is(err, errFailed) || (!tt.fail && err != nil) {
+				t.Fatalf("RunInTx = %v", err)
+			}
+
+			entries, err := s.GetLedgerEntries(ctx, 1, "a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tt.entries {
+				t.Errorf("entries = %d, want %d", len(entries), tt.entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package expense
+
+import "errors"
+
+var (
+	ErrInvalidGroupID       = errors.New("invalid group_id")
+	ErrInvalidActorID       = errors.New("actor_id is required")
+	ErrInvalidAmount        = errors.New("amount must be > 0")
+	ErrInvalidDescription   = errors.New("description is required")
+	ErrNotMember            = errors.New("only active members of the group can add expenses")
+	ErrInvalidPayer         = errors.New("payer is not an active member of the group")
+	ErrInvalidParticipant   = errors.New("participant is not an active member of the group")
+	ErrDuplicateParticipant = errors.New("participant is listed twice")
+	ErrNoOtherParticipants  = errors.New("expense needs at least one participant besides the payer")
+)
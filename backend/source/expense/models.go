@@ -0,0 +1,30 @@
+package expense
+
+import (
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+)
+
+// Expense is a one-off cost paid by one member and split between some of the
+// group. Every participant other than the payer gets a bill for their share.
+type Expense struct {
+	ID           int64       `json:"id"`
+	GroupID      int64       `json:"group_id"`
+	PayerID      string      `json:"payer_id"`
+	Amount       float64     `json:"amount"`
+	Currency     string      `json:"currency"`
+	Description  string      `json:"description"`
+	Participants []string    `json:"participants"`
+	CreatedBy    string      `json:"created_by"`
+	CreatedAt    time.Time   `json:"created_at"`
+	Bills        []bill.Bill `json:"bills,omitempty"`
+}
+
+type CreateExpenseRequest struct {
+	ActorID      string   `json:"actor_id"` // member creating the expense
+	PayerID      string   `json:"payer_id"` // defaults to the actor
+	Amount       float64  `json:"amount"`
+	Description  string   `json:"description"`
+	Participants []string `json:"participants"` // defaults to every active member
+}
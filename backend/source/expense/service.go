@@ -0,0 +1,227 @@
+package expense
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+type Store interface {
+	NextExpenseID(ctx context.Context) (int64, error)
+	SaveExpense(ctx context.Context, e Expense) error
+	GetExpensesByGroupID(ctx context.Context, groupID int64) ([]Expense, error)
+	GetBillsByExpenseID(ctx context.Context, expenseID int64) ([]bill.Bill, error)
+}
+
+type Service struct {
+	store  Store
+	groups *group.Service
+	bills  *bill.Service
+	audit  *audit.Service
+}
+
+func NewService(store Store, groups *group.Service, bills *bill.Service) *Service {
+	return &Service{
+		store:  store,
+		groups: groups,
+		bills:  bills,
+	}
+}
+
+// SetAuditLog makes the service record every expense it creates.
+func (s *Service) SetAuditLog(a *audit.Service) {
+	s.audit = a
+}
+
+// CreateExpense records a one-off expense and issues a bill for the share of
+// every participant except the payer. When the owner paid, the shares are
+// charged to the members' ledgers like the monthly bills; shares owed to
+// another member stay off the ledgers and are settled through settle-up.
+func (s *Service) CreateExpense(ctx context.Context, req CreateExpenseRequest, groupID int64) (*Expense, error) {
+	if groupID <= 0 {
+		return nil, ErrInvalidGroupID
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	g, err := s.groups.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	active := activeMembers(g)
+	if !active[req.ActorID] {
+		return nil, ErrNotMember
+	}
+
+	payerID := req.PayerID
+	if payerID == "" {
+		payerID = req.ActorID
+	}
+	if !active[payerID] {
+		return nil, ErrInvalidPayer
+	}
+
+	participants, err := participantsOf(g, req.Participants, active)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := splitEvenly(req.Amount, len(participants))
+
+	billed := 0
+	for _, p := range participants {
+		if p != payerID {
+			billed++
+		}
+	}
+	if billed == 0 {
+		return nil, ErrNoOtherParticipants
+	}
+
+	id, err := s.store.NextExpenseID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	e := Expense{
+		ID:           id,
+		GroupID:      g.ID,
+		PayerID:      payerID,
+		Amount:       req.Amount,
+		Currency:     g.Currency,
+		Description:  req.Description,
+		Participants: participants,
+		CreatedBy:    req.ActorID,
+		CreatedAt:    now,
+	}
+
+	if err := s.store.SaveExpense(ctx, e); err != nil {
+		return nil, err
+	}
+
+	for i, memberID := range participants {
+		if memberID == payerID {
+			continue
+		}
+
+		expenseID := e.ID
+		b, err := s.bills.CreateBill(ctx, bill.CreateBillRequest{
+			GroupID:     g.ID,
+			MemberID:    memberID,
+			Year:        now.Year(),
+			Month:       int(now.Month()),
+			AmountDue:   shares[i],
+			Currency:    g.Currency,
+			Description: e.Description,
+			Kind:        bill.BillKindExpense,
+			ExpenseID:   &expenseID,
+			PayeeID:     payerID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.groups.ChargeBill(ctx, b, req.ActorID); err != nil {
+			return nil, err
+		}
+
+		e.Bills = append(e.Bills, *b)
+	}
+
+	recorded := e
+	recorded.Bills = nil
+	if err := s.audit.Record(ctx, audit.Entry{
+		GroupID:    g.ID,
+		ActorID:    req.ActorID,
+		Action:     audit.ActionExpenseCreate,
+		EntityType: audit.EntityExpense,
+		EntityID:   strconv.FormatInt(e.ID, 10),
+		After:      recorded,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+func (s *Service) GetExpensesByGroup(ctx context.Context, groupID int64) ([]Expense, error) {
+	if groupID <= 0 {
+		return nil, ErrInvalidGroupID
+	}
+
+	expenses, err := s.store.GetExpensesByGroupID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range expenses {
+		bills, err := s.store.GetBillsByExpenseID(ctx, expenses[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		expenses[i].Bills = bills
+	}
+
+	return expenses, nil
+}
+
+func activeMembers(g *group.Group) map[string]bool {
+	active := make(map[string]bool, len(g.Members))
+	for _, m := range g.Members {
+		if m.Status == group.MemberStatusActive {
+			active[m.MemberID] = true
+		}
+	}
+	return active
+}
+
+// participantsOf validates the requested participants, or picks every active
+// member when none were given.
+func participantsOf(g *group.Group, requested []string, active map[string]bool) ([]string, error) {
+	if len(requested) == 0 {
+		var all []string
+		for _, m := range g.Members {
+			if active[m.MemberID] {
+				all = append(all, m.MemberID)
+			}
+		}
+		return all, nil
+	}
+
+	seen := make(map[string]bool, len(requested))
+	for _, id := range requested {
+		if !active[id] {
+			return nil, ErrInvalidParticipant
+		}
+		if seen[id] {
+			return nil, ErrDuplicateParticipant
+		}
+		seen[id] = true
+	}
+	return requested, nil
+}
+
+// splitEvenly divides amount into n shares to the satang/cent; the first shares
+// absorb the remainder so they add up exactly.
+func splitEvenly(amount float64, n int) []float64 {
+	cents := int64(math.Round(amount * 100))
+	base, rest := cents/int64(n), cents%int64(n)
+
+	shares := make([]float64, n)
+	for i := range shares {
+		c := base
+		if int64(i) < rest {
+			c++
+		}
+		shares[i] = float64(c) / 100
+	}
+	return shares
+}
@@ -0,0 +1,78 @@
+package expense
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+func TestSplitEvenly(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount float64
+		n      int
+		want   []float64
+	}{
+		{name: "even", amount: 300, n: 3, want: []float64{100, 100, 100}},
+		{name: "a satang left over", amount: 100, n: 3, want: []float64{33.34, 33.33, 33.33}},
+		{name: "two left over", amount: 0.05, n: 3, want: []float64{0.02, 0.02, 0.01}},
+		{name: "fractional amount", amount: 10.01, n: 2, want: []float64{5.01, 5}},
+		{name: "float noise is rounded away", amount: 0.1 + 0.2, n: 2, want: []float64{0.15, 0.15}},
+		{name: "one share", amount: 99.99, n: 1, want: []float64{99.99}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares := splitEvenly(tt.amount, tt.n)
+			if !slices.Equal(shares, tt.want) {
+				t.Errorf("shares = %v, want %v", shares, tt.want)
+			}
+
+			var cents int64
+			for _, s := range shares {
+				cents += int64(math.Round(s * 100))
+			}
+			if cents != int64(math.Round(tt.amount*100)) {
+				t.Errorf("shares add up to %d satang, want %v", cents, tt.amount)
+			}
+		})
+	}
+}
+
+func TestParticipantsOf(t *testing.T) {
+	g := &group.Group{Members: []group.GroupMember{
+		{MemberID: "a", Status: group.MemberStatusActive},
+		{MemberID: "b", Status: group.MemberStatusLeft},
+		{MemberID: "c", Status: group.MemberStatusActive},
+		{MemberID: "d", Status: group.MemberStatusInvited},
+	}}
+	active := activeMembers(g)
+
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+		wantErr   error
+	}{
+		{name: "every active member by default", want: []string{"a", "c"}},
+		{name: "the ones asked for", requested: []string{"c"}, want: []string{"c"}},
+		{name: "a member who left", requested: []string{"a", "b"}, wantErr: ErrInvalidParticipant},
+		{name: "an invited member", requested: []string{"d"}, wantErr: ErrInvalidParticipant},
+		{name: "twice", requested: []string{"a", "a"}, wantErr: ErrDuplicateParticipant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := participantsOf(g, tt.requested, active)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("participants = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
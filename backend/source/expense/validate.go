@@ -0,0 +1,13 @@
+package expense
+
+import "github.com/NoNiiEa/subShare-Discord/source/validate"
+
+func (req CreateExpenseRequest) Validate() error {
+	var v validate.Validator
+	v.Snowflake("actor_id", req.ActorID)
+	v.OptionalSnowflake("payer_id", req.PayerID)
+	v.Check(req.Amount > 0, "amount", ErrInvalidAmount)
+	v.Check(req.Description != "", "description", ErrInvalidDescription)
+	v.Snowflakes("participants", req.Participants)
+	return v.Err()
+}
@@ -0,0 +1,74 @@
+package group
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ReceivingMethods are the methods a slip can be matched against.
+var ReceivingMethods = []PaymentMethod{BankAccount, PromptPay, PromptPayNatID, PromptPayEWallet}
+
+// AccountDigits strips separators from an account number. PromptPay phone
+// numbers are brought to the local 0XXXXXXXXX form.
+func AccountDigits(method PaymentMethod, account string) string {
+	var b strings.Builder
+	for _, r := range account {
+		if unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if method == PromptPay && strings.HasPrefix(digits, "66") && len(digits) == 11 {
+		digits = "0" + digits[2:]
+	}
+	return digits
+}
+
+func validAccountNumber(method PaymentMethod, account string) bool {
+	digits := AccountDigits(method, account)
+	switch method {
+	case BankAccount:
+		return len(digits) >= 10 && len(digits) <= 12
+	case PromptPay:
+		return len(digits) == 10 && digits[0] == '0'
+	case PromptPayNatID:
+		return len(digits) == 13
+	case PromptPayEWallet:
+		return len(digits) == 15
+	}
+	return false
+}
+
+func isReceivingMethod(m PaymentMethod) bool {
+	for _, allowed := range ReceivingMethods {
+		if m == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePaymentAccounts returns the accounts of a validated create/update
+// request together with the preferred one. A request carrying only the legacy
+// single Payment is treated as a list of one; when nothing is marked
+// preferred the first account is.
+func normalizePaymentAccounts(single PaymentAccount, list []PaymentAccount) ([]PaymentAccount, PaymentAccount) {
+	accounts := list
+	if len(accounts) == 0 {
+		accounts = []PaymentAccount{single}
+	}
+
+	out := make([]PaymentAccount, len(accounts))
+	copy(out, accounts)
+
+	preferred := 0
+	for i, a := range out {
+		if a.Preferred {
+			preferred = i
+			break
+		}
+	}
+	out[preferred].Preferred = true
+
+	return out, out[preferred]
+}
@@ -0,0 +1,96 @@
+package group
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+)
+
+func TestValidatePaymentAccounts(t *testing.T) {
+	phone := PaymentAccount{Method: PromptPay, Account: "081-234-5678"}
+	bank := PaymentAccount{Method: BankAccount, Account: "123-4-56789-0", BankCode: "004"}
+
+	tests := []struct {
+		name     string
+		single   PaymentAccount
+		list     []PaymentAccount
+		wantErrs map[string]error
+	}{
+		{name: "legacy single account", single: phone},
+		{name: "a list with one preferred", list: []PaymentAccount{phone, {Method: BankAccount, Account: "1234567890", Preferred: true}}},
+		{name: "nothing to pay into", wantErrs: map[string]error{"payment_accounts": ErrNoPaymentAccount}},
+		{name: "legacy account is named as such", single: PaymentAccount{Method: PromptPay, Account: "12345"}, wantErrs: map[string]error{"payment.account": ErrInvalidPaymentAccount}},
+		{
+			name: "every bad entry is named",
+			list: []PaymentAccount{phone, {Method: Cash, Account: "x"}, {Method: PromptPayNatID, Account: "123"}},
+			wantErrs: map[string]error{
+				"payment_accounts[1].method":  ErrInvalidPaymentMethod,
+				"payment_accounts[2].account": ErrInvalidPaymentAccount,
+			},
+		},
+		{
+			name:     "the same number written differently",
+			list:     []PaymentAccount{phone, {Method: PromptPay, Account: "+66812345678"}},
+			wantErrs: map[string]error{"payment_accounts[1].account": ErrDuplicatePaymentAccount},
+		},
+		{
+			name:     "two preferred",
+			list:     []PaymentAccount{{Method: PromptPay, Account: "0812345678", Preferred: true}, {Method: BankAccount, Account: bank.Account, Preferred: true}},
+			wantErrs: map[string]error{"payment_accounts[1].preferred": ErrMultiplePreferred},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v validate.Validator
+			validatePaymentAccounts(&v, tt.single, tt.list)
+
+			var errs validate.Errors
+			errors.As(v.Err(), &errs)
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("errors = %v, want %d", errs, len(tt.wantErrs))
+			}
+			for _, fe := range errs {
+				if !errors.Is(fe, tt.wantErrs[fe.Field]) {
+					t.Errorf("%s: %v, want %v", fe.Field, fe.Err, tt.wantErrs[fe.Field])
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizePaymentAccounts(t *testing.T) {
+	phone := PaymentAccount{Method: PromptPay, Account: "0812345678"}
+	bank := PaymentAccount{Method: BankAccount, Account: "1234567890"}
+
+	tests := []struct {
+		name          string
+		single        PaymentAccount
+		list          []PaymentAccount
+		wantPreferred PaymentMethod
+		wantAccounts  int
+	}{
+		{name: "legacy single account", single: phone, wantPreferred: PromptPay, wantAccounts: 1},
+		{name: "the first when none is preferred", list: []PaymentAccount{bank, phone}, wantPreferred: BankAccount, wantAccounts: 2},
+		{name: "the one marked", list: []PaymentAccount{bank, {Method: PromptPay, Account: "0812345678", Preferred: true}}, wantPreferred: PromptPay, wantAccounts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := slices.Clone(tt.list)
+			accounts, preferred := normalizePaymentAccounts(tt.single, tt.list)
+			if preferred.Method != tt.wantPreferred || !preferred.Preferred {
+				t.Errorf("preferred = %+v, want %s", preferred, tt.wantPreferred)
+			}
+			marked := slices.IndexFunc(accounts, func(a PaymentAccount) bool { return a.Preferred })
+			if len(accounts) != tt.wantAccounts || accounts[marked] != preferred {
+				t.Errorf("accounts = %+v", accounts)
+			}
+			if !slices.Equal(tt.list, before) {
+				t.Error("the request's accounts were changed")
+			}
+		})
+	}
+}
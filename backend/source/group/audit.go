@@ -0,0 +1,116 @@
+package group
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+)
+
+// SetAuditLog makes the service record every change it makes.
+func (s *Service) SetAuditLog(a *audit.Service) {
+	s.audit = a
+}
+
+// AuditLogRequest reads a group's audit log; only the owner may.
+type AuditLogRequest struct {
+	GroupID int64
+	ActorID string
+	Action  audit.Action
+	Cursor  string
+	Limit   int
+}
+
+func (s *Service) GetAuditLog(ctx context.Context, req AuditLogRequest) (*audit.Page, error) {
+	if req.GroupID <= 0 {
+		return nil, ErrInvalidGroupID
+	}
+	if req.ActorID == "" {
+		return nil, ErrNoUserID
+	}
+
+	owner, err := s.auditOwner(ctx, req.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	if owner != req.ActorID {
+		return nil, ErrNotOwner
+	}
+
+	if s.audit == nil {
+		return &audit.Page{Events: []audit.Event{}}, nil
+	}
+
+	return s.audit.List(ctx, audit.ListRequest{
+		GroupID: req.GroupID,
+		Action:  req.Action,
+		Cursor:  req.Cursor,
+		Limit:   req.Limit,
+	})
+}
+
+// auditOwner is who may read a group's audit log: its owner, or once the group
+// is deleted, the owner recorded by the deletion, so the log outlives it.
+func (s *Service) auditOwner(ctx context.Context, groupID int64) (string, error) {
+	g, err := s.store.GetGroup(ctx, groupID)
+	if err == nil {
+		return g.OwnerDiscordID, nil
+	}
+	if s.audit == nil {
+		return "", err
+	}
+
+	page, listErr := s.audit.List(ctx, audit.ListRequest{GroupID: groupID, Action: audit.ActionGroupDelete, Limit: 1})
+	if listErr != nil {
+		return "", listErr
+	}
+	if len(page.Events) == 0 {
+		return "", err
+	}
+
+	var (
+		changes map[string]audit.FieldChange
+		owner   string
+	)
+	if err := json.Unmarshal(page.Events[0].Changes, &changes); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(changes["owner_discord_id"].Before, &owner); err != nil {
+		return "", err
+	}
+	return owner, nil
+}
+
+func groupEntityID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// memberEntityID identifies a member within a group.
+func memberEntityID(groupID int64, memberID string) string {
+	return strconv.FormatInt(groupID, 10) + "/" + memberID
+}
+
+func billEntityID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// billAuditState drops the slip proof, which is large and never changes in
+// the actions audited here.
+func billAuditState(b bill.Bill) bill.Bill {
+	b.ProofJSON = ""
+	return b
+}
+
+func (s *Service) recordBillChange(ctx context.Context, action audit.Action, actorID string, before, after bill.Bill) error {
+	return s.audit.Record(ctx, audit.Entry{
+		GroupID:    after.GroupID,
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: audit.EntityBill,
+		EntityID:   billEntityID(after.ID),
+		Before:     billAuditState(before),
+		After:      billAuditState(after),
+	})
+}
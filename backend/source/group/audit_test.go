@@ -0,0 +1,90 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+)
+
+// auditStore keeps audit events in memory, newest last.
+type auditStore struct {
+	audit.Store
+	events []audit.Event
+}
+
+func (s *auditStore) NextAuditEventID(ctx context.Context) (int64, error) {
+	return int64(len(s.events) + 1), nil
+}
+
+func (s *auditStore) SaveAuditEvent(ctx context.Context, e audit.Event) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *auditStore) ListAuditEvents(ctx context.Context, q audit.Query) ([]audit.Event, error) {
+	var out []audit.Event
+	for i := len(s.events) - 1; i >= 0 && len(out) <= q.Limit; i-- {
+		e := s.events[i]
+		if e.GroupID != q.GroupID || (q.Action != "" && e.Action != q.Action) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func TestGetAuditLog(t *testing.T) {
+	const stranger = "100000000000000009"
+
+	tests := []struct {
+		name    string
+		deleted bool
+		actor   func(g Group) string
+		wantErr error
+	}{
+		{name: "owner reads the log", actor: func(g Group) string { return g.OwnerDiscordID }},
+		{name: "a member is refused", actor: func(g Group) string { return g.Members[1].MemberID }, wantErr: ErrNotOwner},
+		{name: "the last owner reads a deleted group's log", deleted: true, actor: func(g Group) string { return g.OwnerDiscordID }},
+		{name: "others are refused once it is deleted", deleted: true, actor: func(Group) string { return stranger }, wantErr: ErrNotOwner},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &groupStore{g: testGroup()}
+			events := &auditStore{}
+			s := NewService(store)
+			s.SetAuditLog(audit.NewService(events))
+			ctx := context.Background()
+
+			if err := s.ResetPaymentForDueday(ctx, store.g.DueDay); err != nil {
+				t.Fatal(err)
+			}
+			if tt.deleted {
+				if err := s.DeleteGroup(ctx, store.g.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			page, err := s.GetAuditLog(ctx, AuditLogRequest{GroupID: store.g.ID, ActorID: tt.actor(store.g)})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && len(page.Events) != len(events.events) {
+				t.Errorf("got %d events, want %d", len(page.Events), len(events.events))
+			}
+		})
+	}
+}
+
+func TestGetAuditLogOfUnknownGroup(t *testing.T) {
+	store := &groupStore{g: testGroup(), deleted: true}
+	s := NewService(store)
+	s.SetAuditLog(audit.NewService(&auditStore{}))
+
+	_, err := s.GetAuditLog(context.Background(), AuditLogRequest{GroupID: 1, ActorID: store.g.OwnerDiscordID})
+	if !errors.Is(err, errGroupGone) {
+		t.Fatalf("err = %v, want the store's error", err)
+	}
+}
@@ -0,0 +1,439 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
+
+// CancelBill voids a bill: the charge is taken off the member's ledger, so
+// anything already paid on it turns into credit.
+func (s *Service) CancelBill(ctx context.Context, req BillActionRequest, billID int64) (*bill.Bill, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	b, g, err := s.loadOwnedBill(ctx, req.OwnerID, billID)
+	if err != nil {
+		return nil, err
+	}
+	before := *b
+
+	if err := b.CheckTransition(bill.BillStatusCanceled); err != nil {
+		return nil, err
+	}
+
+	charged, err := s.billCharged(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := bill.Transition(ctx, s.store, b, bill.BillStatusCanceled, req.OwnerID, req.Reason, now); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.store.UpdateBill(ctx, *b)
+	if err != nil {
+		return nil, err
+	}
+
+	if charged > 0 {
+		if err := s.adjustMemberBalance(ctx, g, LedgerEntry{
+			GroupID:   g.ID,
+			MemberID:  b.MemberID,
+			Kind:      LedgerAdjustment,
+			Credit:    charged,
+			BillID:    &b.ID,
+			ActorID:   req.OwnerID,
+			Note:      noteOrDefault(req.Reason, "bill canceled"),
+			CreatedAt: now,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.recordBillChange(ctx, audit.ActionBillCancel, req.OwnerID, before, *updated); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// WaiveBill forgives what is left to pay on a bill; payments already made on
+// it stay where they are.
+func (s *Service) WaiveBill(ctx context.Context, req BillActionRequest, billID int64) (*bill.Bill, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	b, g, err := s.loadOwnedBill(ctx, req.OwnerID, billID)
+	if err != nil {
+		return nil, err
+	}
+	before := *b
+
+	if err := b.CheckTransition(bill.BillStatusWaived); err != nil {
+		return nil, err
+	}
+
+	charged, err := s.billCharged(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := bill.Transition(ctx, s.store, b, bill.BillStatusWaived, req.OwnerID, req.Reason, now); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.store.UpdateBill(ctx, *b)
+	if err != nil {
+		return nil, err
+	}
+
+	if outstanding := charged - currency.ToMinor(b.AmountPaid); outstanding > 0 {
+		if err := s.adjustMemberBalance(ctx, g, LedgerEntry{
+			GroupID:   g.ID,
+			MemberID:  b.MemberID,
+			Kind:      LedgerWriteOff,
+			Credit:    outstanding,
+			BillID:    &b.ID,
+			ActorID:   req.OwnerID,
+			Note:      noteOrDefault(req.Reason, "bill waived"),
+			CreatedAt: now,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.recordBillChange(ctx, audit.ActionBillWaive, req.OwnerID, before, *updated); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// AmendBill changes the amount or description of an open bill. A new amount is
+// posted to the ledger as an adjustment and may settle the bill right away
+// when the payments already cover it.
+func (s *Service) AmendBill(ctx context.Context, req AmendBillRequest, billID int64) (*bill.Bill, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.AmountDue == nil && req.Description == nil {
+		return nil, ErrNoBillChanges
+	}
+
+	b, g, err := s.loadOwnedBill(ctx, req.OwnerID, billID)
+	if err != nil {
+		return nil, err
+	}
+	before := *b
+
+	if b.IsFinal() {
+		return nil, ErrBillClosed
+	}
+
+	now := time.Now().UTC()
+	reason := req.Reason
+
+	if req.Description != nil {
+		b.Description = *req.Description
+	}
+
+	var delta currency.Minor
+	if req.AmountDue != nil && *req.AmountDue != b.AmountDue {
+		charged, err := s.billCharged(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		if charged > 0 {
+			delta = currency.ToMinor(*req.AmountDue) - charged
+		}
+
+		reason = fmt.Sprintf("amount_due %.2f -> %.2f", b.AmountDue, *req.AmountDue)
+		if req.Reason != "" {
+			reason += ": " + req.Reason
+		}
+
+		b.AmountDue = *req.AmountDue
+		if b.ExchangeRate > 0 {
+			b.SettlementAmountDue = math.Round(b.AmountDue*b.ExchangeRate*100) / 100
+		}
+	}
+	b.UpdatedAt = now
+
+	if err := bill.RecordEvent(ctx, s.store, b, req.OwnerID, noteOrDefault(reason, "bill amended"), now); err != nil {
+		return nil, err
+	}
+
+	payments, err := s.store.GetPaymentsByBillID(ctx, b.ID)
+	if err != nil {
+		return nil, err
+	}
+	if bill.AcceptedTotal(payments) > 0 {
+		if err := bill.Settle(ctx, s.store, b, payments, req.OwnerID, "amount amended", now); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.store.UpdateBill(ctx, *b)
+	if err != nil {
+		return nil, err
+	}
+
+	if delta != 0 {
+		entry := LedgerEntry{
+			GroupID:   g.ID,
+			MemberID:  b.MemberID,
+			Kind:      LedgerAdjustment,
+			BillID:    &b.ID,
+			ActorID:   req.OwnerID,
+			Note:      reason,
+			CreatedAt: now,
+		}
+		if delta > 0 {
+			entry.Debit = delta
+		} else {
+			entry.Credit = -delta
+		}
+		if err := s.adjustMemberBalance(ctx, g, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.recordBillChange(ctx, audit.ActionBillAmend, req.OwnerID, before, *updated); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (s *Service) loadOwnedBill(ctx context.Context, ownerID string, billID int64) (*bill.Bill, *Group, error) {
+	if billID <= 0 {
+		return nil, nil, bill.ErrInvalidBillID
+	}
+
+	b, err := s.store.GetBillByID(ctx, billID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g, err := s.GetGroup(ctx, b.GroupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if g.OwnerDiscordID != ownerID {
+		return nil, nil, ErrNotOwner
+	}
+
+	return b, g, nil
+}
+
+// billCharged is what the ledger currently charges the member for a bill:
+// its charge plus any adjustments. Bills issued to members who had left were
+// never charged.
+func (s *Service) billCharged(ctx context.Context, b *bill.Bill) (currency.Minor, error) {
+	entries, err := s.store.GetLedgerEntries(ctx, b.GroupID, b.MemberID)
+	if err != nil {
+		return 0, err
+	}
+
+	var charged currency.Minor
+	for _, e := range entries {
+		if e.BillID == nil || *e.BillID != b.ID {
+			continue
+		}
+		if e.Kind == LedgerCharge || e.Kind == LedgerAdjustment {
+			charged += e.Debit - e.Credit
+		}
+	}
+	return charged, nil
+}
+
+// adjustMemberBalance posts a correction to the ledger and brings the member's
+// payment status in line with the new balance.
+func (s *Service) adjustMemberBalance(ctx context.Context, g *Group, entry LedgerEntry) error {
+	if err := s.postLedger(ctx, entry); err != nil {
+		return err
+	}
+
+	updated, err := s.updateMembers(ctx, g.ID, func(g *Group) error {
+		if m := findMember(g, entry.MemberID); m != nil && m.Status != MemberStatusLeft {
+			if m.Dept == 0 {
+				m.Payment = PaymentStatusPaid
+			} else {
+				m.Payment = PaymentStatusNotPaid
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*g = *updated
+
+	return nil
+}
+
+func noteOrDefault(note, fallback string) string {
+	if note != "" {
+		return note
+	}
+	return fallback
+}
+
+// OwedToOwner reports whether a bill is money owed to the group owner. Only
+// those bills are on the members' ledgers; a share of an expense another
+// member paid is settled between the two of them.
+func OwedToOwner(g *Group, b *bill.Bill) bool {
+	return b.PayeeID == "" || b.PayeeID == g.OwnerDiscordID
+}
+
+// ChargeBill posts the charge of a bill issued outside the monthly cycle, such
+// as a member's share of an expense, to the member's ledger. Bills owed to
+// someone other than the owner are left off.
+func (s *Service) ChargeBill(ctx context.Context, b *bill.Bill, actorID string) error {
+	g, err := s.GetGroup(ctx, b.GroupID)
+	if err != nil {
+		return err
+	}
+
+	if !OwedToOwner(g, b) {
+		return nil
+	}
+
+	return s.adjustMemberBalance(ctx, g, LedgerEntry{
+		GroupID:   g.ID,
+		MemberID:  b.MemberID,
+		Kind:      LedgerCharge,
+		Debit:     currency.ToMinor(b.AmountDue),
+		BillID:    &b.ID,
+		ActorID:   actorID,
+		Note:      b.Description,
+		CreatedAt: b.CreatedAt,
+	})
+}
+
+// CreditBillPayment posts a payment accepted on a bill, such as a verified
+// slip, to the member's ledger.
+func (s *Service) CreditBillPayment(ctx context.Context, b *bill.Bill, amount float64, actorID, note string, at time.Time) error {
+	g, err := s.GetGroup(ctx, b.GroupID)
+	if err != nil {
+		return err
+	}
+
+	if !OwedToOwner(g, b) {
+		return nil
+	}
+
+	if findMember(g, b.MemberID) == nil {
+		return ErrMemberNotFound
+	}
+
+	_, err = s.recordMemberPayment(ctx, g, LedgerEntry{
+		GroupID:   g.ID,
+		MemberID:  b.MemberID,
+		Kind:      LedgerPayment,
+		Credit:    currency.ToMinor(amount),
+		BillID:    &b.ID,
+		ActorID:   actorID,
+		Note:      note,
+		CreatedAt: at,
+	})
+	return err
+}
+
+// SettleBill pays off what is left on a bill as part of a guild settlement
+// (see the settlement package). Rejected bills are reopened first.
+func (s *Service) SettleBill(ctx context.Context, billID int64, reference, actorID string) (*bill.Bill, error) {
+	b, err := s.store.GetBillByID(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.IsFinal() {
+		return nil, ErrBillClosed
+	}
+
+	g, err := s.GetGroup(ctx, b.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if b.Status == bill.BillStatusRejected {
+		if err := bill.Transition(ctx, s.store, b, bill.BillStatusPending, actorID, "reopened for settlement", now); err != nil {
+			return nil, err
+		}
+	}
+
+	amount := b.Outstanding()
+	if amount <= 0 {
+		// nothing to pay, but a reopened bill has changed
+		return s.store.UpdateBill(ctx, *b)
+	}
+
+	paymentID, err := s.store.NextPaymentID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := bill.Payment{
+		ID:         paymentID,
+		BillID:     b.ID,
+		GroupID:    b.GroupID,
+		MemberID:   b.MemberID,
+		Amount:     amount,
+		Currency:   b.Currency,
+		Source:     bill.PaymentSourceSettlement,
+		Status:     bill.PaymentStatusAccepted,
+		Reference:  reference,
+		RecordedBy: actorID,
+		PaidAt:     &now,
+		CreatedAt:  now,
+	}
+	if err := s.store.SavePayment(ctx, p); err != nil {
+		return nil, err
+	}
+
+	payments, err := s.store.GetPaymentsByBillID(ctx, b.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bill.Settle(ctx, s.store, b, payments, actorID, reference, now); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.store.UpdateBill(ctx, *b)
+	if err != nil {
+		return nil, err
+	}
+
+	if !OwedToOwner(g, b) {
+		return updated, nil
+	}
+
+	if _, err := s.recordMemberPayment(ctx, g, LedgerEntry{
+		GroupID:   g.ID,
+		MemberID:  b.MemberID,
+		Kind:      LedgerPayment,
+		Credit:    currency.ToMinor(amount),
+		BillID:    &b.ID,
+		ActorID:   actorID,
+		Note:      reference,
+		CreatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
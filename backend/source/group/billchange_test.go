@@ -0,0 +1,171 @@
+package group
+
+import (
+	"context"
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
+
+// issuedBill runs the monthly cycle on testGroup and returns the member's
+// bill of 200, with paid already recorded on it.
+func issuedBill(t *testing.T, paid float64) (*Service, *groupStore, *bill.Bill) {
+	t.Helper()
+
+	store := &groupStore{g: testGroup()}
+	s := NewService(store)
+	ctx := context.Background()
+	if err := s.ResetPaymentForDueday(ctx, store.g.DueDay); err != nil {
+		t.Fatal(err)
+	}
+
+	var b *bill.Bill
+	for i := range store.bills {
+		if store.bills[i].MemberID == "100000000000000002" {
+			b = &store.bills[i]
+		}
+	}
+	if paid > 0 {
+		req := RecordPaymentRequest{OwnerID: store.g.OwnerDiscordID, Method: Cash, Amount: paid}
+		if _, _, err := s.RecordManualPayment(ctx, req, b.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, _ = store.GetBillByID(ctx, b.ID)
+	return s, store, b
+}
+
+func TestBillChangeLedger(t *testing.T) {
+	amount := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name       string
+		paid       float64
+		change     func(s *Service, owner string, billID int64) (*bill.Bill, error)
+		kind       LedgerKind
+		debit      currency.Minor
+		credit     currency.Minor
+		wantStatus bill.BillStatus
+		wantDept   currency.Minor
+		wantCredit currency.Minor
+	}{
+		{
+			name: "cancel an unpaid bill",
+			change: func(s *Service, owner string, id int64) (*bill.Bill, error) {
+				return s.CancelBill(context.Background(), BillActionRequest{OwnerID: owner}, id)
+			},
+			kind: LedgerAdjustment, credit: currency.ToMinor(200), wantStatus: bill.BillStatusCanceled,
+		},
+		{
+			name: "cancel a part-paid bill turns the payment into credit",
+			paid: 50,
+			change: func(s *Service, owner string, id int64) (*bill.Bill, error) {
+				return s.CancelBill(context.Background(), BillActionRequest{OwnerID: owner}, id)
+			},
+			kind: LedgerAdjustment, credit: currency.ToMinor(200), wantStatus: bill.BillStatusCanceled, wantCredit: currency.ToMinor(50),
+		},
+		{
+			name: "waive writes off only what is left",
+			paid: 50.25,
+			change: func(s *Service, owner string, id int64) (*bill.Bill, error) {
+				return s.WaiveBill(context.Background(), BillActionRequest{OwnerID: owner}, id)
+			},
+			kind: LedgerWriteOff, credit: currency.ToMinor(149.75), wantStatus: bill.BillStatusWaived,
+		},
+		{
+			name: "amend up charges the difference",
+			change: func(s *Service, owner string, id int64) (*bill.Bill, error) {
+				return s.AmendBill(context.Background(), AmendBillRequest{OwnerID: owner, AmountDue: amount(250.5)}, id)
+			},
+			kind: LedgerAdjustment, debit: currency.ToMinor(50.5), wantStatus: bill.BillStatusPending, wantDept: currency.ToMinor(250.5),
+		},
+		{
+			name: "amend down credits the difference",
+			paid: 50,
+			change: func(s *Service, owner string, id int64) (*bill.Bill, error) {
+				return s.AmendBill(context.Background(), AmendBillRequest{OwnerID: owner, AmountDue: amount(120)}, id)
+			},
+			kind: LedgerAdjustment, credit: currency.ToMinor(80), wantStatus: bill.BillStatusPartiallyPaid, wantDept: currency.ToMinor(70),
+		},
+		{
+			name: "amend below what was paid settles the bill",
+			paid: 50,
+			change: func(s *Service, owner string, id int64) (*bill.Bill, error) {
+				return s.AmendBill(context.Background(), AmendBillRequest{OwnerID: owner, AmountDue: amount(40)}, id)
+			},
+			kind: LedgerAdjustment, credit: currency.ToMinor(160), wantStatus: bill.BillStatusVerified, wantCredit: currency.ToMinor(10),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store, b := issuedBill(t, tt.paid)
+
+			updated, err := tt.change(s, store.g.OwnerDiscordID, b.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", updated.Status, tt.wantStatus)
+			}
+
+			last := store.ledger[len(store.ledger)-1]
+			if last.Kind != tt.kind || last.Debit != tt.debit || last.Credit != tt.credit || last.BillID == nil || *last.BillID != b.ID {
+				t.Errorf("ledger entry = %s %v/%v, want %s %v/%v", last.Kind, last.Debit, last.Credit, tt.kind, tt.debit, tt.credit)
+			}
+
+			g, _ := s.GetGroup(context.Background(), 1)
+			m := findMember(g, b.MemberID)
+			if m.Dept != tt.wantDept || m.Credit != tt.wantCredit {
+				t.Errorf("dept %v, credit %v; want %v, %v", m.Dept, m.Credit, tt.wantDept, tt.wantCredit)
+			}
+		})
+	}
+}
+
+func TestSettleBill(t *testing.T) {
+	tests := []struct {
+		name       string
+		paid       float64 // recorded before the settlement
+		rejected   bool
+		amountDue  float64 // amended without a ledger adjustment
+		wantStatus bill.BillStatus
+		wantCredit currency.Minor
+	}{
+		{name: "pays what is left", paid: 50, wantStatus: bill.BillStatusVerified, wantCredit: currency.ToMinor(150)},
+		{name: "reopens a rejected bill", rejected: true, wantStatus: bill.BillStatusVerified, wantCredit: currency.ToMinor(200)},
+		{name: "a reopened bill with nothing left is still saved", paid: 50, rejected: true, amountDue: 50, wantStatus: bill.BillStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store, b := issuedBill(t, tt.paid)
+			if tt.rejected {
+				b.Status = bill.BillStatusRejected
+			}
+			if tt.amountDue > 0 {
+				b.AmountDue = tt.amountDue
+			}
+			store.UpdateBill(context.Background(), *b)
+			ledger := len(store.ledger)
+
+			if _, err := s.SettleBill(context.Background(), b.ID, "settlement:1", store.g.OwnerDiscordID); err != nil {
+				t.Fatal(err)
+			}
+
+			stored, _ := store.GetBillByID(context.Background(), b.ID)
+			if stored.Status != tt.wantStatus {
+				t.Errorf("stored status = %s, want %s", stored.Status, tt.wantStatus)
+			}
+			var credited currency.Minor
+			for _, e := range store.ledger[ledger:] {
+				credited += e.Credit
+			}
+			if credited != tt.wantCredit {
+				t.Errorf("credited %v, want %v", credited, tt.wantCredit)
+			}
+		})
+	}
+}
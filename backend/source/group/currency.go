@@ -0,0 +1,35 @@
+package group
+
+import (
+	"context"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
+
+func (s *Service) SetRateProvider(p currency.RateProvider) {
+	s.rates = p
+}
+
+// groupCurrency normalizes the currency of a create/update request. An empty
+// code falls back to fallback (THB for new groups); anything other than the
+// settlement currency needs a rate, or slips could never be checked.
+func (s *Service) groupCurrency(ctx context.Context, code, fallback string) (string, error) {
+	if code == "" {
+		code = fallback
+	}
+	if code == "" {
+		code = currency.Settlement
+	}
+
+	cur, err := currency.Normalize(code)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := currency.Lookup(ctx, s.rates, cur, currency.Settlement, time.Now().UTC()); err != nil {
+		return "", err
+	}
+
+	return cur, nil
+}
@@ -13,14 +13,14 @@ var (
 	ErrInvalidMemberID   = errors.New("invalid member id")
 	ErrNoMembersProvided = errors.New("at least one member is required")
 	ErrInvalidGroupID    = errors.New("invalid group_id")
-	ErrMemberNotFound   = errors.New("member is not found in group")
+	ErrMemberNotFound    = errors.New("member is not found in group")
 	ErrNotActiveMember   = errors.New("member is not active in group")
 	ErrAlreadyPaid       = errors.New("member is already paid")
 )
 
 var (
-	ErrAleadyInvited = errors.New("User is aleady invited")
-	ErrAleadyMembered = errors.New("User is aleady Member")
+	ErrAleadyInvited     = errors.New("User is aleady invited")
+	ErrAleadyMembered    = errors.New("User is aleady Member")
 	ErrInvitedPermission = errors.New("User have no permission to invite")
 )
 
@@ -29,4 +29,37 @@ var (
 )
 
 var ErrNoUserID = errors.New("userID is required")
-var ErrNotValidSlip = errors.New("invalid slip")
\ No newline at end of file
+var ErrNotValidSlip = errors.New("invalid slip")
+
+var (
+	ErrNotOwner            = errors.New("only the group owner can do this")
+	ErrInvalidRefundAmount = errors.New("refund amount must be > 0")
+	ErrInsufficientCredit  = errors.New("refund is larger than the member's credit")
+)
+
+var (
+	ErrInvalidPaymentMethod = errors.New("unsupported payment method")
+	ErrInvalidPaymentAmount = errors.New("payment amount must be > 0")
+	ErrBillClosed           = errors.New("bill is already verified, canceled or waived")
+	ErrNoBillChanges        = errors.New("nothing to change on the bill")
+	ErrNotOwedToOwner       = errors.New("bill is owed to another member, pay them directly")
+)
+
+var (
+	ErrNoPaymentAccount        = errors.New("at least one payment account is required")
+	ErrInvalidPaymentAccount   = errors.New("invalid payment account number")
+	ErrDuplicatePaymentAccount = errors.New("payment account is listed twice")
+	ErrMultiplePreferred       = errors.New("only one payment account can be preferred")
+)
+var (
+	ErrInvalidMemberStatus = errors.New("status must be Active, Invited or Left")
+	ErrInvalidSort         = errors.New("sort must be id, name, due_day or created_at, optionally prefixed with -")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrInvalidLimit        = errors.New("limit must be between 1 and 100")
+)
+
+var (
+	ErrVersionMismatch = errors.New("group was changed in the meantime; fetch it and try again")
+	ErrInvalidPatch    = errors.New("patch must be a JSON object")
+	ErrCurrencyLocked  = errors.New("currency can't change once the group has bills or ledger entries")
+)
@@ -0,0 +1,116 @@
+package group
+
+import (
+	"context"
+	"sort"
+
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
+
+func (s *Service) postLedger(ctx context.Context, e LedgerEntry) error {
+	id, err := s.store.NextLedgerEntryID(ctx)
+	if err != nil {
+		return err
+	}
+	e.ID = id
+
+	return s.store.SaveLedgerEntry(ctx, e)
+}
+
+// applyBalances fills each member's Dept and Credit from the ledger.
+func (s *Service) applyBalances(ctx context.Context, g *Group) error {
+	balances, err := s.store.GetLedgerBalances(ctx, g.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range g.Members {
+		g.Members[i].Dept, g.Members[i].Credit = splitBalance(balances[g.Members[i].MemberID])
+	}
+
+	return nil
+}
+
+// splitBalance turns a ledger balance into what the member owes and what they
+// have in credit; at most one of the two is non-zero.
+func splitBalance(balance currency.Minor) (dept, credit currency.Minor) {
+	if balance >= 0 {
+		return balance, 0
+	}
+	return 0, -balance
+}
+
+func (s *Service) GetMemberLedger(ctx context.Context, groupID int64, memberID string) (*MemberLedger, error) {
+	if groupID <= 0 {
+		return nil, ErrInvalidGroupID
+	}
+
+	if memberID == "" {
+		return nil, ErrNoUserID
+	}
+
+	g, err := s.store.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if findMember(g, memberID) == nil {
+		return nil, ErrMemberNotFound
+	}
+
+	entries, err := s.store.GetLedgerEntries(ctx, groupID, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance currency.Minor
+	for i := range entries {
+		balance += entries[i].Debit - entries[i].Credit
+		entries[i].Balance = balance
+	}
+
+	dept, credit := splitBalance(balance)
+
+	return &MemberLedger{
+		GroupID:  groupID,
+		MemberID: memberID,
+		Balance:  balance,
+		Dept:     dept,
+		Credit:   credit,
+		Entries:  entries,
+	}, nil
+}
+
+// GetGroupLedger returns the ledger entries of every member of the group,
+// oldest first, each with the member's running balance.
+func (s *Service) GetGroupLedger(ctx context.Context, groupID int64) ([]LedgerEntry, error) {
+	if groupID <= 0 {
+		return nil, ErrInvalidGroupID
+	}
+
+	g, err := s.store.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	all := []LedgerEntry{}
+	for _, m := range g.Members {
+		entries, err := s.store.GetLedgerEntries(ctx, groupID, m.MemberID)
+		if err != nil {
+			return nil, err
+		}
+
+		var balance currency.Minor
+		for i := range entries {
+			balance += entries[i].Debit - entries[i].Credit
+			entries[i].Balance = balance
+		}
+		all = append(all, entries...)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].ID < all[j].ID
+	})
+
+	return all, nil
+}
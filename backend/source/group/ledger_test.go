@@ -0,0 +1,70 @@
+package group
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
+
+func TestLedgerKeepsFractions(t *testing.T) {
+	tests := []struct {
+		name     string
+		charges  []float64
+		payments []float64
+		want     currency.Minor
+	}{
+		{name: "cents paid in full", charges: []float64{4.99}, payments: []float64{4.99}},
+		{name: "half units paid in two parts", charges: []float64{2.50}, payments: []float64{1.25, 1.25}},
+		{name: "thirds of a bill", charges: []float64{3.33, 3.33, 3.34}, payments: []float64{10}},
+		{name: "a cent short", charges: []float64{10.01}, payments: []float64{10}, want: 1},
+		{name: "overpaid by cents", charges: []float64{0.40}, payments: []float64{0.45}, want: -5},
+	}
+
+	const member = "100000000000000002"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGroup()
+			g.Currency = "USD"
+			store := &groupStore{g: g}
+			s := NewService(store)
+			s.SetRateProvider(fixedRates(35))
+			ctx := context.Background()
+			now := time.Now().UTC()
+
+			for i, amount := range tt.charges {
+				b := bill.Bill{
+					ID:        int64(i + 1),
+					Kind:      bill.BillKindExpense,
+					GroupID:   g.ID,
+					MemberID:  member,
+					AmountDue: amount,
+					Currency:  g.Currency,
+					Status:    bill.BillStatusPending,
+					CreatedAt: now,
+				}
+				store.bills = append(store.bills, b)
+				if err := s.ChargeBill(ctx, &b, g.OwnerDiscordID); err != nil {
+					t.Fatalf("ChargeBill: %v", err)
+				}
+			}
+			for _, amount := range tt.payments {
+				if err := s.CreditBillPayment(ctx, &store.bills[0], amount, g.OwnerDiscordID, "", now); err != nil {
+					t.Fatalf("CreditBillPayment: %v", err)
+				}
+			}
+
+			balances, _ := store.GetLedgerBalances(ctx, g.ID)
+			if got := balances[member]; got != tt.want {
+				t.Errorf("balance = %v, want %v", got, tt.want)
+			}
+			m := findMember(&store.g, member)
+			if settled := tt.want <= 0; settled != (m.Payment == PaymentStatusPaid) {
+				t.Errorf("payment status = %q with balance %v", m.Payment, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package group
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type GroupSort string
+
+const (
+	SortByID        GroupSort = "id"
+	SortByName      GroupSort = "name"
+	SortByDueDay    GroupSort = "due_day"
+	SortByCreatedAt GroupSort = "created_at"
+
+	defaultGroupLimit = 20
+	maxGroupLimit     = 100
+)
+
+// ListGroupsRequest holds the query parameters of the group listing endpoints.
+// Sort is a GroupSort, prefixed with "-" for descending order. Status filters
+// on MemberID's membership status, or on any member's status when MemberID is
+// empty.
+type ListGroupsRequest struct {
+	GuildID  string
+	MemberID string
+	OwnerID  string
+	Status   MemberStatus
+	DueDay   int
+	Sort     string
+	Cursor   string
+	Limit    int
+}
+
+// GroupQuery is a validated ListGroupsRequest as handed to the store, which
+// returns up to Limit+1 groups so the service can tell if there is a next page.
+type GroupQuery struct {
+	GuildID  string
+	MemberID string
+	OwnerID  string
+	Status   MemberStatus
+	DueDay   int
+	SortBy   GroupSort
+	Desc     bool
+	After    *GroupCursor
+	Limit    int
+}
+
+// GroupCursor is the position after the last group of a page: its sort value
+// and ID. Clients only see it base64 encoded.
+type GroupCursor struct {
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+type GroupPage struct {
+	Groups     []Group `json:"groups"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+func (s *Service) ListGroups(ctx context.Context, req ListGroupsRequest) (*GroupPage, error) {
+	q, err := buildGroupQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.store.ListGroups(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &GroupPage{Groups: []Group{}}
+	if len(groups) > q.Limit {
+		groups = groups[:q.Limit]
+		last := groups[len(groups)-1]
+		page.NextCursor = encodeGroupCursor(GroupCursor{Value: sortValue(last, q.SortBy), ID: last.ID})
+	}
+
+	for i := range groups {
+		if err := s.applyBalances(ctx, &groups[i]); err != nil {
+			return nil, err
+		}
+	}
+	page.Groups = append(page.Groups, groups...)
+
+	return page, nil
+}
+
+func buildGroupQuery(req ListGroupsRequest) (GroupQuery, error) {
+	q := GroupQuery{
+		GuildID:  req.GuildID,
+		MemberID: req.MemberID,
+		OwnerID:  req.OwnerID,
+		Status:   req.Status,
+		DueDay:   req.DueDay,
+		SortBy:   SortByID,
+		Limit:    req.Limit,
+	}
+
+	if q.GuildID == "" && q.MemberID == "" {
+		return q, ErrInvalidGuildID
+	}
+
+	switch q.Status {
+	case "", MemberStatusActive, MemberStatusInvited, MemberStatusLeft:
+	default:
+		return q, ErrInvalidMemberStatus
+	}
+
+	if q.DueDay != 0 && (q.DueDay < 1 || q.DueDay > 31) {
+		return q, ErrInvalidDueDay
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultGroupLimit
+	}
+	if q.Limit < 0 || q.Limit > maxGroupLimit {
+		return q, ErrInvalidLimit
+	}
+
+	if req.Sort != "" {
+		sortBy := strings.TrimPrefix(req.Sort, "-")
+		q.Desc = sortBy != req.Sort
+		switch GroupSort(sortBy) {
+		case SortByID, SortByName, SortByDueDay, SortByCreatedAt:
+			q.SortBy = GroupSort(sortBy)
+		default:
+			return q, ErrInvalidSort
+		}
+	}
+
+	if req.Cursor != "" {
+		c, err := decodeGroupCursor(req.Cursor)
+		if err != nil {
+			return q, ErrInvalidCursor
+		}
+		q.After = &c
+	}
+
+	return q, nil
+}
+
+// sortValue renders the sort column the way the store compares it.
+func sortValue(g Group, sortBy GroupSort) string {
+	switch sortBy {
+	case SortByName:
+		return g.Name
+	case SortByDueDay:
+		return strconv.Itoa(g.DueDay)
+	case SortByCreatedAt:
+		return g.CreateAt.UTC().Format(time.RFC3339)
+	default:
+		return strconv.FormatInt(g.ID, 10)
+	}
+}
+
+func encodeGroupCursor(c GroupCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeGroupCursor(s string) (GroupCursor, error) {
+	var c GroupCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
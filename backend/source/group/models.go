@@ -1,7 +1,11 @@
 package group
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
 )
 
 type MemberStatus string
@@ -9,63 +13,94 @@ type PaymentStatus string
 type PaymentMethod string
 
 const (
-	MemberStatusActive MemberStatus = "Active"
+	MemberStatusActive  MemberStatus = "Active"
 	MemberStatusInvited MemberStatus = "Invited"
-	MemberStatusLeft MemberStatus = "Left"
+	MemberStatusLeft    MemberStatus = "Left"
 
 	PaymentStatusNotPaid PaymentStatus = "Not_Paid"
-	PaymentStatusPaid PaymentStatus = "Paid"
+	PaymentStatusPaid    PaymentStatus = "Paid"
+
+	BankAccount      PaymentMethod = "BANKAC"
+	PromptPay        PaymentMethod = "MSISDN"
+	PromptPayNatID   PaymentMethod = "NATID"
+	PromptPayEWallet PaymentMethod = "EWALLETID"
 
-	BankAccount PaymentMethod = "BANKAC"
-	PromptPay PaymentMethod = "MSISDN"
+	// methods without a slip, recorded by the owner
+	Cash      PaymentMethod = "CASH"
+	TrueMoney PaymentMethod = "TRUEMONEY"
+	PayPal    PaymentMethod = "PAYPAL"
+	InKind    PaymentMethod = "IN_KIND"
 )
 
+// ManualPaymentMethods can be recorded with RecordManualPayment.
+var ManualPaymentMethods = []PaymentMethod{Cash, TrueMoney, PayPal, InKind, BankAccount, PromptPay}
+
 type GroupMember struct {
-	MemberID string `json:"member_id"`
-	Dept int64 `json:"dept"`
-	Status MemberStatus `json:"status"`
-	Payment PaymentStatus `json:"payment_status"`
+	MemberID string         `json:"member_id"`
+	Dept     currency.Minor `json:"dept"`   // derived from the ledger, see LedgerEntry
+	Credit   currency.Minor `json:"credit"` // overpaid amount, applied to the next bills
+	Status   MemberStatus   `json:"status"`
+	Payment  PaymentStatus  `json:"payment_status"`
 }
 
 type PaymentAccount struct {
-	Method PaymentMethod `json:"method"`
-	Account string `json:"account"`
+	Method    PaymentMethod `json:"method"`
+	Account   string        `json:"account"`
+	BankCode  string        `json:"bank_code,omitempty"` // e.g. "004" for KBank, only used with BANKAC
+	NameTh    string        `json:"name_th,omitempty"`   // receiver name as printed on Thai slips
+	NameEn    string        `json:"name_en,omitempty"`
+	Preferred bool          `json:"preferred,omitempty"` // account used for QR codes
 }
 
 type Group struct {
-	ID int64 `json:"id"`
-	Name string `json:"name"`
-	Amount float64 `json:"amount"`
-	AmountPerMember int64 `json:"amount_per_person"`
-	DueDay int `json:"due_day"`
-	Members []GroupMember `json:"members"`
-	DiscordGuildID string `json:"discord_guild_id"`
-	OwnerDiscordID string `json:"owner_discord_id"`
-	Payment PaymentAccount `json:"payment"`
-	CreateAt time.Time `json:"create_at"`
+	ID              int64            `json:"id"`
+	Name            string           `json:"name"`
+	Amount          float64          `json:"amount"`
+	AmountPerMember currency.Minor   `json:"amount_per_person"`
+	DueDay          int              `json:"due_day"`
+	Members         []GroupMember    `json:"members"`
+	DiscordGuildID  string           `json:"discord_guild_id"`
+	OwnerDiscordID  string           `json:"owner_discord_id"`
+	Payment         PaymentAccount   `json:"payment"` // the preferred entry of PaymentAccounts
+	PaymentAccounts []PaymentAccount `json:"payment_accounts"`
+	Currency        string           `json:"currency"` // bills are issued in this currency
+	CreateAt        time.Time        `json:"create_at"`
+	Version         int64            `json:"version"` // bumped by every change, served as the ETag
 }
 
 type CreateGroupRequest struct {
-	Name           string   `json:"name"`
-	Amount         float64  `json:"amount"`
-	DueDay         int      `json:"due_day"`
-	DiscordGuildID string   `json:"discord_guild_id"`
-	OwnerDiscordID string   `json:"owner_discord_id"`
-	Payment        PaymentAccount `json:"payment"`
+	Name            string           `json:"name"`
+	Amount          float64          `json:"amount"`
+	DueDay          int              `json:"due_day"`
+	DiscordGuildID  string           `json:"discord_guild_id"`
+	OwnerDiscordID  string           `json:"owner_discord_id"`
+	Payment         PaymentAccount   `json:"payment"`
+	PaymentAccounts []PaymentAccount `json:"payment_accounts"` // takes precedence over Payment
+	Currency        string           `json:"currency"`         // ISO 4217, defaults to THB
 }
 
 type UpdateGroupRequest struct {
-	Name           string   `json:"name"`
-	Amount         float64  `json:"amount"`
-	DueDay         int      `json:"due_day"`
-	Members        []GroupMember `json:"members"`
-	DiscordGuildID string   `json:"discord_guild_id"`
-	OwnerDiscordID string   `json:"owner_discord_id"`
-	Payment        PaymentAccount `json:"payment"`
+	Name            string           `json:"name"`
+	Amount          float64          `json:"amount"`
+	DueDay          int              `json:"due_day"`
+	DiscordGuildID  string           `json:"discord_guild_id"`
+	OwnerDiscordID  string           `json:"owner_discord_id"`
+	Payment         PaymentAccount   `json:"payment"`
+	PaymentAccounts []PaymentAccount `json:"payment_accounts"` // takes precedence over Payment
+	Currency        string           `json:"currency"`         // ISO 4217, fixed once the group has bills
+
+	Version *int64 `json:"-"` // from If-Match; nil updates whatever version is stored
+}
+
+// PatchGroupRequest is a JSON merge patch (RFC 7386) over the fields of
+// UpdateGroupRequest, e.g. {"name": "Spotify"}. Members can't be patched.
+type PatchGroupRequest struct {
+	Patch   json.RawMessage
+	Version *int64 // from If-Match; nil patches whatever version is stored
 }
 
 type InviteGroupRequest struct {
-	OwnerID string `json:"owner_id"`
+	OwnerID   string   `json:"owner_id"`
 	MemberIDs []string `json:"member_ids"`
 }
 
@@ -74,5 +109,93 @@ type AcceptInviteRequest struct {
 }
 
 type MarkAsPaidRequest struct {
-	Amount int64 `json:"amount"`
-}
\ No newline at end of file
+	Amount currency.Minor `json:"amount"`
+	BillID *int64         `json:"bill_id,omitempty"` // bill the payment was made for, if any
+}
+
+// RecordPaymentRequest is an owner recording a payment made outside of slips
+// (cash, TrueMoney, PayPal, paid in kind...) against one bill.
+type RecordPaymentRequest struct {
+	OwnerID string        `json:"owner_id"`
+	Method  PaymentMethod `json:"method"`
+	Amount  float64       `json:"amount"`
+	Note    string        `json:"note"`
+	PaidAt  *time.Time    `json:"paid_at,omitempty"`
+
+	AttachmentName string `json:"-"`
+	AttachmentType string `json:"-"`
+	Attachment     []byte `json:"-"`
+}
+
+// RecordPaymentResult is the bill after a manual payment and the payment.
+type RecordPaymentResult struct {
+	Bill    *bill.Bill    `json:"bill"`
+	Payment *bill.Payment `json:"payment"`
+}
+
+// BillActionRequest is an owner canceling or waiving a bill.
+type BillActionRequest struct {
+	OwnerID string `json:"owner_id"`
+	Reason  string `json:"reason"`
+}
+
+// AmendBillRequest changes a bill that is still open; nil fields are left as
+// they are.
+type AmendBillRequest struct {
+	OwnerID     string   `json:"owner_id"`
+	AmountDue   *float64 `json:"amount_due,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Reason      string   `json:"reason"`
+}
+
+type LedgerKind string
+
+const (
+	LedgerOpening    LedgerKind = "opening"    // balance carried over from before the ledger existed
+	LedgerCharge     LedgerKind = "charge"     // a bill issued to the member
+	LedgerPayment    LedgerKind = "payment"    // slip or manual payment
+	LedgerCredit     LedgerKind = "credit"     // credit granted by the owner
+	LedgerAdjustment LedgerKind = "adjustment" // owner correction, either direction
+	LedgerWriteOff   LedgerKind = "write_off"  // dept forgiven by the owner
+	LedgerRefund     LedgerKind = "refund"     // credit paid back by the owner
+)
+
+// LedgerEntry is one movement on a member's account in a group. Debit raises
+// what the member owes, Credit lowers it; the balance is the running sum of
+// Debit - Credit, negative when the member is in credit. Amounts are in the
+// group's currency, kept in minor units so fractional bills add up.
+type LedgerEntry struct {
+	ID        int64          `json:"id"`
+	GroupID   int64          `json:"group_id"`
+	MemberID  string         `json:"member_id"`
+	Kind      LedgerKind     `json:"kind"`
+	Debit     currency.Minor `json:"debit"`
+	Credit    currency.Minor `json:"credit"`
+	BillID    *int64         `json:"bill_id,omitempty"`
+	ActorID   string         `json:"actor_id,omitempty"`
+	Note      string         `json:"note,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+
+	Balance currency.Minor `json:"balance"` // running balance after this entry, not stored
+}
+
+type MemberLedger struct {
+	GroupID  int64          `json:"group_id"`
+	MemberID string         `json:"member_id"`
+	Balance  currency.Minor `json:"balance"`
+	Dept     currency.Minor `json:"dept"`
+	Credit   currency.Minor `json:"credit"`
+	Entries  []LedgerEntry  `json:"entries"`
+}
+
+type MemberCredit struct {
+	MemberID string         `json:"member_id"`
+	Credit   currency.Minor `json:"credit"`
+	Refunds  []LedgerEntry  `json:"refunds"`
+}
+
+type RefundCreditRequest struct {
+	OwnerID string         `json:"owner_id"`
+	Amount  currency.Minor `json:"amount"`
+	Note    string         `json:"note"`
+}
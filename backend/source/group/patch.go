@@ -0,0 +1,96 @@
+package group
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+)
+
+// PatchGroup applies a JSON merge patch to a group's settings: keys in the
+// patch replace the current values, null resets them, and everything else is
+// kept. The result is checked like a full update.
+func (s *Service) PatchGroup(ctx context.Context, req PatchGroupRequest, id int64) (*Group, error) {
+	var patch map[string]any
+	if err := json.Unmarshal(req.Patch, &patch); err != nil || patch == nil {
+		return nil, ErrInvalidPatch
+	}
+
+	g, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := toJSONObject(settingsOf(g))
+	if err != nil {
+		return nil, err
+	}
+	// the legacy single payment replaces the whole list
+	if _, ok := patch["payment"]; ok {
+		if _, ok := patch["payment_accounts"]; !ok {
+			delete(current, "payment_accounts")
+		}
+	}
+
+	merged, err := json.Marshal(mergePatch(current, patch))
+	if err != nil {
+		return nil, err
+	}
+
+	var update UpdateGroupRequest
+	if err := validate.DecodeJSON(bytes.NewReader(merged), &update); err != nil {
+		return nil, err
+	}
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+	update.Version = req.Version
+
+	return s.updateSettings(ctx, g, update)
+}
+
+// settingsOf is the update that would leave g as it is.
+func settingsOf(g *Group) UpdateGroupRequest {
+	return UpdateGroupRequest{
+		Name:            g.Name,
+		Amount:          g.Amount,
+		DueDay:          g.DueDay,
+		DiscordGuildID:  g.DiscordGuildID,
+		OwnerDiscordID:  g.OwnerDiscordID,
+		Payment:         g.Payment,
+		PaymentAccounts: g.PaymentAccounts,
+		Currency:        g.Currency,
+	}
+}
+
+func toJSONObject(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]any
+	err = json.Unmarshal(data, &obj)
+	return obj, err
+}
+
+// mergePatch applies patch to target as RFC 7386 describes.
+func mergePatch(target any, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
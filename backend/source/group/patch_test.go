@@ -0,0 +1,192 @@
+package group
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	decode := func(s string) any {
+		var v any
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	for _, tt := range tests {
+		got := mergePatch(decode(tt.target), decode(tt.patch))
+		if want := decode(tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("mergePatch(%s, %s) = %v, want %s", tt.target, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestPatchGroup(t *testing.T) {
+	version := func(v int64) *int64 { return &v }
+	bank := PaymentAccount{Method: BankAccount, Account: "1234567890", BankCode: "004"}
+
+	tests := []struct {
+		name    string
+		patch   string
+		version *int64
+		wantErr error
+		check   func(t *testing.T, g *Group)
+	}{
+		{
+			name:  "keeps what the patch leaves out",
+			patch: `{"name":"Disney+"}`,
+			check: func(t *testing.T, g *Group) {
+				if g.Name != "Disney+" || g.Amount != 400 || g.DueDay != 5 || len(g.PaymentAccounts) != 1 {
+					t.Errorf("group = %+v", g)
+				}
+			},
+		},
+		{
+			name:  "splits a new amount over the members",
+			patch: `{"amount":600}`,
+			check: func(t *testing.T, g *Group) {
+				if g.AmountPerMember != currency.ToMinor(300) {
+					t.Errorf("amount per member = %v, want 300", g.AmountPerMember)
+				}
+			},
+		},
+		{
+			name:  "null currency falls back to the current one",
+			patch: `{"currency":null}`,
+			check: func(t *testing.T, g *Group) {
+				if g.Currency != "THB" {
+					t.Errorf("currency = %q, want THB", g.Currency)
+				}
+			},
+		},
+		{
+			name:  "the legacy payment replaces the whole list",
+			patch: `{"payment":{"method":"BANKAC","account":"1234567890","bank_code":"004"}}`,
+			check: func(t *testing.T, g *Group) {
+				if len(g.PaymentAccounts) != 1 || g.PaymentAccounts[0].Account != bank.Account || g.Payment.Account != bank.Account {
+					t.Errorf("payment = %+v, accounts = %+v", g.Payment, g.PaymentAccounts)
+				}
+			},
+		},
+		{
+			name:    "matching version",
+			patch:   `{"due_day":10}`,
+			version: version(3),
+			check: func(t *testing.T, g *Group) {
+				if g.DueDay != 10 || g.Version != 4 {
+					t.Errorf("due day %d at version %d", g.DueDay, g.Version)
+				}
+			},
+		},
+		{name: "stale version", patch: `{"due_day":10}`, version: version(2), wantErr: ErrVersionMismatch},
+		{name: "not an object", patch: `["name"]`, wantErr: ErrInvalidPatch},
+		{name: "null patch", patch: `null`, wantErr: ErrInvalidPatch},
+		{name: "unknown field", patch: `{"colour":"red"}`, wantErr: validate.ErrInvalid},
+		{name: "result must still be valid", patch: `{"due_day":40}`, wantErr: validate.ErrInvalid},
+		{name: "required setting reset", patch: `{"name":null}`, wantErr: validate.ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &groupStore{g: testGroup()}
+			s := NewService(store)
+
+			g, err := s.PatchGroup(context.Background(), PatchGroupRequest{Patch: json.RawMessage(tt.patch), Version: tt.version}, 1)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("PatchGroup = %v, want %v", err, tt.wantErr)
+				}
+				if store.g.Version != 3 {
+					t.Errorf("refused patch was written")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PatchGroup: %v", err)
+			}
+			if len(g.Members) != 2 {
+				t.Errorf("members = %+v", g.Members)
+			}
+			tt.check(t, g)
+		})
+	}
+}
+
+func TestMemberChangesKeepConcurrentSettings(t *testing.T) {
+	store := &groupStore{g: testGroup()}
+	store.interfere = func(g *Group) { g.Name = "Renamed" }
+	s := NewService(store)
+
+	g, err := s.InviteGroup(context.Background(), InviteGroupRequest{
+		OwnerID:   "100000000000000001",
+		MemberIDs: []string{"100000000000000003"},
+	}, 1)
+	if err != nil {
+		t.Fatalf("InviteGroup: %v", err)
+	}
+
+	if store.g.Name != "Renamed" || len(store.g.Members) != 3 {
+		t.Errorf("stored group = %q with %d members, want the rename and the invite", store.g.Name, len(store.g.Members))
+	}
+	if g.Version != 5 || store.g.Version != 5 {
+		t.Errorf("version = %d, stored %d; want 5", g.Version, store.g.Version)
+	}
+}
+
+func TestCurrencyChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		bills   []bill.Bill
+		ledger  []LedgerEntry
+		wantErr error
+	}{
+		{name: "new group", patch: `{"currency":"USD"}`},
+		{name: "group with bills", patch: `{"currency":"USD"}`, bills: []bill.Bill{{ID: 1, GroupID: 1}}, wantErr: ErrCurrencyLocked},
+		{name: "group with a ledger", patch: `{"currency":"USD"}`, ledger: []LedgerEntry{{ID: 1, GroupID: 1}}, wantErr: ErrCurrencyLocked},
+		{name: "same currency with bills", patch: `{"currency":"thb","name":"Disney+"}`, bills: []bill.Bill{{ID: 1, GroupID: 1}}},
+		{name: "other settings with bills", patch: `{"name":"Disney+"}`, bills: []bill.Bill{{ID: 1, GroupID: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &groupStore{g: testGroup(), bills: tt.bills, ledger: tt.ledger}
+			s := NewService(store)
+			s.SetRateProvider(fixedRates(35))
+
+			_, err := s.PatchGroup(context.Background(), PatchGroupRequest{Patch: json.RawMessage(tt.patch)}, 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PatchGroup = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && store.g.Currency != "THB" {
+				t.Errorf("currency changed to %s", store.g.Currency)
+			}
+		})
+	}
+}
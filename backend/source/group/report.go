@@ -0,0 +1,248 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+)
+
+// GroupReport is the owner's view of how a group has been paying over a range
+// of billing periods. Amounts are in the group currency; canceled bills are
+// left out.
+type GroupReport struct {
+	GroupID     int64          `json:"group_id"`
+	GroupName   string         `json:"group_name"`
+	Currency    string         `json:"currency"`
+	From        string         `json:"from,omitempty"` // "YYYY-MM", empty when unbounded
+	To          string         `json:"to,omitempty"`
+	Months      []MonthReport  `json:"months"`  // oldest first
+	Members     []MemberReport `json:"members"` // by member ID
+	Totals      ReportTotals   `json:"totals"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+type MonthReport struct {
+	Period      string  `json:"period"` // "YYYY-MM"
+	Bills       int     `json:"bills"`
+	Due         float64 `json:"due"`
+	Collected   float64 `json:"collected"`
+	Outstanding float64 `json:"outstanding"`
+	Waived      float64 `json:"waived"`
+}
+
+// MemberReport rates a member's payments. A bill counts as on time when it was
+// verified by its due date; it counts as late when it was verified after it,
+// or is still open and past due. OnTimeRate is OnTime / (OnTime + Late).
+type MemberReport struct {
+	MemberID      string   `json:"member_id"`
+	Bills         int      `json:"bills"`
+	Paid          int      `json:"paid"`
+	OnTime        int      `json:"on_time"`
+	Late          int      `json:"late"`
+	OnTimeRate    *float64 `json:"on_time_rate"`    // nil until a bill has come due
+	AvgDaysToPay  *float64 `json:"avg_days_to_pay"` // CreatedAt to VerifiedAt, nil without paid bills
+	Due           float64  `json:"due"`
+	Collected     float64  `json:"collected"`
+	Outstanding   float64  `json:"outstanding"`
+	RejectedSlips int      `json:"rejected_slips"`
+}
+
+type ReportTotals struct {
+	Bills         int     `json:"bills"`
+	Due           float64 `json:"due"`
+	Collected     float64 `json:"collected"`
+	Outstanding   float64 `json:"outstanding"`
+	Waived        float64 `json:"waived"`
+	RejectedSlips int     `json:"rejected_slips"`
+}
+
+// ReportRequest selects the billing periods of a report; From and To are
+// inclusive "YYYY-MM" periods and may be left empty.
+type ReportRequest struct {
+	GroupID int64
+	From    string
+	To      string
+}
+
+// memberStats carries the sums a MemberReport is derived from.
+type memberStats struct {
+	report    MemberReport
+	daysToPay float64
+}
+
+func (s *Service) GetGroupReport(ctx context.Context, req ReportRequest) (*GroupReport, error) {
+	if req.GroupID <= 0 {
+		return nil, ErrInvalidGroupID
+	}
+
+	fromPeriod, err := bill.ParsePeriod(req.From)
+	if err != nil {
+		return nil, err
+	}
+	toPeriod, err := bill.ParsePeriod(req.To)
+	if err != nil {
+		return nil, err
+	}
+	if fromPeriod != 0 && toPeriod != 0 && fromPeriod > toPeriod {
+		return nil, bill.ErrInvalidPeriod
+	}
+
+	g, err := s.store.GetGroup(ctx, req.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	bills, err := s.reportBills(ctx, bill.BillQuery{
+		GroupID:    g.ID,
+		FromPeriod: fromPeriod,
+		ToPeriod:   toPeriod,
+		Limit:      reportPageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rejections, err := s.store.CountRejectedSlips(ctx, g.ID, fromPeriod, toPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	report := &GroupReport{
+		GroupID:     g.ID,
+		GroupName:   g.Name,
+		Currency:    g.Currency,
+		From:        req.From,
+		To:          req.To,
+		Months:      []MonthReport{},
+		Members:     []MemberReport{},
+		GeneratedAt: now,
+	}
+
+	months := map[int]*MonthReport{}
+	members := map[string]*memberStats{}
+	member := func(id string) *memberStats {
+		m := members[id]
+		if m == nil {
+			m = &memberStats{report: MemberReport{MemberID: id}}
+			members[id] = m
+		}
+		return m
+	}
+	for _, gm := range g.Members {
+		if gm.Status == MemberStatusActive {
+			member(gm.MemberID)
+		}
+	}
+
+	for _, b := range bills {
+		if b.Status == bill.BillStatusCanceled {
+			continue
+		}
+
+		period := b.Year*100 + b.Month
+		mr := months[period]
+		if mr == nil {
+			mr = &MonthReport{Period: fmt.Sprintf("%04d-%02d", b.Year, b.Month)}
+			months[period] = mr
+		}
+		m := member(b.MemberID)
+		r := &m.report
+
+		outstanding := 0.0
+		waived := 0.0
+		if b.Status == bill.BillStatusWaived {
+			waived = b.Outstanding()
+		} else {
+			outstanding = b.Outstanding()
+		}
+
+		mr.Bills++
+		mr.Due += b.AmountDue
+		mr.Collected += b.AmountPaid
+		mr.Outstanding += outstanding
+		mr.Waived += waived
+
+		r.Bills++
+		r.Due += b.AmountDue
+		r.Collected += b.AmountPaid
+		r.Outstanding += outstanding
+
+		due := nextDueDate(g.DueDay, b.CreatedAt)
+		switch {
+		case b.Status == bill.BillStatusVerified && b.VerifiedAt != nil:
+			r.Paid++
+			m.daysToPay += b.VerifiedAt.Sub(b.CreatedAt).Hours() / 24
+			if b.VerifiedAt.After(due) {
+				r.Late++
+			} else {
+				r.OnTime++
+			}
+		case !b.IsFinal() && now.After(due):
+			r.Late++
+		}
+	}
+
+	for memberID, n := range rejections {
+		member(memberID).report.RejectedSlips += n
+	}
+
+	for _, mr := range months {
+		report.Months = append(report.Months, *mr)
+		report.Totals.Bills += mr.Bills
+		report.Totals.Due += mr.Due
+		report.Totals.Collected += mr.Collected
+		report.Totals.Outstanding += mr.Outstanding
+		report.Totals.Waived += mr.Waived
+	}
+	sort.Slice(report.Months, func(i, j int) bool {
+		return report.Months[i].Period < report.Months[j].Period
+	})
+
+	for _, m := range members {
+		r := m.report
+		if rated := r.OnTime + r.Late; rated > 0 {
+			rate := roundReport(float64(r.OnTime) / float64(rated))
+			r.OnTimeRate = &rate
+		}
+		if r.Paid > 0 {
+			avg := roundReport(m.daysToPay / float64(r.Paid))
+			r.AvgDaysToPay = &avg
+		}
+		report.Totals.RejectedSlips += r.RejectedSlips
+		report.Members = append(report.Members, r)
+	}
+	sort.Slice(report.Members, func(i, j int) bool {
+		return report.Members[i].MemberID < report.Members[j].MemberID
+	})
+
+	return report, nil
+}
+
+const reportPageSize = 500
+
+// reportBills pages through every bill matching q.
+func (s *Service) reportBills(ctx context.Context, q bill.BillQuery) ([]bill.Bill, error) {
+	var all []bill.Bill
+	for {
+		bills, err := s.store.ListBills(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		if len(bills) <= q.Limit {
+			return append(all, bills...), nil
+		}
+
+		bills = bills[:q.Limit]
+		all = append(all, bills...)
+		last := bills[len(bills)-1]
+		q.After = &bill.BillCursor{Period: last.Year*100 + last.Month, ID: last.ID}
+	}
+}
+
+func roundReport(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
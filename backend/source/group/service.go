@@ -4,7 +4,9 @@ import (
 	"context"
 	"time"
 
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
 	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
 )
 
 type Store interface {
@@ -12,19 +14,42 @@ type Store interface {
 	SaveGroup(ctx context.Context, g Group) error
 	GetGroup(ctx context.Context, id int64) (*Group, error)
 	DeleteGroup(ctx context.Context, id int64) error
-	UpdateGroup(ctx context.Context, id int64, g Group) error
+	UpdateGroupVersion(ctx context.Context, id, version int64, g Group) (bool, error)
 	GetGroupByDueday(ctx context.Context, dueDay int) ([]Group, error)
+	ListGroups(ctx context.Context, q GroupQuery) ([]Group, error)
+	GetOpenBillsByMemberID(ctx context.Context, memberID string) ([]bill.Bill, error)
+	ListBills(ctx context.Context, q bill.BillQuery) ([]bill.Bill, error)
+	CountRejectedSlips(ctx context.Context, groupID int64, fromPeriod, toPeriod int) (map[string]int, error)
+	GetLatestPayment(ctx context.Context, groupID int64, memberID string) (*bill.Payment, error)
 	NextBillID(ctx context.Context) (int64, error)
 	SaveBill(ctx context.Context, b bill.Bill) error
 	GetBillByID(ctx context.Context, id int64) (*bill.Bill, error)
 	GetBillsByGroupAndMember(ctx context.Context, groupID int64, memberID string) ([]bill.Bill, error)
+	// GetBillByGroupMemberCycle returns the member's recurring bill for a
+	// month, or nil when there is none.
 	GetBillByGroupMemberCycle(ctx context.Context, groupID int64, memberID string, year, month int) (*bill.Bill, error)
 	GetBillsByMemberID(ctx context.Context, memberID string) ([]bill.Bill, error)
 	GetBillsByGroupID(ctx context.Context, groupID int64) ([]bill.Bill, error)
+	NextPaymentID(ctx context.Context) (int64, error)
+	SavePayment(ctx context.Context, p bill.Payment) error
+	GetPaymentsByBillID(ctx context.Context, billID int64) ([]bill.Payment, error)
+	UpdateBill(ctx context.Context, b bill.Bill) (*bill.Bill, error)
+	NextLedgerEntryID(ctx context.Context) (int64, error)
+	SaveLedgerEntry(ctx context.Context, e LedgerEntry) error
+	GetLedgerEntries(ctx context.Context, groupID int64, memberID string) ([]LedgerEntry, error)
+	GetLedgerBalances(ctx context.Context, groupID int64) (map[string]currency.Minor, error)
+	HasBillsOrLedger(ctx context.Context, groupID int64) (bool, error)
+	// RunInTx runs fn in one transaction; store calls made with the context
+	// fn gets join it.
+	RunInTx(ctx context.Context, fn func(ctx context.Context) error) error
+	NextBillEventID(ctx context.Context) (int64, error)
+	SaveBillEvent(ctx context.Context, e bill.BillEvent) error
 }
 
 type Service struct {
 	store Store
+	rates currency.RateProvider
+	audit *audit.Service
 }
 
 func NewService(store Store) *Service {
@@ -32,20 +57,13 @@ func NewService(store Store) *Service {
 }
 
 func (s *Service) CreateGroup(ctx context.Context, req CreateGroupRequest) (*Group, error) {
-	if req.Name == "" {
-		return nil, ErrInvalidName
-	}
-	if req.Amount <= 0 {
-		return nil, ErrInvalidAmount
-	}
-	if req.DueDay < 1 || req.DueDay > 31 {
-		return nil, ErrInvalidDueDay
-	}
-	if req.DiscordGuildID == "" {
-		return nil, ErrInvalidGuildID
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
-	if req.OwnerDiscordID == "" {
-		return nil, ErrInvalidOwnerID
+	accounts, preferred := normalizePaymentAccounts(req.Payment, req.PaymentAccounts)
+	cur, err := s.groupCurrency(ctx, req.Currency, "")
+	if err != nil {
+		return nil, err
 	}
 
 	id, err := s.store.NextGroupID(ctx)
@@ -55,174 +73,268 @@ func (s *Service) CreateGroup(ctx context.Context, req CreateGroupRequest) (*Gro
 
 	var owner GroupMember = GroupMember{
 		MemberID: req.OwnerDiscordID,
-		Dept: 0,
-		Status: MemberStatusActive,
-		Payment: PaymentStatusNotPaid,
+		Dept:     0,
+		Status:   MemberStatusActive,
+		Payment:  PaymentStatusNotPaid,
 	}
 
 	members := []GroupMember{owner}
 
 	g := Group{
-		ID:             id,
-		Name:           req.Name,
-		Amount:         req.Amount,
-		AmountPerMember: int64(req.Amount),
-		DueDay:         req.DueDay,
-		Members:        members,
-		DiscordGuildID: req.DiscordGuildID,
-		OwnerDiscordID: req.OwnerDiscordID,
-		Payment:        req.Payment,
-		CreateAt:      time.Now().UTC(),
+		ID:              id,
+		Name:            req.Name,
+		Amount:          req.Amount,
+		AmountPerMember: currency.ToMinor(req.Amount),
+		DueDay:          req.DueDay,
+		Members:         members,
+		DiscordGuildID:  req.DiscordGuildID,
+		OwnerDiscordID:  req.OwnerDiscordID,
+		Payment:         preferred,
+		PaymentAccounts: accounts,
+		Currency:        cur,
+		CreateAt:        time.Now().UTC(),
+		Version:         1,
 	}
 
 	if err := s.store.SaveGroup(ctx, g); err != nil {
 		return nil, err
 	}
 
+	if err := s.audit.Record(ctx, audit.Entry{
+		GroupID:    g.ID,
+		ActorID:    req.OwnerDiscordID,
+		Action:     audit.ActionGroupCreate,
+		EntityType: audit.EntityGroup,
+		EntityID:   groupEntityID(g.ID),
+		After:      g,
+	}); err != nil {
+		return nil, err
+	}
+
 	return &g, nil
 }
 
 func (s *Service) GetGroup(ctx context.Context, id int64) (*Group, error) {
-	return s.store.GetGroup(ctx, id)
+	g, err := s.store.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyBalances(ctx, g); err != nil {
+		return nil, err
+	}
+
+	return g, nil
 }
 
 func (s *Service) DeleteGroup(ctx context.Context, id int64) error {
-	return s.store.DeleteGroup(ctx, id)
+	g, err := s.store.GetGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteGroup(ctx, id); err != nil {
+		return err
+	}
+
+	return s.audit.Record(ctx, audit.Entry{
+		GroupID:    id,
+		Action:     audit.ActionGroupDelete,
+		EntityType: audit.EntityGroup,
+		EntityID:   groupEntityID(id),
+		Before:     g,
+	})
 }
 
+// UpdateGroup replaces a group's settings. Members are left alone; they change
+// through invites.
 func (s *Service) UpdateGroup(ctx context.Context, req UpdateGroupRequest, id int64) (*Group, error) {
-	if req.Name == "" {
-		return nil, ErrInvalidName
-	}
-	if req.Amount <= 0 {
-		return nil, ErrInvalidAmount
-	}
-	if req.DueDay < 1 || req.DueDay > 31 {
-		return nil, ErrInvalidDueDay
-	}
-	if req.DiscordGuildID == "" {
-		return nil, ErrInvalidGuildID
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
-	if req.OwnerDiscordID == "" {
-		return nil, ErrInvalidOwnerID
+
+	g, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	if len(req.Members) == 0 {
-		return nil, ErrNoMembersProvided
+
+	return s.updateSettings(ctx, g, req)
+}
+
+// updateSettings writes the settings in req over g as it was just loaded. The
+// write fails with ErrVersionMismatch when req.Version is not g's version, or
+// when the group changes between the load and the write.
+func (s *Service) updateSettings(ctx context.Context, g *Group, req UpdateGroupRequest) (*Group, error) {
+	if req.Version != nil && *req.Version != g.Version {
+		return nil, ErrVersionMismatch
 	}
 
-	g, err := s.GetGroup(ctx, id)
+	accounts, preferred := normalizePaymentAccounts(req.Payment, req.PaymentAccounts)
+
+	cur, err := s.groupCurrency(ctx, req.Currency, g.Currency)
 	if err != nil {
 		return nil, err
 	}
+	if cur != g.Currency {
+		// bills and ledger amounts are in the old currency
+		used, err := s.store.HasBillsOrLedger(ctx, g.ID)
+		if err != nil {
+			return nil, err
+		}
+		if used {
+			return nil, ErrCurrencyLocked
+		}
+	}
 
 	newGroup := Group{
-		ID: g.ID,
-		Name: req.Name,
-		Amount: req.Amount,
-		DueDay: req.DueDay,
-		Members: req.Members,
-		DiscordGuildID: req.DiscordGuildID,
-		OwnerDiscordID: req.OwnerDiscordID,
-		Payment: req.Payment,
-		CreateAt: g.CreateAt,
-	}
-
-	err = s.store.UpdateGroup(ctx, g.ID, newGroup)
+		ID:              g.ID,
+		Name:            req.Name,
+		Amount:          req.Amount,
+		AmountPerMember: currency.ToMinor(req.Amount) / currency.Minor(len(g.Members)),
+		DueDay:          req.DueDay,
+		Members:         g.Members,
+		DiscordGuildID:  req.DiscordGuildID,
+		OwnerDiscordID:  req.OwnerDiscordID,
+		Payment:         preferred,
+		PaymentAccounts: accounts,
+		Currency:        cur,
+		CreateAt:        g.CreateAt,
+	}
+
+	updated, err := s.store.UpdateGroupVersion(ctx, g.ID, g.Version, newGroup)
 	if err != nil {
 		return nil, err
 	}
+	if !updated {
+		return nil, ErrVersionMismatch
+	}
 
-	g, err = s.GetGroup(ctx, id)
+	before := g
+	g, err = s.GetGroup(ctx, g.ID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.audit.Record(ctx, audit.Entry{
+		GroupID:    g.ID,
+		Action:     audit.ActionGroupUpdate,
+		EntityType: audit.EntityGroup,
+		EntityID:   groupEntityID(g.ID),
+		Before:     before,
+		After:      g,
+	}); err != nil {
+		return nil, err
+	}
 
-	return g, err
+	return g, nil
 }
 
 func (s *Service) InviteGroup(ctx context.Context, req InviteGroupRequest, id int64) (*Group, error) {
-	g, err := s.GetGroup(ctx, id)
-	if err != nil {
+	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
-	if g.OwnerDiscordID != req.OwnerID {
-		return nil, ErrInvitedPermission
-	}
-
-	if len(req.MemberIDs) == 0 {
-		return nil, ErrNoMembersProvided
-	}
+	g, err := s.updateMembers(ctx, id, func(g *Group) error {
+		if g.OwnerDiscordID != req.OwnerID {
+			return ErrInvitedPermission
+		}
 
-	for _, newID := range req.MemberIDs {
-		for _, m := range g.Members {
-			if m.MemberID != newID {
-				continue
+		for _, newID := range req.MemberIDs {
+			for _, m := range g.Members {
+				if m.MemberID != newID {
+					continue
+				}
+
+				switch m.Status {
+				case MemberStatusActive:
+					return ErrAleadyMembered
+				case MemberStatusInvited:
+					return ErrAleadyInvited
+				case MemberStatusLeft:
+					// allow re-invite -> do nothing here
+				default:
+					// unknown status -> treat as conflict or log
+					return ErrAleadyInvited
+				}
 			}
+		}
 
-			switch m.Status {
-			case MemberStatusActive:
-				return nil, ErrAleadyMembered
-			case MemberStatusInvited:
-				return nil, ErrAleadyInvited
-			case MemberStatusLeft:
-				// allow re-invite -> do nothing here
-			default:
-				// unknown status -> treat as conflict or log
-				return nil, ErrAleadyInvited
-			}
+		for _, newID := range req.MemberIDs {
+			g.Members = append(g.Members, GroupMember{
+				MemberID: newID,
+				Dept:     0,
+				Status:   MemberStatusInvited,
+				Payment:  PaymentStatusNotPaid,
+			})
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	members := g.Members
-
 	for _, newID := range req.MemberIDs {
-		member := GroupMember{
-			MemberID: newID,
-			Dept: 0,
-			Status:   MemberStatusInvited,
-			Payment:  PaymentStatusNotPaid,
+		if err := s.audit.Record(ctx, audit.Entry{
+			GroupID:    id,
+			ActorID:    req.OwnerID,
+			Action:     audit.ActionMemberInvite,
+			EntityType: audit.EntityMember,
+			EntityID:   memberEntityID(id, newID),
+			After:      map[string]any{"status": MemberStatusInvited},
+		}); err != nil {
+			return nil, err
 		}
-		members = append(members, member)
-	}
-
-	g.Members = members
-	if err := s.store.UpdateGroup(ctx, id, *g); err != nil {
-		return nil, err
 	}
 
 	return g, nil
 }
 
 func (s *Service) AcceptInvite(ctx context.Context, req AcceptInviteRequest, id int64) (*Group, error) {
-	g, err := s.GetGroup(ctx, id)
-	if err != nil {
+	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
-	var index = -1
-	for i, member := range g.Members {
-		if member.MemberID == req.UserID && member.Status == MemberStatusInvited {
-			index = i
-			break
+	var perMemberBefore currency.Minor
+	g, err := s.updateMembers(ctx, id, func(g *Group) error {
+		var index = -1
+		for i, member := range g.Members {
+			if member.MemberID == req.UserID && member.Status == MemberStatusInvited {
+				index = i
+				break
+			}
 		}
-	}
 
-	if index == -1 {
-		return nil, ErrNotInvited
+		if index == -1 {
+			return ErrNotInvited
+		}
+
+		perMemberBefore = g.AmountPerMember
+		g.Members[index].Status = MemberStatusActive
+		g.AmountPerMember = currency.ToMinor(g.Amount) / currency.Minor(len(g.Members))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	g.Members[index].Status = MemberStatusActive
-	g.AmountPerMember = int64(int(g.Amount) / len(g.Members))
-	
-	if err := s.store.UpdateGroup(ctx, id, *g); err != nil {
+	if err := s.audit.Record(ctx, audit.Entry{
+		GroupID:    id,
+		ActorID:    req.UserID,
+		Action:     audit.ActionMemberAccept,
+		EntityType: audit.EntityMember,
+		EntityID:   memberEntityID(id, req.UserID),
+		Before:     map[string]any{"status": MemberStatusInvited, "amount_per_person": perMemberBefore},
+		After:      map[string]any{"status": MemberStatusActive, "amount_per_person": g.AmountPerMember},
+	}); err != nil {
 		return nil, err
 	}
 
 	return g, nil
 }
 
+// ResetPaymentForDueday issues this month's bills of every group due on
+// dueDay. Only active members are billed: invited ones start with the first
+// cycle after they join, and members who left owe nothing new.
 func (s *Service) ResetPaymentForDueday(ctx context.Context, dueDay int) error {
 	if dueDay < 1 || dueDay > 31 {
 		return ErrInvalidDueDay
@@ -234,43 +346,126 @@ func (s *Service) ResetPaymentForDueday(ctx context.Context, dueDay int) error {
 	}
 
 	for _, g := range groups {
+		if err := s.applyBalances(ctx, &g); err != nil {
+			return err
+		}
+
+		local := time.Now()
+		year, month := local.Year(), int(local.Month())
+		now := local.UTC()
+		issued := 0
+
 		for i := range g.Members {
-			if g.Members[i].Status != MemberStatusLeft {
-				g.Members[i].Payment = PaymentStatusNotPaid
-				g.Members[i].Dept += g.AmountPerMember
+			if g.Members[i].Status != MemberStatusActive {
+				continue
 			}
 
-			newID, err := s.store.NextBillID(ctx)
+			// running twice in a month must not bill anyone twice
+			existing, err := s.store.GetBillByGroupMemberCycle(ctx, g.ID, g.Members[i].MemberID, year, month)
 			if err != nil {
 				return err
 			}
+			if existing != nil {
+				continue
+			}
 
-			year := time.Now().Year()
-			month := time.Now().Month()
-			now := time.Now().UTC()
+			newID, err := s.store.NextBillID(ctx)
+			if err != nil {
+				return err
+			}
 
 			b := bill.Bill{
-				ID: newID,
-				GroupID: g.ID,
-				MemberID: g.Members[i].MemberID,
-				Year: year,
-				Month: int(month),
-				AmountDue: float64(g.AmountPerMember),
-				AmountPaid: 0,
-				Currency: "THB",
-				Status: bill.BillStatusSubmitted,
+				ID:          newID,
+				Kind:        bill.BillKindRecurring,
+				PayeeID:     g.OwnerDiscordID,
+				GroupID:     g.ID,
+				MemberID:    g.Members[i].MemberID,
+				Year:        year,
+				Month:       month,
+				AmountDue:   g.AmountPerMember.Major(),
+				AmountPaid:  0,
+				Currency:    g.Currency,
+				Status:      bill.BillStatusPending,
 				Description: "",
-				ProofJSON: "",
-				CreatedAt: now,
-				UpdatedAt: now,
+				ProofJSON:   "",
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+
+			if err := b.SnapshotRate(ctx, s.rates, now); err != nil {
+				return err
 			}
 
 			if err := s.store.SaveBill(ctx, b); err != nil {
 				return err
 			}
+
+			if err := bill.Issued(ctx, s.store, &b, bill.ActorSystem); err != nil {
+				return err
+			}
+			issued++
+
+			// credit from earlier overpayments covers the new bill first
+			applied := g.Members[i].Credit
+			if applied > g.AmountPerMember {
+				applied = g.AmountPerMember
+			}
+
+			billID := b.ID
+			if err := s.postLedger(ctx, LedgerEntry{
+				GroupID:   g.ID,
+				MemberID:  g.Members[i].MemberID,
+				Kind:      LedgerCharge,
+				Debit:     g.AmountPerMember,
+				BillID:    &billID,
+				CreatedAt: now,
+			}); err != nil {
+				return err
+			}
+
+			if applied > 0 {
+				if err := s.applyCreditToBill(ctx, &b, applied, now); err != nil {
+					return err
+				}
+				if _, err := s.store.UpdateBill(ctx, b); err != nil {
+					return err
+				}
+			}
+
+			if g.Members[i].Credit-g.AmountPerMember >= 0 {
+				g.Members[i].Payment = PaymentStatusPaid
+			} else {
+				g.Members[i].Payment = PaymentStatusNotPaid
+			}
+		}
+
+		if issued == 0 {
+			continue
+		}
+
+		payment := map[string]PaymentStatus{}
+		for _, m := range g.Members {
+			payment[m.MemberID] = m.Payment
+		}
+		if _, err := s.updateMembers(ctx, g.ID, func(cur *Group) error {
+			for i := range cur.Members {
+				if p, ok := payment[cur.Members[i].MemberID]; ok {
+					cur.Members[i].Payment = p
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
 		}
 
-		if err := s.store.UpdateGroup(ctx, g.ID, g); err != nil {
+		if err := s.audit.Record(ctx, audit.Entry{
+			GroupID:    g.ID,
+			ActorID:    bill.ActorSystem,
+			Action:     audit.ActionBillsIssue,
+			EntityType: audit.EntityGroup,
+			EntityID:   groupEntityID(g.ID),
+			After:      map[string]any{"year": year, "month": month, "bills": issued},
+		}); err != nil {
 			return err
 		}
 	}
@@ -278,6 +473,34 @@ func (s *Service) ResetPaymentForDueday(ctx context.Context, dueDay int) error {
 	return nil
 }
 
+// applyCreditToBill records the credit used for a freshly issued bill as a
+// payment, so the bill shows it as (partially) paid. The ledger needs no entry:
+// the charge simply eats into the negative balance.
+func (s *Service) applyCreditToBill(ctx context.Context, b *bill.Bill, amount currency.Minor, now time.Time) error {
+	paymentID, err := s.store.NextPaymentID(ctx)
+	if err != nil {
+		return err
+	}
+
+	p := bill.Payment{
+		ID:        paymentID,
+		BillID:    b.ID,
+		GroupID:   b.GroupID,
+		MemberID:  b.MemberID,
+		Amount:    amount.Major(),
+		Currency:  b.Currency,
+		Source:    bill.PaymentSourceCredit,
+		Status:    bill.PaymentStatusAccepted,
+		PaidAt:    &now,
+		CreatedAt: now,
+	}
+	if err := s.store.SavePayment(ctx, p); err != nil {
+		return err
+	}
+
+	return bill.Settle(ctx, s.store, b, []bill.Payment{p}, bill.ActorSystem, "credit applied", now)
+}
+
 func (s *Service) MarkMemberPaid(ctx context.Context, req MarkAsPaidRequest, groupID int64, memberID string) (*GroupMember, error) {
 	if groupID <= 0 {
 		return nil, ErrInvalidGroupID
@@ -287,7 +510,11 @@ func (s *Service) MarkMemberPaid(ctx context.Context, req MarkAsPaidRequest, gro
 		return nil, ErrNoUserID
 	}
 
-	g, err := s.store.GetGroup(ctx, groupID)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	g, err := s.GetGroup(ctx, groupID)
 	if err != nil {
 		return nil, err
 	}
@@ -300,29 +527,336 @@ func (s *Service) MarkMemberPaid(ctx context.Context, req MarkAsPaidRequest, gro
 	}
 
 	if index == -1 {
-		return  nil, ErrMemberNotFound
+		return nil, ErrMemberNotFound
 	}
 
 	if g.Members[index].Status != MemberStatusActive {
 		return nil, ErrNotActiveMember
 	}
 
-	if g.Members[index].Payment == PaymentStatusPaid {
+	// a payment on a bill is money that arrived; it is booked whatever the
+	// member's status says
+	if req.BillID == nil && g.Members[index].Payment == PaymentStatusPaid {
 		return nil, ErrAlreadyPaid
 	}
 
-	g.Members[index].Dept -= req.Amount
-	if g.Members[index].Dept < 0 {
-		g.Members[index].Dept = 0
+	before := *findMember(g, memberID)
+	m, err := s.recordMemberPayment(ctx, g, LedgerEntry{
+		GroupID:   groupID,
+		MemberID:  memberID,
+		Kind:      LedgerPayment,
+		Credit:    req.Amount,
+		BillID:    req.BillID,
+		ActorID:   audit.Actor(ctx),
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit.Record(ctx, audit.Entry{
+		GroupID:    groupID,
+		Action:     audit.ActionMemberMarkPaid,
+		EntityType: audit.EntityMember,
+		EntityID:   memberEntityID(groupID, memberID),
+		Before:     before,
+		After: struct {
+			GroupMember
+			Amount currency.Minor `json:"amount"`
+			BillID *int64         `json:"bill_id,omitempty"`
+		}{*m, req.Amount, req.BillID},
+	}); err != nil {
+		return nil, err
 	}
 
-	if g.Members[index].Dept == 0 {
-		g.Members[index].Payment = PaymentStatusPaid
+	return m, nil
+}
+
+// recordMemberPayment posts a payment to the ledger and marks the member paid
+// once nothing is owed. Anything above the dept ends up as a negative balance,
+// i.e. credit.
+func (s *Service) recordMemberPayment(ctx context.Context, g *Group, entry LedgerEntry) (*GroupMember, error) {
+	if err := s.postLedger(ctx, entry); err != nil {
+		return nil, err
 	}
 
-	if err := s.store.UpdateGroup(ctx, groupID, *g); err != nil {
+	updated, err := s.updateMembers(ctx, g.ID, func(g *Group) error {
+		m := findMember(g, entry.MemberID)
+		if m == nil {
+			return ErrMemberNotFound
+		}
+
+		if m.Dept == 0 {
+			m.Payment = PaymentStatusPaid
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	*g = *updated
+
+	return findMember(g, entry.MemberID), nil
+}
+
+// memberUpdateAttempts bounds how often updateMembers reloads a group that
+// keeps changing under it.
+const memberUpdateAttempts = 3
+
+// updateMembers applies change to the group as it is now and writes it only
+// at that version, reloading and retrying when something else wrote in
+// between. Member changes thus never overwrite settings saved meanwhile.
+func (s *Service) updateMembers(ctx context.Context, id int64, change func(g *Group) error) (*Group, error) {
+	for attempt := 0; attempt < memberUpdateAttempts; attempt++ {
+		g, err := s.GetGroup(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := change(g); err != nil {
+			return nil, err
+		}
+
+		updated, err := s.store.UpdateGroupVersion(ctx, id, g.Version, *g)
+		if err != nil {
+			return nil, err
+		}
+		if updated {
+			g.Version++ // as the store did
+			return g, nil
+		}
+	}
+	return nil, ErrVersionMismatch
+}
+
+// RecordManualPayment lets the owner record a payment made without a slip
+// against one bill. The bill and the member's ledger move exactly as they do
+// for a verified slip.
+func (s *Service) RecordManualPayment(ctx context.Context, req RecordPaymentRequest, billID int64) (*bill.Bill, *bill.Payment, error) {
+	if billID <= 0 {
+		return nil, nil, bill.ErrInvalidBillID
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	// the payment, the bill, the ledger and the audit log change together
+	var (
+		updated *bill.Bill
+		p       bill.Payment
+	)
+	err := s.store.RunInTx(ctx, func(ctx context.Context) error {
+		b, err := s.store.GetBillByID(ctx, billID)
+		if err != nil {
+			return err
+		}
+		before := *b
+
+		if b.IsFinal() {
+			return ErrBillClosed
+		}
+
+		// a rejected bill has to be reopened first
+		if err := b.CheckTransition(bill.BillStatusVerified); err != nil {
+			return err
+		}
+
+		g, err := s.GetGroup(ctx, b.GroupID)
+		if err != nil {
+			return err
+		}
+
+		if g.OwnerDiscordID != req.OwnerID {
+			return ErrNotOwner
+		}
+
+		// the owner can't vouch for money paid to someone else
+		if !OwedToOwner(g, b) {
+			return ErrNotOwedToOwner
+		}
+
+		if findMember(g, b.MemberID) == nil {
+			return ErrMemberNotFound
+		}
+
+		now := time.Now().UTC()
+		paidAt := now
+		if req.PaidAt != nil {
+			paidAt = req.PaidAt.UTC()
+		}
+
+		paymentID, err := s.store.NextPaymentID(ctx)
+		if err != nil {
+			return err
+		}
+
+		p = bill.Payment{
+			ID:             paymentID,
+			BillID:         b.ID,
+			GroupID:        b.GroupID,
+			MemberID:       b.MemberID,
+			Amount:         req.Amount,
+			Currency:       b.Currency,
+			Source:         bill.PaymentSourceManual,
+			Status:         bill.PaymentStatusAccepted,
+			Method:         string(req.Method),
+			Note:           req.Note,
+			RecordedBy:     req.OwnerID,
+			AttachmentName: req.AttachmentName,
+			AttachmentType: req.AttachmentType,
+			Attachment:     req.Attachment,
+			PaidAt:         &paidAt,
+			CreatedAt:      now,
+		}
+		if err := s.store.SavePayment(ctx, p); err != nil {
+			return err
+		}
+
+		payments, err := s.store.GetPaymentsByBillID(ctx, b.ID)
+		if err != nil {
+			return err
+		}
+
+		b.SubmittedAt = &now
+		if err := bill.Settle(ctx, s.store, b, payments, req.OwnerID, "manual payment recorded", now); err != nil {
+			return err
+		}
 
-	return &g.Members[index], nil
-}
\ No newline at end of file
+		updated, err = s.store.UpdateBill(ctx, *b)
+		if err != nil {
+			return err
+		}
+
+		billRef := b.ID
+		if _, err := s.recordMemberPayment(ctx, g, LedgerEntry{
+			GroupID:   g.ID,
+			MemberID:  b.MemberID,
+			Kind:      LedgerPayment,
+			Credit:    currency.ToMinor(req.Amount),
+			BillID:    &billRef,
+			ActorID:   req.OwnerID,
+			Note:      req.Note,
+			CreatedAt: now,
+		}); err != nil {
+			return err
+		}
+
+		p.Attachment = nil
+		return s.audit.Record(ctx, audit.Entry{
+			GroupID:    g.ID,
+			ActorID:    req.OwnerID,
+			Action:     audit.ActionManualPayment,
+			EntityType: audit.EntityBill,
+			EntityID:   billEntityID(b.ID),
+			Before:     billAuditState(before),
+			After: struct {
+				bill.Bill
+				Payment bill.Payment `json:"payment"`
+			}{billAuditState(*updated), p},
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return updated, &p, nil
+}
+
+func isManualPaymentMethod(m PaymentMethod) bool {
+	for _, allowed := range ManualPaymentMethods {
+		if m == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) GetMemberCredit(ctx context.Context, groupID int64, memberID string) (*MemberCredit, error) {
+	ledger, err := s.GetMemberLedger(ctx, groupID, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	refunds := []LedgerEntry{}
+	for _, e := range ledger.Entries {
+		if e.Kind == LedgerRefund {
+			refunds = append(refunds, e)
+		}
+	}
+
+	return &MemberCredit{
+		MemberID: memberID,
+		Credit:   ledger.Credit,
+		Refunds:  refunds,
+	}, nil
+}
+
+// RefundCredit lets the owner pay back part or all of a member's credit. The
+// refund is a ledger entry carrying the owner and note.
+func (s *Service) RefundCredit(ctx context.Context, req RefundCreditRequest, groupID int64, memberID string) (*MemberCredit, error) {
+	if groupID <= 0 {
+		return nil, ErrInvalidGroupID
+	}
+
+	if memberID == "" {
+		return nil, ErrNoUserID
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	g, err := s.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.OwnerDiscordID != req.OwnerID {
+		return nil, ErrNotOwner
+	}
+
+	m := findMember(g, memberID)
+	if m == nil {
+		return nil, ErrMemberNotFound
+	}
+
+	if req.Amount > m.Credit {
+		return nil, ErrInsufficientCredit
+	}
+
+	if err := s.postLedger(ctx, LedgerEntry{
+		GroupID:   groupID,
+		MemberID:  memberID,
+		Kind:      LedgerRefund,
+		Debit:     req.Amount,
+		ActorID:   req.OwnerID,
+		Note:      req.Note,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.audit.Record(ctx, audit.Entry{
+		GroupID:    groupID,
+		ActorID:    req.OwnerID,
+		Action:     audit.ActionCreditRefund,
+		EntityType: audit.EntityMember,
+		EntityID:   memberEntityID(groupID, memberID),
+		Before:     map[string]any{"credit": m.Credit},
+		After:      map[string]any{"credit": m.Credit - req.Amount, "refund": req.Amount, "note": req.Note},
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.GetMemberCredit(ctx, groupID, memberID)
+}
+
+func findMember(g *Group, memberID string) *GroupMember {
+	for i := range g.Members {
+		if g.Members[i].MemberID == memberID {
+			return &g.Members[i]
+		}
+	}
+	return nil
+}
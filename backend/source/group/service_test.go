@@ -0,0 +1,167 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
+
+func TestResetPaymentForDueday(t *testing.T) {
+	const member = "100000000000000002"
+
+	tests := []struct {
+		name      string
+		amount    float64
+		members   int
+		credit    currency.Minor // the member's credit before the run
+		runs      int
+		wantBills int
+		wantDue   float64
+		wantPaid  float64
+	}{
+		{name: "one bill per member", amount: 400, members: 2, runs: 1, wantBills: 2, wantDue: 200},
+		{name: "a second run in the month issues nothing", amount: 400, members: 2, runs: 2, wantBills: 2, wantDue: 200},
+		{name: "thirds keep their satang", amount: 100, members: 3, runs: 1, wantBills: 3, wantDue: 33.33},
+		{name: "credit pays part of the bill", amount: 400, members: 2, credit: currency.ToMinor(50.5), runs: 2, wantBills: 2, wantDue: 200, wantPaid: 50.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGroup()
+			g.Amount = tt.amount
+			g.AmountPerMember = currency.ToMinor(tt.amount) / currency.Minor(tt.members)
+			for len(g.Members) < tt.members {
+				id := strconv.Itoa(100000000000000001 + len(g.Members))
+				g.Members = append(g.Members, GroupMember{MemberID: id, Status: MemberStatusActive})
+			}
+			store := &groupStore{g: g}
+			if tt.credit > 0 {
+				store.ledger = []LedgerEntry{{ID: 1, GroupID: g.ID, MemberID: member, Kind: LedgerCredit, Credit: tt.credit}}
+			}
+			s := NewService(store)
+
+			for i := 0; i < tt.runs; i++ {
+				if err := s.ResetPaymentForDueday(context.Background(), g.DueDay); err != nil {
+					t.Fatalf("run %d: %v", i+1, err)
+				}
+			}
+
+			if len(store.bills) != tt.wantBills {
+				t.Fatalf("bills = %d, want %d", len(store.bills), tt.wantBills)
+			}
+			var charges int
+			for _, e := range store.ledger {
+				if e.Kind == LedgerCharge {
+					charges++
+				}
+			}
+			if charges != tt.wantBills {
+				t.Errorf("ledger charges = %d, want %d", charges, tt.wantBills)
+			}
+
+			b, _ := store.GetBillByGroupMemberCycle(context.Background(), g.ID, member, store.bills[0].Year, store.bills[0].Month)
+			if b == nil {
+				t.Fatalf("no bill for %s", member)
+			}
+			if b.AmountDue != tt.wantDue || b.AmountPaid != tt.wantPaid {
+				t.Errorf("bill due %v, paid %v; want due %v, paid %v", b.AmountDue, b.AmountPaid, tt.wantDue, tt.wantPaid)
+			}
+			if tt.wantPaid > 0 && b.Status != bill.BillStatusPartiallyPaid {
+				t.Errorf("bill status = %s, want partially paid", b.Status)
+			}
+		})
+	}
+}
+
+func TestResetPaymentForDuedaySkipsInactiveMembers(t *testing.T) {
+	tests := []struct {
+		status MemberStatus
+		billed bool
+	}{
+		{MemberStatusActive, true},
+		{MemberStatusInvited, false},
+		{MemberStatusLeft, false},
+	}
+
+	const member = "100000000000000003"
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			g := testGroup()
+			g.Members = append(g.Members, GroupMember{MemberID: member, Status: tt.status})
+			store := &groupStore{g: g}
+			s := NewService(store)
+
+			if err := s.ResetPaymentForDueday(context.Background(), g.DueDay); err != nil {
+				t.Fatal(err)
+			}
+
+			var billed, charged bool
+			for _, b := range store.bills {
+				billed = billed || b.MemberID == member
+			}
+			for _, e := range store.ledger {
+				charged = charged || e.MemberID == member
+			}
+			if billed != tt.billed || charged != tt.billed {
+				t.Errorf("billed %v, charged %v; want %v", billed, charged, tt.billed)
+			}
+		})
+	}
+}
+
+func TestRecordManualPayment(t *testing.T) {
+	const (
+		owner  = "100000000000000001"
+		member = "100000000000000002"
+	)
+	errStoreDown := errors.New("store down")
+
+	tests := []struct {
+		name       string
+		amount     float64
+		failLedger error
+		wantErr    error
+		wantStatus bill.BillStatus
+		wantDept   currency.Minor
+	}{
+		{name: "pays the bill", amount: 200, wantStatus: bill.BillStatusVerified},
+		{name: "pays part of it", amount: 50.25, wantStatus: bill.BillStatusPartiallyPaid, wantDept: currency.ToMinor(149.75)},
+		{name: "a failed ledger write keeps the bill as it was", amount: 200, failLedger: errStoreDown, wantErr: errStoreDown, wantStatus: bill.BillStatusPending, wantDept: currency.ToMinor(200)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &groupStore{g: testGroup()}
+			s := NewService(store)
+			ctx := context.Background()
+			if err := s.ResetPaymentForDueday(ctx, store.g.DueDay); err != nil {
+				t.Fatal(err)
+			}
+			b, _ := store.GetBillByGroupMemberCycle(ctx, 1, member, store.bills[0].Year, store.bills[0].Month)
+			store.failLedger = tt.failLedger
+
+			_, _, err := s.RecordManualPayment(ctx, RecordPaymentRequest{OwnerID: owner, Method: Cash, Amount: tt.amount}, b.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RecordManualPayment = %v, want %v", err, tt.wantErr)
+			}
+
+			b, _ = store.GetBillByID(ctx, b.ID)
+			if b.Status != tt.wantStatus {
+				t.Errorf("bill status = %s, want %s", b.Status, tt.wantStatus)
+			}
+			payments, _ := store.GetPaymentsByBillID(ctx, b.ID)
+			if recorded := len(payments) > 0; recorded != (tt.wantErr == nil) {
+				t.Errorf("payments = %+v", payments)
+			}
+			g, _ := s.GetGroup(ctx, 1)
+			if m := findMember(g, member); m.Dept != tt.wantDept {
+				t.Errorf("dept = %v, want %v", m.Dept, tt.wantDept)
+			}
+		})
+	}
+}
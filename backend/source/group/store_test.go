@@ -0,0 +1,217 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
+
+var errGroupGone = errors.New("group is gone")
+
+// groupStore keeps one group and its bills, payments and ledger in memory;
+// the methods it doesn't override panic through the nil Store.
+type groupStore struct {
+	Store
+	g Group
+
+	bills    []bill.Bill
+	payments []bill.Payment
+	ledger   []LedgerEntry
+	events   int64
+	deleted  bool
+
+	failLedger error // SaveLedgerEntry fails with it
+
+	// interfere runs before a versioned write, standing in for a concurrent
+	// request that gets there first
+	interfere func(g *Group)
+}
+
+func (s *groupStore) GetGroup(ctx context.Context, id int64) (*Group, error) {
+	if s.deleted {
+		return nil, errGroupGone
+	}
+	g := s.g
+	g.Members = append([]GroupMember(nil), s.g.Members...)
+	g.PaymentAccounts = append([]PaymentAccount(nil), s.g.PaymentAccounts...)
+	return &g, nil
+}
+
+func (s *groupStore) DeleteGroup(ctx context.Context, id int64) error {
+	s.deleted = true
+	return nil
+}
+
+func (s *groupStore) GetGroupByDueday(ctx context.Context, dueDay int) ([]Group, error) {
+	g, _ := s.GetGroup(ctx, s.g.ID)
+	return []Group{*g}, nil
+}
+
+func (s *groupStore) UpdateGroupVersion(ctx context.Context, id, version int64, g Group) (bool, error) {
+	if s.interfere != nil {
+		s.interfere(&s.g)
+		s.g.Version++
+		s.interfere = nil
+	}
+	if s.g.Version != version {
+		return false, nil
+	}
+	g.Version = version + 1
+	s.g = g
+	return true, nil
+}
+
+func (s *groupStore) NextBillID(ctx context.Context) (int64, error) {
+	return int64(len(s.bills)) + 1, nil
+}
+
+func (s *groupStore) SaveBill(ctx context.Context, b bill.Bill) error {
+	s.bills = append(s.bills, b)
+	return nil
+}
+
+func (s *groupStore) GetBillByID(ctx context.Context, id int64) (*bill.Bill, error) {
+	for _, b := range s.bills {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+	return nil, bill.ErrBillNotFound
+}
+
+func (s *groupStore) GetBillByGroupMemberCycle(ctx context.Context, groupID int64, memberID string, year, month int) (*bill.Bill, error) {
+	for _, b := range s.bills {
+		if b.GroupID == groupID && b.MemberID == memberID && b.Year == year && b.Month == month && b.Kind == bill.BillKindRecurring {
+			return &b, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *groupStore) GetBillsByGroupID(ctx context.Context, groupID int64) ([]bill.Bill, error) {
+	return append([]bill.Bill(nil), s.bills...), nil
+}
+
+func (s *groupStore) UpdateBill(ctx context.Context, b bill.Bill) (*bill.Bill, error) {
+	for i := range s.bills {
+		if s.bills[i].ID == b.ID {
+			s.bills[i] = b
+			return &b, nil
+		}
+	}
+	return nil, bill.ErrBillNotFound
+}
+
+func (s *groupStore) NextPaymentID(ctx context.Context) (int64, error) {
+	return int64(len(s.payments)) + 1, nil
+}
+
+func (s *groupStore) SavePayment(ctx context.Context, p bill.Payment) error {
+	s.payments = append(s.payments, p)
+	return nil
+}
+
+func (s *groupStore) GetPaymentsByBillID(ctx context.Context, billID int64) ([]bill.Payment, error) {
+	var result []bill.Payment
+	for _, p := range s.payments {
+		if p.BillID == billID {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (s *groupStore) NextLedgerEntryID(ctx context.Context) (int64, error) {
+	return int64(len(s.ledger)) + 1, nil
+}
+
+func (s *groupStore) SaveLedgerEntry(ctx context.Context, e LedgerEntry) error {
+	if s.failLedger != nil {
+		return s.failLedger
+	}
+	s.ledger = append(s.ledger, e)
+	return nil
+}
+
+func (s *groupStore) GetLedgerEntries(ctx context.Context, groupID int64, memberID string) ([]LedgerEntry, error) {
+	var result []LedgerEntry
+	for _, e := range s.ledger {
+		if e.GroupID == groupID && e.MemberID == memberID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func (s *groupStore) GetLedgerBalances(ctx context.Context, groupID int64) (map[string]currency.Minor, error) {
+	balances := map[string]currency.Minor{}
+	for _, e := range s.ledger {
+		if e.GroupID == groupID {
+			balances[e.MemberID] += e.Debit - e.Credit
+		}
+	}
+	return balances, nil
+}
+
+func (s *groupStore) HasBillsOrLedger(ctx context.Context, groupID int64) (bool, error) {
+	return len(s.bills) > 0 || len(s.ledger) > 0, nil
+}
+
+func (s *groupStore) NextBillEventID(ctx context.Context) (int64, error) {
+	return s.events + 1, nil
+}
+
+// SaveBillEvent refuses events of bills that weren't saved first.
+func (s *groupStore) SaveBillEvent(ctx context.Context, e bill.BillEvent) error {
+	if _, err := s.GetBillByID(ctx, e.BillID); err != nil {
+		return err
+	}
+	s.events++
+	return nil
+}
+
+// RunInTx puts the group, bills, payments and ledger back when fn fails.
+func (s *groupStore) RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	g, _ := s.GetGroup(ctx, s.g.ID)
+	bills := slices.Clone(s.bills)
+	payments := slices.Clone(s.payments)
+	ledger := slices.Clone(s.ledger)
+
+	err := fn(ctx)
+	if err != nil {
+		s.g, s.bills, s.payments, s.ledger = *g, bills, payments, ledger
+	}
+	return err
+}
+
+// fixedRates quotes every currency at the same rate.
+type fixedRates float64
+
+func (r fixedRates) Rate(ctx context.Context, from, to string, at time.Time) (currency.Rate, error) {
+	return currency.Rate{From: from, To: to, Value: float64(r), Source: "test", AsOf: at}, nil
+}
+
+func testGroup() Group {
+	account := PaymentAccount{Method: PromptPay, Account: "0812345678", Preferred: true}
+	return Group{
+		ID:              1,
+		Name:            "Netflix",
+		Amount:          400,
+		AmountPerMember: currency.ToMinor(200),
+		DueDay:          5,
+		Members: []GroupMember{
+			{MemberID: "100000000000000001", Status: MemberStatusActive},
+			{MemberID: "100000000000000002", Status: MemberStatusActive},
+		},
+		DiscordGuildID:  "200000000000000001",
+		OwnerDiscordID:  "100000000000000001",
+		Payment:         account,
+		PaymentAccounts: []PaymentAccount{account},
+		Currency:        "THB",
+		Version:         3,
+	}
+}
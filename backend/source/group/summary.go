@@ -0,0 +1,167 @@
+package group
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+)
+
+// MemberSummary answers "what do I owe right now?" for one member across all
+// their groups.
+type MemberSummary struct {
+	MemberID    string          `json:"member_id"`
+	Groups      []GroupSummary  `json:"groups"`
+	Totals      []CurrencyTotal `json:"totals"` // one entry per currency
+	GeneratedAt time.Time       `json:"generated_at"`
+}
+
+type GroupSummary struct {
+	GroupID      int64          `json:"group_id"`
+	GroupName    string         `json:"group_name"`
+	Currency     string         `json:"currency"`
+	Status       MemberStatus   `json:"status"`
+	Outstanding  float64        `json:"outstanding"` // left to pay on open bills
+	Credit       currency.Minor `json:"credit"`
+	OpenBills    int            `json:"open_bills"`
+	NextDueDate  time.Time      `json:"next_due_date"`
+	LastPayment  *bill.Payment  `json:"last_payment,omitempty"`
+	OverdueBills []bill.Bill    `json:"overdue_bills"`
+}
+
+type CurrencyTotal struct {
+	Currency    string         `json:"currency"`
+	Outstanding float64        `json:"outstanding"`
+	Overdue     float64        `json:"overdue"`
+	Credit      currency.Minor `json:"credit"`
+}
+
+func (s *Service) GetMemberSummary(ctx context.Context, memberID string) (*MemberSummary, error) {
+	if memberID == "" {
+		return nil, ErrNoUserID
+	}
+
+	groups, err := s.memberGroups(ctx, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	bills, err := s.store.GetOpenBillsByMemberID(ctx, memberID)
+	if err != nil {
+		return nil, err
+	}
+	billsByGroup := map[int64][]bill.Bill{}
+	for _, b := range bills {
+		billsByGroup[b.GroupID] = append(billsByGroup[b.GroupID], b)
+	}
+
+	now := time.Now().UTC()
+	summary := &MemberSummary{
+		MemberID:    memberID,
+		Groups:      []GroupSummary{},
+		Totals:      []CurrencyTotal{},
+		GeneratedAt: now,
+	}
+	totals := map[string]*CurrencyTotal{}
+
+	for i := range groups {
+		g := &groups[i]
+		if err := s.applyBalances(ctx, g); err != nil {
+			return nil, err
+		}
+		m := findMember(g, memberID)
+		if m == nil {
+			continue
+		}
+
+		gs := GroupSummary{
+			GroupID:      g.ID,
+			GroupName:    g.Name,
+			Currency:     g.Currency,
+			Status:       m.Status,
+			Credit:       m.Credit,
+			NextDueDate:  nextDueDate(g.DueDay, now),
+			OverdueBills: []bill.Bill{},
+		}
+
+		t := totals[g.Currency]
+		if t == nil {
+			t = &CurrencyTotal{Currency: g.Currency}
+			totals[g.Currency] = t
+		}
+
+		for _, b := range billsByGroup[g.ID] {
+			owed := b.Outstanding()
+			if owed <= 0 {
+				continue
+			}
+			gs.OpenBills++
+			gs.Outstanding += owed
+			t.Outstanding += owed
+
+			if now.After(nextDueDate(g.DueDay, b.CreatedAt)) {
+				gs.OverdueBills = append(gs.OverdueBills, b)
+				t.Overdue += owed
+			}
+		}
+		t.Credit += m.Credit
+
+		last, err := s.store.GetLatestPayment(ctx, g.ID, memberID)
+		if err != nil {
+			return nil, err
+		}
+		gs.LastPayment = last
+
+		summary.Groups = append(summary.Groups, gs)
+	}
+
+	for _, t := range totals {
+		summary.Totals = append(summary.Totals, *t)
+	}
+	sort.Slice(summary.Totals, func(i, j int) bool {
+		return summary.Totals[i].Currency < summary.Totals[j].Currency
+	})
+
+	return summary, nil
+}
+
+// memberGroups pages through every group the member belongs to.
+func (s *Service) memberGroups(ctx context.Context, memberID string) ([]Group, error) {
+	q := GroupQuery{MemberID: memberID, SortBy: SortByID, Limit: maxGroupLimit}
+
+	var all []Group
+	for {
+		groups, err := s.store.ListGroups(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		if len(groups) <= q.Limit {
+			return append(all, groups...), nil
+		}
+
+		groups = groups[:q.Limit]
+		all = append(all, groups...)
+		last := groups[len(groups)-1]
+		q.After = &GroupCursor{Value: sortValue(last, SortByID), ID: last.ID}
+	}
+}
+
+// nextDueDate is the first due day strictly after t. Due days past the end of
+// a month fall on its last day.
+func nextDueDate(dueDay int, t time.Time) time.Time {
+	y, m, _ := t.Date()
+	for i := 0; i < 2; i++ {
+		d := dueDay
+		if last := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day(); d > last {
+			d = last
+		}
+		due := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		if due.After(t) {
+			return due
+		}
+		m++
+	}
+	return time.Date(y, m, dueDay, 0, 0, 0, 0, time.UTC)
+}
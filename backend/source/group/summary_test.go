@@ -0,0 +1,33 @@
+package group
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDueDate(t *testing.T) {
+	day := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name   string
+		dueDay int
+		from   time.Time
+		want   time.Time
+	}{
+		{name: "later this month", dueDay: 15, from: day(2024, 3, 2, 0), want: day(2024, 3, 15, 0)},
+		{name: "already passed this month", dueDay: 5, from: day(2024, 3, 20, 0), want: day(2024, 4, 5, 0)},
+		{name: "on the due day itself", dueDay: 5, from: day(2024, 3, 5, 9), want: day(2024, 4, 5, 0)},
+		{name: "short month", dueDay: 31, from: day(2024, 4, 2, 0), want: day(2024, 4, 30, 0)},
+		{name: "february of a leap year", dueDay: 30, from: day(2024, 2, 1, 0), want: day(2024, 2, 29, 0)},
+		{name: "into a short month", dueDay: 31, from: day(2023, 1, 31, 12), want: day(2023, 2, 28, 0)},
+		{name: "over the new year", dueDay: 1, from: day(2024, 12, 15, 0), want: day(2025, 1, 1, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextDueDate(tt.dueDay, tt.from); !got.Equal(tt.want) {
+				t.Errorf("nextDueDate(%d, %s) = %s, want %s", tt.dueDay, tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package group
+
+import (
+	"fmt"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/validate"
+)
+
+// accountFormats describes the account number each receiving method takes,
+// for the error a caller sees.
+var accountFormats = map[PaymentMethod]string{
+	BankAccount:      "10 to 12 digits",
+	PromptPay:        "a phone number of 10 digits starting with 0",
+	PromptPayNatID:   "a national ID of 13 digits",
+	PromptPayEWallet: "an e-wallet ID of 15 digits",
+}
+
+func (req CreateGroupRequest) Validate() error {
+	var v validate.Validator
+	validateSettings(&v, req.Name, req.Amount, req.DueDay, req.DiscordGuildID, req.OwnerDiscordID, req.Currency)
+	validatePaymentAccounts(&v, req.Payment, req.PaymentAccounts)
+	return v.Err()
+}
+
+// Validate checks a group's new settings. Members are managed through
+// invites, never by replacing the list.
+func (req UpdateGroupRequest) Validate() error {
+	var v validate.Validator
+	validateSettings(&v, req.Name, req.Amount, req.DueDay, req.DiscordGuildID, req.OwnerDiscordID, req.Currency)
+	validatePaymentAccounts(&v, req.Payment, req.PaymentAccounts)
+	return v.Err()
+}
+
+func (req InviteGroupRequest) Validate() error {
+	var v validate.Validator
+	v.Snowflake("owner_id", req.OwnerID)
+	v.Check(len(req.MemberIDs) > 0, "member_ids", ErrNoMembersProvided)
+	v.Snowflakes("member_ids", req.MemberIDs)
+	return v.Err()
+}
+
+func (req AcceptInviteRequest) Validate() error {
+	var v validate.Validator
+	v.Snowflake("user_id", req.UserID)
+	return v.Err()
+}
+
+func (req MarkAsPaidRequest) Validate() error {
+	var v validate.Validator
+	v.Check(req.Amount > 0, "amount", ErrInvalidPaymentAmount)
+	v.Check(req.BillID == nil || *req.BillID > 0, "bill_id", bill.ErrInvalidBillID)
+	return v.Err()
+}
+
+func (req RefundCreditRequest) Validate() error {
+	var v validate.Validator
+	v.Snowflake("owner_id", req.OwnerID)
+	v.Check(req.Amount > 0, "amount", ErrInvalidRefundAmount)
+	return v.Err()
+}
+
+func (req RecordPaymentRequest) Validate() error {
+	var v validate.Validator
+	v.Snowflake("owner_id", req.OwnerID)
+	v.Check(isManualPaymentMethod(req.Method), "method", ErrInvalidPaymentMethod)
+	v.Check(req.Amount > 0, "amount", ErrInvalidPaymentAmount)
+	return v.Err()
+}
+
+func (req BillActionRequest) Validate() error {
+	var v validate.Validator
+	v.Snowflake("owner_id", req.OwnerID)
+	return v.Err()
+}
+
+func (req AmendBillRequest) Validate() error {
+	var v validate.Validator
+	v.Snowflake("owner_id", req.OwnerID)
+	v.Check(req.AmountDue == nil || *req.AmountDue > 0, "amount_due", bill.ErrInvalidAmount)
+	return v.Err()
+}
+
+// validateSettings checks the fields create and update share.
+func validateSettings(v *validate.Validator, name string, amount float64, dueDay int, guildID, ownerID, cur string) {
+	v.Check(name != "", "name", ErrInvalidName)
+	v.Check(amount > 0, "amount", ErrInvalidAmount)
+	v.Check(dueDay >= 1 && dueDay <= 31, "due_day", ErrInvalidDueDay)
+	v.Snowflake("discord_guild_id", guildID)
+	v.Snowflake("owner_discord_id", ownerID)
+	if cur != "" {
+		_, err := currency.Normalize(cur)
+		v.Check(err == nil, "currency", currency.ErrInvalidCurrency)
+	}
+}
+
+// validatePaymentAccounts checks the accounts of a create/update request. A
+// request carrying only the legacy single Payment is checked as a list of
+// one.
+func validatePaymentAccounts(v *validate.Validator, single PaymentAccount, list []PaymentAccount) {
+	field := func(i int, name string) string {
+		return validate.Index("payment_accounts", i) + "." + name
+	}
+	accounts := list
+	if len(accounts) == 0 && single.Account != "" {
+		accounts = []PaymentAccount{single}
+		field = func(_ int, name string) string { return "payment." + name }
+	}
+	if len(accounts) == 0 {
+		v.Add("payment_accounts", ErrNoPaymentAccount)
+		return
+	}
+
+	seen := map[string]bool{}
+	preferred := 0
+	for i, a := range accounts {
+		if !isReceivingMethod(a.Method) {
+			v.Add(field(i, "method"), ErrInvalidPaymentMethod)
+			continue
+		}
+		if !validAccountNumber(a.Method, a.Account) {
+			v.Add(field(i, "account"), fmt.Errorf("%w: %s takes %s", ErrInvalidPaymentAccount, a.Method, accountFormats[a.Method]))
+			continue
+		}
+
+		key := string(a.Method) + ":" + AccountDigits(a.Method, a.Account)
+		v.Check(!seen[key], field(i, "account"), ErrDuplicatePaymentAccount)
+		seen[key] = true
+
+		if a.Preferred {
+			preferred++
+			v.Check(preferred == 1, field(i, "preferred"), ErrMultiplePreferred)
+		}
+	}
+}
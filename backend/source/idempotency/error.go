@@ -0,0 +1,10 @@
+package idempotency
+
+import "errors"
+
+var (
+	ErrInvalidKey       = errors.New("Idempotency-Key must be 1 to 255 printable ASCII characters")
+	ErrKeyReused        = errors.New("Idempotency-Key was already used for a different request")
+	ErrInProgress       = errors.New("a request with this Idempotency-Key is still running")
+	ErrInvalidRetention = errors.New("retention must be positive")
+)
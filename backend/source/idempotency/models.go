@@ -0,0 +1,27 @@
+package idempotency
+
+import "time"
+
+// Scope is who sent a key and where to. The same key sent by another actor,
+// or with another method or path, is a different key.
+type Scope struct {
+	ActorID string
+	Method  string
+	Route   string
+}
+
+// Record is a request made with an Idempotency-Key and, once it finished, the
+// response retries of it get back.
+type Record struct {
+	Key         string // the client's key within its Scope, see Scope.Key
+	Fingerprint string // of the request, see Fingerprint
+	Status      int    // 0 while the request is running
+	Header      map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+func (r *Record) Completed() bool {
+	return r.CompletedAt != nil
+}
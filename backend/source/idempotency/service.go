@@ -0,0 +1,152 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	// DefaultRetention is how long a key is remembered.
+	DefaultRetention = 24 * time.Hour
+
+	// staleAfter is when a request that never finished, e.g. because the
+	// server stopped, no longer blocks its key.
+	staleAfter = 5 * time.Minute
+
+	maxKeyLength = 255
+)
+
+// Store keeps records by Record.Key. GetIdempotencyKey returns nil, nil when
+// the key isn't stored.
+type Store interface {
+	ClaimIdempotencyKey(ctx context.Context, r Record) (bool, error)
+	GetIdempotencyKey(ctx context.Context, key string) (*Record, error)
+	CompleteIdempotencyKey(ctx context.Context, r Record) error
+	DeleteIdempotencyKey(ctx context.Context, key string) error
+	DeleteIdempotencyKeysBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type Service struct {
+	store     Store
+	retention time.Duration
+}
+
+func NewService(store Store) *Service {
+	return &Service{store: store, retention: DefaultRetention}
+}
+
+// SetRetention changes how long keys are remembered.
+func (s *Service) SetRetention(retention time.Duration) error {
+	if retention <= 0 {
+		return ErrInvalidRetention
+	}
+	s.retention = retention
+	return nil
+}
+
+// Fingerprint identifies a request, so a key sent again with a different
+// request is caught.
+func Fingerprint(method, uri, actorID string, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{method, uri, actorID} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Key is the stored key of the client's key sent within sc.
+func (sc Scope) Key(key string) string {
+	h := sha256.New()
+	for _, part := range []string{sc.ActorID, sc.Method, sc.Route, key} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin claims key within scope for a request. When the key was already used for the same
+// request and that one finished, its record comes back with replay set and
+// the response should be sent again instead of running the request.
+func (s *Service) Begin(ctx context.Context, scope Scope, key, fingerprint string, now time.Time) (rec *Record, replay bool, err error) {
+	if !validKey(key) {
+		return nil, false, ErrInvalidKey
+	}
+
+	key = scope.Key(key)
+	rec = &Record{Key: key, Fingerprint: fingerprint, CreatedAt: now}
+
+	// another attempt after clearing a key that expired or went stale, or
+	// that was released between the claim and the read
+	for attempt := 0; attempt < 3; attempt++ {
+		claimed, err := s.store.ClaimIdempotencyKey(ctx, *rec)
+		if err != nil {
+			return nil, false, err
+		}
+		if claimed {
+			return rec, false, nil
+		}
+
+		prev, err := s.store.GetIdempotencyKey(ctx, key)
+		if err != nil {
+			return nil, false, err
+		}
+		if prev == nil {
+			continue
+		}
+
+		expired := prev.CreatedAt.Before(now.Add(-s.retention))
+		stale := !prev.Completed() && prev.CreatedAt.Before(now.Add(-staleAfter))
+		if expired || stale {
+			if err := s.store.DeleteIdempotencyKey(ctx, key); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+
+		if prev.Fingerprint != fingerprint {
+			return nil, false, ErrKeyReused
+		}
+		if !prev.Completed() {
+			return nil, false, ErrInProgress
+		}
+		return prev, true, nil
+	}
+
+	return nil, false, ErrInProgress
+}
+
+// Complete stores the response of a request started with Begin.
+func (s *Service) Complete(ctx context.Context, rec *Record, status int, header map[string]string, body []byte, now time.Time) error {
+	rec.Status = status
+	rec.Header = header
+	rec.Body = body
+	rec.CompletedAt = &now
+	return s.store.CompleteIdempotencyKey(ctx, *rec)
+}
+
+// Release forgets the key of a request that failed on the server's side, so
+// the client can retry it with the same key.
+func (s *Service) Release(ctx context.Context, rec *Record) error {
+	return s.store.DeleteIdempotencyKey(ctx, rec.Key)
+}
+
+// Prune deletes keys older than the retention and returns how many went.
+func (s *Service) Prune(ctx context.Context, now time.Time) (int64, error) {
+	return s.store.DeleteIdempotencyKeysBefore(ctx, now.Add(-s.retention))
+}
+
+func validKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,260 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	records map[string]Record
+
+	// releaseAfterClaim drops the key once a claim on it fails, like a
+	// request that releases it just before it is read
+	releaseAfterClaim bool
+}
+
+func (s *memStore) ClaimIdempotencyKey(ctx context.Context, r Record) (bool, error) {
+	if _, ok := s.records[r.Key]; ok {
+		if s.releaseAfterClaim {
+			delete(s.records, r.Key)
+			s.releaseAfterClaim = false
+		}
+		return false, nil
+	}
+	s.records[r.Key] = r
+	return true, nil
+}
+
+func (s *memStore) GetIdempotencyKey(ctx context.Context, key string) (*Record, error) {
+	r, ok := s.records[key]
+	if !ok {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+func (s *memStore) CompleteIdempotencyKey(ctx context.Context, r Record) error {
+	s.records[r.Key] = r
+	return nil
+}
+
+func (s *memStore) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	delete(s.records, key)
+	return nil
+}
+
+func (s *memStore) DeleteIdempotencyKeysBefore(ctx context.Context, t time.Time) (int64, error) {
+	var n int64
+	for key, r := range s.records {
+		if r.CreatedAt.Before(t) {
+			delete(s.records, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+var testScope = Scope{ActorID: "100000000000000001", Method: "POST", Route: "/groups"}
+
+func TestBegin(t *testing.T) {
+	start := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// prev is what happened with the key before, nil when it is new
+		prev        func(s *Service)
+		scope       Scope // testScope when zero
+		released    bool  // the key goes between the failed claim and the read
+		fingerprint string
+		at          time.Time
+		wantReplay  bool
+		wantErr     error
+	}{
+		{name: "new key", fingerprint: "a", at: start},
+		{
+			name:        "same request after it finished",
+			prev:        completed("a", start),
+			fingerprint: "a",
+			at:          start.Add(time.Hour),
+			wantReplay:  true,
+		},
+		{
+			name:        "different request",
+			prev:        completed("a", start),
+			fingerprint: "b",
+			at:          start.Add(time.Hour),
+			wantErr:     ErrKeyReused,
+		},
+		{
+			name:        "still running",
+			prev:        begun("a", start),
+			fingerprint: "a",
+			at:          start.Add(time.Minute),
+			wantErr:     ErrInProgress,
+		},
+		{
+			name:        "running too long to still be alive",
+			prev:        begun("a", start),
+			fingerprint: "a",
+			at:          start.Add(staleAfter + time.Second),
+		},
+		{
+			name:        "expired",
+			prev:        completed("a", start),
+			fingerprint: "b",
+			at:          start.Add(DefaultRetention + time.Second),
+		},
+		{
+			name: "released after a failure",
+			prev: func(s *Service) {
+				rec, _, err := s.Begin(context.Background(), testScope, "k", "a", start)
+				if err != nil {
+					panic(err)
+				}
+				if err := s.Release(context.Background(), rec); err != nil {
+					panic(err)
+				}
+			},
+			fingerprint: "a",
+			at:          start.Add(time.Second),
+		},
+		{
+			name:        "released while claiming",
+			prev:        begun("a", start),
+			released:    true,
+			fingerprint: "a",
+			at:          start.Add(time.Second),
+		},
+		{
+			name:        "another actor's key",
+			prev:        completed("a", start),
+			scope:       Scope{ActorID: "100000000000000002", Method: "POST", Route: "/groups"},
+			fingerprint: "b",
+			at:          start.Add(time.Hour),
+		},
+		{
+			name:        "another route",
+			prev:        completed("a", start),
+			scope:       Scope{ActorID: testScope.ActorID, Method: "POST", Route: "/groups/1/invite"},
+			fingerprint: "b",
+			at:          start.Add(time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memStore{records: map[string]Record{}}
+			s := NewService(store)
+			if tt.prev != nil {
+				tt.prev(s)
+			}
+			store.releaseAfterClaim = tt.released
+
+			scope := tt.scope
+			if scope == (Scope{}) {
+				scope = testScope
+			}
+			rec, replay, err := s.Begin(context.Background(), scope, "k", tt.fingerprint, tt.at)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Begin = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if replay != tt.wantReplay {
+				t.Fatalf("replay = %v, want %v", replay, tt.wantReplay)
+			}
+
+			if replay {
+				if rec.Status != 201 || string(rec.Body) != `{"id":1}` || rec.Header["Location"] != "/groups/1" {
+					t.Errorf("replayed record = %+v", rec)
+				}
+				return
+			}
+			if rec.Completed() || rec.Fingerprint != tt.fingerprint || !rec.CreatedAt.Equal(tt.at) {
+				t.Errorf("claimed record = %+v", rec)
+			}
+		})
+	}
+}
+
+// begun leaves the key claimed by a request that hasn't finished.
+func begun(fingerprint string, at time.Time) func(s *Service) {
+	return func(s *Service) {
+		if _, _, err := s.Begin(context.Background(), testScope, "k", fingerprint, at); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// completed leaves the key with a stored response.
+func completed(fingerprint string, at time.Time) func(s *Service) {
+	return func(s *Service) {
+		rec, _, err := s.Begin(context.Background(), testScope, "k", fingerprint, at)
+		if err != nil {
+			panic(err)
+		}
+		header := map[string]string{"Location": "/groups/1"}
+		if err := s.Complete(context.Background(), rec, 201, header, []byte(`{"id":1}`), at.Add(time.Second)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestBeginRejectsInvalidKeys(t *testing.T) {
+	s := NewService(&memStore{records: map[string]Record{}})
+	for _, key := range []string{"", strings.Repeat("k", maxKeyLength+1), "tab\tkey", "ключ"} {
+		if _, _, err := s.Begin(context.Background(), testScope, key, "a", time.Now()); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Begin(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if _, _, err := s.Begin(context.Background(), testScope, strings.Repeat("k", maxKeyLength), "a", time.Now()); err != nil {
+		t.Errorf("Begin with a key of the maximum length: %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	store := &memStore{records: map[string]Record{
+		"old": {Key: "old", CreatedAt: now.Add(-2 * time.Hour)},
+		"new": {Key: "new", CreatedAt: now.Add(-30 * time.Minute)},
+	}}
+	s := NewService(store)
+	if err := s.SetRetention(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := s.Prune(context.Background(), now)
+	if err != nil || n != 1 {
+		t.Fatalf("Prune = %d, %v; want 1", n, err)
+	}
+	if _, ok := store.records["new"]; !ok || len(store.records) != 1 {
+		t.Errorf("left %v", store.records)
+	}
+
+	if err := s.SetRetention(0); !errors.Is(err, ErrInvalidRetention) {
+		t.Errorf("SetRetention(0) = %v", err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint("POST", "/groups", "1", []byte(`{}`))
+	for _, other := range []string{
+		Fingerprint("PUT", "/groups", "1", []byte(`{}`)),
+		Fingerprint("POST", "/groups/1", "1", []byte(`{}`)),
+		Fingerprint("POST", "/groups", "2", []byte(`{}`)),
+		Fingerprint("POST", "/groups", "1", []byte(`{"a":1}`)),
+		// parts are separated, so shifting bytes between them changes it
+		Fingerprint("POST", "/groups1", "", []byte(`{}`)),
+	} {
+		if other == base {
+			t.Errorf("different requests share fingerprint %s", base)
+		}
+	}
+	if again := Fingerprint("POST", "/groups", "1", []byte(`{}`)); again != base {
+		t.Errorf("fingerprint is not stable")
+	}
+}
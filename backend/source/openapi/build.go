@@ -0,0 +1,195 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const contentJSON = "application/json"
+
+var pathParamPattern = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// PathParamNames returns the names of the {params} in a route pattern, in
+// order.
+func PathParamNames(path string) []string {
+	var names []string
+	for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// Build describes endpoints as an OpenAPI document. Every operation answers
+// errors with errorBody, e.g. the API error envelope.
+func Build(info Info, errorBody any, endpoints []Endpoint) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+	}
+	s := newSchemas()
+
+	errorSchema, err := s.of(errorBody)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]bool{}
+	for _, e := range endpoints {
+		if ids[e.ID] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateOperation, e.ID)
+		}
+		ids[e.ID] = true
+
+		op, err := buildOperation(s, e)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", e.Method, e.Path, err)
+		}
+		op.Responses["default"] = &Response{
+			Description: "error",
+			Content:     map[string]*MediaType{contentJSON: {Schema: errorSchema}},
+		}
+
+		item := doc.Paths[e.Path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[e.Path] = item
+		}
+		method := strings.ToLower(e.Method)
+		if (*item)[method] != nil {
+			return nil, fmt.Errorf("%w: %s %s", ErrDuplicateOperation, e.Method, e.Path)
+		}
+		(*item)[method] = op
+	}
+
+	doc.Components.Schemas = s.components
+	return doc, nil
+}
+
+func buildOperation(s *schemas, e Endpoint) (*Operation, error) {
+	op := &Operation{
+		OperationID: e.ID,
+		Summary:     e.Summary,
+		Responses:   map[string]*Response{},
+	}
+	if e.Tag != "" {
+		op.Tags = []string{e.Tag}
+	}
+
+	names := PathParamNames(e.Path)
+	if len(names) != len(e.PathParams) {
+		return nil, ErrPathParams
+	}
+	for i, p := range e.PathParams {
+		if p.Name != names[i] {
+			return nil, fmt.Errorf("%w: %s is not %s", ErrPathParams, p.Name, names[i])
+		}
+		param, err := buildParam(s, p, "path")
+		if err != nil {
+			return nil, err
+		}
+		param.Required = true
+		op.Parameters = append(op.Parameters, param)
+	}
+	for _, p := range e.Query {
+		param, err := buildParam(s, p, "query")
+		if err != nil {
+			return nil, err
+		}
+		op.Parameters = append(op.Parameters, param)
+	}
+	for _, p := range e.Headers {
+		param, err := buildParam(s, p, "header")
+		if err != nil {
+			return nil, err
+		}
+		op.Parameters = append(op.Parameters, param)
+	}
+
+	body, err := buildRequestBody(s, e)
+	if err != nil {
+		return nil, err
+	}
+	op.RequestBody = body
+
+	res := &Response{Description: http.StatusText(e.Status)}
+	if e.Response != nil || len(e.Downloads) > 0 {
+		res.Content = map[string]*MediaType{}
+	}
+	if e.Response != nil {
+		schema, err := s.of(e.Response)
+		if err != nil {
+			return nil, err
+		}
+		res.Content[contentJSON] = &MediaType{Schema: schema}
+	}
+	for _, ct := range e.Downloads {
+		res.Content[ct] = &MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+	}
+	op.Responses[strconv.Itoa(e.Status)] = res
+
+	return op, nil
+}
+
+func buildParam(s *schemas, p Param, in string) (Parameter, error) {
+	schema, err := s.of(p.Type)
+	if err != nil {
+		return Parameter{}, err
+	}
+	return Parameter{
+		Name:        p.Name,
+		In:          in,
+		Description: p.Description,
+		Required:    p.Required,
+		Schema:      schema,
+	}, nil
+}
+
+func buildRequestBody(s *schemas, e Endpoint) (*RequestBody, error) {
+	if e.Body == nil && len(e.Form) == 0 && e.File == "" && e.RawBody == "" {
+		return nil, nil
+	}
+
+	body := &RequestBody{Required: true, Content: map[string]*MediaType{}}
+
+	if e.Body != nil {
+		schema, err := s.of(e.Body)
+		if err != nil {
+			return nil, err
+		}
+		body.Content[contentJSON] = &MediaType{Schema: schema}
+	}
+
+	if len(e.Form) > 0 || e.File != "" {
+		form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for _, p := range e.Form {
+			schema, err := s.of(p.Type)
+			if err != nil {
+				return nil, err
+			}
+			if p.Description != "" {
+				schema.Description = p.Description
+			}
+			form.Properties[p.Name] = schema
+			if p.Required {
+				form.Required = append(form.Required, p.Name)
+			}
+		}
+		if e.File != "" {
+			form.Properties[e.File] = &Schema{Type: "string", Format: "binary"}
+			if e.NeedsFile {
+				form.Required = append(form.Required, e.File)
+			}
+		}
+		body.Content["multipart/form-data"] = &MediaType{Schema: form}
+	}
+
+	if e.RawBody != "" {
+		body.Content[e.RawBody] = &MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+	}
+
+	return body, nil
+}
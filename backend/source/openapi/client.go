@@ -0,0 +1,238 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// GenerateClient writes Go methods on *Client, one per endpoint, for a
+// package named pkg. The package supplies Client and its do and doJSON
+// helpers itself; request and response types are the model types the
+// endpoints were described with.
+func GenerateClient(pkg, command string, endpoints []Endpoint) ([]byte, error) {
+	g := &clientGen{imports: map[string]string{"context": "context"}}
+
+	var methods bytes.Buffer
+	for _, e := range endpoints {
+		if err := g.method(&methods, e); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", e.Method, e.Path, err)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by %s; DO NOT EDIT.\n\n", command)
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	out.WriteString("import (\n")
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		// standard library first
+		if std := isStdlib(paths[i]); std != isStdlib(paths[j]) {
+			return std
+		}
+		return paths[i] < paths[j]
+	})
+	for i, p := range paths {
+		if i > 0 && isStdlib(p) != isStdlib(paths[i-1]) {
+			out.WriteString("\n")
+		}
+		if name := g.imports[p]; name != path.Base(p) {
+			fmt.Fprintf(&out, "\t%s %q\n", name, p)
+		} else {
+			fmt.Fprintf(&out, "\t%q\n", p)
+		}
+	}
+	out.WriteString(")\n")
+	out.Write(methods.Bytes())
+
+	return format.Source(out.Bytes())
+}
+
+type clientGen struct {
+	imports map[string]string // import path -> package name
+}
+
+func (g *clientGen) method(w *bytes.Buffer, e Endpoint) error {
+	args := []string{"ctx context.Context"}
+	for _, p := range e.PathParams {
+		t, err := g.typeName(reflect.TypeOf(p.Type))
+		if err != nil {
+			return err
+		}
+		args = append(args, goIdent(p.Name)+" "+t)
+	}
+
+	query := "nil"
+	if len(e.Query) > 0 {
+		g.imports["net/url"] = "url"
+		args = append(args, "query url.Values")
+		query = "query"
+	}
+
+	var call string
+	switch {
+	case e.Body != nil:
+		t, err := g.typeName(reflect.TypeOf(e.Body))
+		if err != nil {
+			return err
+		}
+		args = append(args, "body "+t)
+		call = fmt.Sprintf("c.doJSON(ctx, %q, path, %s, body, %%s)", e.Method, query)
+	case len(e.Form) > 0 || e.File != "" || e.RawBody != "":
+		g.imports["io"] = "io"
+		args = append(args, "body io.Reader", "contentType string")
+		call = fmt.Sprintf("c.do(ctx, %q, path, %s, body, contentType, %%s)", e.Method, query)
+	default:
+		call = fmt.Sprintf("c.do(ctx, %q, path, %s, nil, \"\", %%s)", e.Method, query)
+	}
+
+	var result, outDecl, outArg, ret string
+	switch {
+	case e.Response != nil:
+		t := reflect.TypeOf(e.Response)
+		name, err := g.typeName(t)
+		if err != nil {
+			return err
+		}
+		outDecl = "var out " + name
+		outArg = "&out"
+		if t.Kind() == reflect.Struct {
+			result, ret = "*"+name, "&out"
+		} else {
+			result, ret = name, "out"
+		}
+	case len(e.Downloads) > 0:
+		outDecl, outArg = "var out []byte", "&out"
+		result, ret = "[]byte", "out"
+	default:
+		outArg = "nil"
+	}
+
+	fmt.Fprintf(w, "\n// %s calls %s %s.\n", e.ID, e.Method, e.Path)
+	if e.Summary != "" {
+		fmt.Fprintf(w, "//\n// %s\n", e.Summary)
+	}
+	if len(e.Query) > 0 {
+		names := make([]string, len(e.Query))
+		for i, p := range e.Query {
+			names[i] = p.Name
+		}
+		fmt.Fprintf(w, "// Query parameters: %s.\n", strings.Join(names, ", "))
+	}
+
+	if result == "" {
+		fmt.Fprintf(w, "func (c *Client) %s(%s) error {\n", e.ID, strings.Join(args, ", "))
+	} else {
+		fmt.Fprintf(w, "func (c *Client) %s(%s) (%s, error) {\n", e.ID, strings.Join(args, ", "), result)
+	}
+
+	pathExpr, err := g.pathExpr(e)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\tpath := %s\n", pathExpr)
+
+	if result == "" {
+		fmt.Fprintf(w, "\treturn "+call+"\n}\n", outArg)
+		return nil
+	}
+
+	fmt.Fprintf(w, "\t%s\n", outDecl)
+	fmt.Fprintf(w, "\tif err := "+call+"; err != nil {\n\t\treturn nil, err\n\t}\n", outArg)
+	fmt.Fprintf(w, "\treturn %s, nil\n}\n", ret)
+	return nil
+}
+
+// pathExpr builds the Go expression for the request path.
+func (g *clientGen) pathExpr(e Endpoint) (string, error) {
+	kinds := map[string]reflect.Kind{}
+	for _, p := range e.PathParams {
+		kinds[p.Name] = reflect.TypeOf(p.Type).Kind()
+	}
+
+	var parts []string
+	last := 0
+	for _, loc := range pathParamPattern.FindAllStringSubmatchIndex(e.Path, -1) {
+		if lit := e.Path[last:loc[0]]; lit != "" {
+			parts = append(parts, fmt.Sprintf("%q", lit))
+		}
+		name := e.Path[loc[2]:loc[3]]
+		ident := goIdent(name)
+
+		switch kinds[name] {
+		case reflect.String:
+			g.imports["net/url"] = "url"
+			parts = append(parts, "url.PathEscape("+ident+")")
+		case reflect.Int64:
+			g.imports["strconv"] = "strconv"
+			parts = append(parts, "strconv.FormatInt("+ident+", 10)")
+		case reflect.Int, reflect.Int32:
+			g.imports["strconv"] = "strconv"
+			parts = append(parts, "strconv.FormatInt(int64("+ident+"), 10)")
+		default:
+			return "", fmt.Errorf("%w: path parameter %s", ErrUnsupportedType, name)
+		}
+		last = loc[1]
+	}
+	if tail := e.Path[last:]; tail != "" {
+		parts = append(parts, fmt.Sprintf("%q", tail))
+	}
+
+	return strings.Join(parts, " + "), nil
+}
+
+// typeName spells t in Go, importing the packages it needs.
+func (g *clientGen) typeName(t reflect.Type) (string, error) {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name(), nil
+		}
+		qualified := t.String()
+		pkgName := qualified[:strings.LastIndex(qualified, ".")]
+		g.imports[t.PkgPath()] = pkgName
+		return qualified, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "any", nil
+		}
+	case reflect.Pointer:
+		elem, err := g.typeName(t.Elem())
+		return "*" + elem, err
+	case reflect.Slice:
+		elem, err := g.typeName(t.Elem())
+		return "[]" + elem, err
+	case reflect.Map:
+		key, err := g.typeName(t.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := g.typeName(t.Elem())
+		return "map[" + key + "]" + elem, err
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedType, t)
+}
+
+func isStdlib(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// goIdent turns a path parameter such as GroupID or id into a Go parameter
+// name.
+func goIdent(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
@@ -0,0 +1,9 @@
+package openapi
+
+import "errors"
+
+var (
+	ErrDuplicateOperation = errors.New("operation is described twice")
+	ErrPathParams         = errors.New("path parameters don't match the path")
+	ErrUnsupportedType    = errors.New("type has no JSON schema")
+)
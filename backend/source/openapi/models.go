@@ -0,0 +1,103 @@
+package openapi
+
+// Version is the OpenAPI version of the documents built here.
+const Version = "3.0.3"
+
+// Document is an OpenAPI 3 document, reduced to the parts this API uses.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps a lower case HTTP method to its operation.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query or header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema is a JSON schema as OpenAPI 3.0 understands it. A schema with Ref
+// set points at Components.Schemas and has no other field.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+// Param is a path, query, header or form parameter of an Endpoint.
+type Param struct {
+	Name        string
+	Type        any // zero value of the Go type, e.g. int64(0) or ""
+	Description string
+	Required    bool
+}
+
+// Endpoint describes one route. Go types are given as zero values; their
+// schemas are derived from the types by reflection.
+type Endpoint struct {
+	Method  string // GET, POST...
+	Path    string // chi pattern, e.g. /groups/{id}
+	ID      string // operationId, also the name of the generated client method
+	Tag     string
+	Summary string
+
+	PathParams []Param
+	Query      []Param
+	Headers    []Param // request headers; the generated client leaves them to the caller
+
+	Body      any     // JSON request body
+	Form      []Param // multipart/form-data fields
+	File      string  // multipart file field
+	NeedsFile bool    // whether File must be sent
+	RawBody   string  // content type of a raw request body, e.g. text/csv
+
+	Status    int      // status of a successful call
+	Response  any      // JSON response, nil when there is none
+	Downloads []string // content types of non-JSON responses
+}
@@ -0,0 +1,215 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	typedType      = reflect.TypeOf((*typed)(nil)).Elem()
+)
+
+// typed is implemented by types whose JSON form differs from their Go kind,
+// e.g. an integer amount sent as a decimal number.
+type typed interface {
+	OpenAPIType() (typ, format string)
+}
+
+// schemas turns Go types into schemas the way encoding/json encodes them.
+// Named structs are added to components once and referenced from then on.
+type schemas struct {
+	components map[string]*Schema
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]*Schema{}}
+}
+
+// ComponentName is the name a named Go type gets under components/schemas,
+// e.g. "group.Group".
+func ComponentName(t reflect.Type) string {
+	return t.String()
+}
+
+func (s *schemas) of(v any) (*Schema, error) {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) (*Schema, error) {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	case rawMessageType:
+		return &Schema{Description: "any JSON value"}, nil
+	}
+	if t.Kind() != reflect.Pointer && t.Implements(typedType) {
+		typ, format := reflect.Zero(t).Interface().(typed).OpenAPIType()
+		return &Schema{Type: typ, Format: format}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}, nil
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}, nil
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Interface:
+		return &Schema{Description: "any JSON value"}, nil
+	case reflect.Pointer:
+		inner, err := s.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return nullable(inner), nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := s.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String && !isInteger(t.Key().Kind()) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, t)
+		}
+		values, err := s.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		return s.structRef(t)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, t)
+}
+
+func (s *schemas) structRef(t reflect.Type) (*Schema, error) {
+	if t.Name() == "" {
+		return s.structSchema(t)
+	}
+
+	name := ComponentName(t)
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := s.components[name]; ok {
+		return ref, nil
+	}
+
+	// registered first so self-referencing types terminate
+	placeholder := &Schema{}
+	s.components[name] = placeholder
+
+	built, err := s.structSchema(t)
+	if err != nil {
+		delete(s.components, name)
+		return nil, err
+	}
+	*placeholder = *built
+
+	return ref, nil
+}
+
+func (s *schemas) structSchema(t reflect.Type) (*Schema, error) {
+	out := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	if err := s.addFields(out, t); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// addFields adds the JSON fields of struct t, flattening embedded structs
+// like encoding/json does.
+func (s *schemas) addFields(out *Schema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, omitEmpty, skip := jsonName(f)
+		if skip {
+			continue
+		}
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				if err := s.addFields(out, ft); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fs, err := s.schema(ft)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t, f.Name, err)
+		}
+
+		// nil slices and maps encode as null too
+		if !omitEmpty && (ft.Kind() == reflect.Slice || ft.Kind() == reflect.Map) && ft != rawMessageType {
+			fs = nullable(fs)
+		}
+
+		out.Properties[name] = fs
+		if !omitEmpty {
+			out.Required = append(out.Required, name)
+		}
+	}
+	return nil
+}
+
+// jsonName reads the json tag of a field.
+func jsonName(f reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty, false
+}
+
+// nullable marks a schema as accepting null. A reference can't carry other
+// keywords in OpenAPI 3.0, so it is wrapped.
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{Nullable: true, AllOf: []*Schema{s}}
+	}
+	cp := *s
+	cp.Nullable = true
+	return &cp
+}
+
+func isInteger(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package portable
+
+import "errors"
+
+var (
+	ErrInvalidGroupID     = errors.New("invalid group_id")
+	ErrInvalidDocument    = errors.New("not a subShare group export")
+	ErrUnsupportedVersion = errors.New("unsupported export version")
+	ErrInvalidGroup       = errors.New("export has an invalid group")
+	ErrUnknownBill        = errors.New("export refers to a bill it doesn't contain")
+	ErrUnknownExpense     = errors.New("export refers to an expense it doesn't contain")
+	ErrDuplicateRecordID  = errors.New("export lists the same id twice")
+	ErrInvalidBillRecord  = errors.New("export has an invalid bill")
+	ErrSlipAlreadyCounted = errors.New("export has a slip this server already counted")
+)
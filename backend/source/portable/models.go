@@ -0,0 +1,58 @@
+package portable
+
+import (
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/expense"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+const (
+	DocumentFormat = "subshare.group"
+
+	// DocumentVersion changes whenever a field is added, renamed or given a
+	// new meaning; imports only accept documents of this version.
+	DocumentVersion = 1
+)
+
+// Document is a whole group as moved between deployments. IDs in it are the
+// ones of the exporting deployment; Import hands out new ones.
+type Document struct {
+	Format     string              `json:"format"`
+	Version    int                 `json:"version"`
+	ExportedAt time.Time           `json:"exported_at"`
+	Group      group.Group         `json:"group"`
+	Expenses   []expense.Expense   `json:"expenses"`
+	Bills      []BillRecord        `json:"bills"`
+	Ledger     []group.LedgerEntry `json:"ledger"` // balance is not imported, it's recomputed
+}
+
+// BillRecord is a bill with its slip proof, payments and history.
+type BillRecord struct {
+	bill.Bill
+	Payments []PaymentRecord  `json:"payments"`
+	Events   []bill.BillEvent `json:"events"`
+}
+
+// PaymentRecord carries the attachment, which bill.Payment leaves out of JSON.
+type PaymentRecord struct {
+	bill.Payment
+	Attachment []byte `json:"attachment,omitempty"` // base64 in JSON
+}
+
+// ImportRequest recreates a group from a Document. GuildID, when set, moves
+// the group to another Discord guild.
+type ImportRequest struct {
+	Document Document
+	GuildID  string
+}
+
+// ImportResult maps the document's IDs to the new ones.
+type ImportResult struct {
+	Group    *group.Group    `json:"group"`
+	BillIDs  map[int64]int64 `json:"bill_ids"`
+	Expenses map[int64]int64 `json:"expense_ids"`
+	Payments int             `json:"payments"`
+	Ledger   int             `json:"ledger_entries"`
+}
@@ -0,0 +1,361 @@
+package portable
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/expense"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+type Store interface {
+	NextGroupID(ctx context.Context) (int64, error)
+	SaveGroup(ctx context.Context, g group.Group) error
+	ListBills(ctx context.Context, q bill.BillQuery) ([]bill.Bill, error)
+	NextBillID(ctx context.Context) (int64, error)
+	SaveBill(ctx context.Context, b bill.Bill) error
+	GetPaymentsByBillID(ctx context.Context, billID int64) ([]bill.Payment, error)
+	GetPaymentAttachment(ctx context.Context, id int64) (*bill.Payment, error)
+	NextPaymentID(ctx context.Context) (int64, error)
+	SavePayment(ctx context.Context, p bill.Payment) error
+	HasAcceptedPaymentReference(ctx context.Context, reference string) (bool, error)
+	GetBillEvents(ctx context.Context, billID int64) ([]bill.BillEvent, error)
+	NextBillEventID(ctx context.Context) (int64, error)
+	SaveBillEvent(ctx context.Context, e bill.BillEvent) error
+	GetLedgerEntries(ctx context.Context, groupID int64, memberID string) ([]group.LedgerEntry, error)
+	NextLedgerEntryID(ctx context.Context) (int64, error)
+	SaveLedgerEntry(ctx context.Context, e group.LedgerEntry) error
+	GetExpensesByGroupID(ctx context.Context, groupID int64) ([]expense.Expense, error)
+	NextExpenseID(ctx context.Context) (int64, error)
+	SaveExpense(ctx context.Context, e expense.Expense) error
+}
+
+type Service struct {
+	store  Store
+	groups *group.Service
+	audit  *audit.Service
+}
+
+func NewService(store Store, groups *group.Service) *Service {
+	return &Service{store: store, groups: groups}
+}
+
+// SetAuditLog makes the service record every group it imports.
+func (s *Service) SetAuditLog(a *audit.Service) {
+	s.audit = a
+}
+
+const exportPageSize = 200
+
+// Export writes the group, its expenses, every bill with proofs, payments,
+// attachments and history, and the members' ledgers into one document.
+func (s *Service) Export(ctx context.Context, groupID int64) (*Document, error) {
+	if groupID <= 0 {
+		return nil, ErrInvalidGroupID
+	}
+
+	g, err := s.groups.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		Format:     DocumentFormat,
+		Version:    DocumentVersion,
+		ExportedAt: time.Now().UTC(),
+		Group:      *g,
+		Bills:      []BillRecord{},
+		Ledger:     []group.LedgerEntry{},
+	}
+
+	if doc.Expenses, err = s.store.GetExpensesByGroupID(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	q := bill.BillQuery{GroupID: groupID, Limit: exportPageSize, IncludeProof: true}
+	for {
+		bills, err := s.store.ListBills(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		more := len(bills) > q.Limit
+		if more {
+			bills = bills[:q.Limit]
+		}
+
+		for _, b := range bills {
+			rec, err := s.exportBill(ctx, b)
+			if err != nil {
+				return nil, err
+			}
+			doc.Bills = append(doc.Bills, *rec)
+		}
+
+		if !more {
+			break
+		}
+		last := bills[len(bills)-1]
+		q.After = &bill.BillCursor{Period: last.Year*100 + last.Month, ID: last.ID}
+	}
+
+	for _, m := range g.Members {
+		entries, err := s.store.GetLedgerEntries(ctx, groupID, m.MemberID)
+		if err != nil {
+			return nil, err
+		}
+		doc.Ledger = append(doc.Ledger, entries...)
+	}
+	sort.Slice(doc.Ledger, func(i, j int) bool {
+		return doc.Ledger[i].ID < doc.Ledger[j].ID
+	})
+
+	return doc, nil
+}
+
+func (s *Service) exportBill(ctx context.Context, b bill.Bill) (*BillRecord, error) {
+	rec := &BillRecord{Bill: b, Payments: []PaymentRecord{}}
+
+	payments, err := s.store.GetPaymentsByBillID(ctx, b.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		pr := PaymentRecord{Payment: p}
+		if p.AttachmentName != "" || p.AttachmentType != "" {
+			withAttachment, err := s.store.GetPaymentAttachment(ctx, p.ID)
+			if err != nil {
+				return nil, err
+			}
+			pr.Attachment = withAttachment.Attachment
+		}
+		rec.Payments = append(rec.Payments, pr)
+	}
+
+	if rec.Events, err = s.store.GetBillEvents(ctx, b.ID); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// Import recreates a document as a new group. The whole document is checked
+// before anything is written.
+func (s *Service) Import(ctx context.Context, req ImportRequest) (*ImportResult, error) {
+	doc := &req.Document
+	if err := validateDocument(doc); err != nil {
+		return nil, err
+	}
+
+	// a slip counts once per server, e.g. importing a group next to itself
+	for _, ref := range slipReferences(doc) {
+		used, err := s.store.HasAcceptedPaymentReference(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if used {
+			return nil, fmt.Errorf("%w: %s", ErrSlipAlreadyCounted, ref)
+		}
+	}
+
+	g := doc.Group
+	if req.GuildID != "" {
+		g.DiscordGuildID = req.GuildID
+	}
+
+	groupID, err := s.store.NextGroupID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	g.ID = groupID
+	if err := s.store.SaveGroup(ctx, g); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{
+		BillIDs:  map[int64]int64{},
+		Expenses: map[int64]int64{},
+	}
+
+	for _, e := range doc.Expenses {
+		oldID := e.ID
+		if e.ID, err = s.store.NextExpenseID(ctx); err != nil {
+			return nil, err
+		}
+		e.GroupID = groupID
+		e.Bills = nil
+		if err := s.store.SaveExpense(ctx, e); err != nil {
+			return nil, err
+		}
+		result.Expenses[oldID] = e.ID
+	}
+
+	// oldest first, so new IDs keep the original order
+	for i := len(doc.Bills) - 1; i >= 0; i-- {
+		rec := doc.Bills[i]
+
+		b := rec.Bill
+		if b.ID, err = s.store.NextBillID(ctx); err != nil {
+			return nil, err
+		}
+		b.GroupID = groupID
+		if b.ExpenseID != nil {
+			expenseID := result.Expenses[*b.ExpenseID]
+			b.ExpenseID = &expenseID
+		}
+		if err := s.store.SaveBill(ctx, b); err != nil {
+			return nil, err
+		}
+		result.BillIDs[rec.ID] = b.ID
+
+		for _, pr := range rec.Payments {
+			p := pr.Payment
+			if p.ID, err = s.store.NextPaymentID(ctx); err != nil {
+				return nil, err
+			}
+			p.BillID = b.ID
+			p.GroupID = groupID
+			p.Attachment = pr.Attachment
+			if err := s.store.SavePayment(ctx, p); err != nil {
+				return nil, err
+			}
+			result.Payments++
+		}
+
+		for _, e := range rec.Events {
+			if e.ID, err = s.store.NextBillEventID(ctx); err != nil {
+				return nil, err
+			}
+			e.BillID = b.ID
+			if err := s.store.SaveBillEvent(ctx, e); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, e := range doc.Ledger {
+		if e.ID, err = s.store.NextLedgerEntryID(ctx); err != nil {
+			return nil, err
+		}
+		e.GroupID = groupID
+		if e.BillID != nil {
+			billID := result.BillIDs[*e.BillID]
+			e.BillID = &billID
+		}
+		if err := s.store.SaveLedgerEntry(ctx, e); err != nil {
+			return nil, err
+		}
+		result.Ledger++
+	}
+
+	imported, err := s.groups.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	result.Group = imported
+
+	if err := s.audit.Record(ctx, audit.Entry{
+		GroupID:    groupID,
+		ActorID:    g.OwnerDiscordID,
+		Action:     audit.ActionGroupImport,
+		EntityType: audit.EntityGroup,
+		EntityID:   strconv.FormatInt(groupID, 10),
+		After: map[string]any{
+			"exported_at": doc.ExportedAt,
+			"bills":       len(result.BillIDs),
+			"expenses":    len(result.Expenses),
+			"payments":    result.Payments,
+			"ledger":      result.Ledger,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// validateDocument checks the version and that every reference inside the
+// document points at a record it contains.
+func validateDocument(doc *Document) error {
+	if doc.Format != DocumentFormat {
+		return ErrInvalidDocument
+	}
+	if doc.Version != DocumentVersion {
+		return fmt.Errorf("%w: got %d, this server reads %d", ErrUnsupportedVersion, doc.Version, DocumentVersion)
+	}
+
+	g := doc.Group
+	if g.Name == "" || g.DiscordGuildID == "" || g.OwnerDiscordID == "" || g.DueDay < 1 || g.DueDay > 31 {
+		return ErrInvalidGroup
+	}
+	if _, err := currency.Normalize(g.Currency); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidGroup, err)
+	}
+
+	expenses := map[int64]bool{}
+	for _, e := range doc.Expenses {
+		if expenses[e.ID] {
+			return fmt.Errorf("%w: expense %d", ErrDuplicateRecordID, e.ID)
+		}
+		expenses[e.ID] = true
+	}
+
+	bills := map[int64]bool{}
+	slips := map[string]bool{}
+	for _, rec := range doc.Bills {
+		if bills[rec.ID] {
+			return fmt.Errorf("%w: bill %d", ErrDuplicateRecordID, rec.ID)
+		}
+		bills[rec.ID] = true
+
+		if rec.MemberID == "" || !bill.IsValidStatus(rec.Status) {
+			return fmt.Errorf("%w: bill %d", ErrInvalidBillRecord, rec.ID)
+		}
+		if rec.Kind != bill.BillKindRecurring && rec.Kind != bill.BillKindExpense {
+			return fmt.Errorf("%w: bill %d", ErrInvalidBillRecord, rec.ID)
+		}
+		if rec.ExpenseID != nil && !expenses[*rec.ExpenseID] {
+			return fmt.Errorf("%w: expense %d of bill %d", ErrUnknownExpense, *rec.ExpenseID, rec.ID)
+		}
+		for _, pr := range rec.Payments {
+			if !countsSlip(pr.Payment) {
+				continue
+			}
+			if slips[pr.Reference] {
+				return fmt.Errorf("%w: slip %s", ErrDuplicateRecordID, pr.Reference)
+			}
+			slips[pr.Reference] = true
+		}
+	}
+
+	for _, e := range doc.Ledger {
+		if e.BillID != nil && !bills[*e.BillID] {
+			return fmt.Errorf("%w: bill %d of ledger entry %d", ErrUnknownBill, *e.BillID, e.ID)
+		}
+	}
+
+	return nil
+}
+
+// countsSlip reports whether p counts its slip reference, which may happen
+// only once per server.
+func countsSlip(p bill.Payment) bool {
+	return p.Source == bill.PaymentSourceSlip && p.Status == bill.PaymentStatusAccepted && p.Reference != ""
+}
+
+func slipReferences(doc *Document) []string {
+	var refs []string
+	for _, rec := range doc.Bills {
+		for _, pr := range rec.Payments {
+			if countsSlip(pr.Payment) {
+				refs = append(refs, pr.Reference)
+			}
+		}
+	}
+	return refs
+}
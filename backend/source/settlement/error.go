@@ -0,0 +1,15 @@
+package settlement
+
+import "errors"
+
+var (
+	ErrInvalidGuildID   = errors.New("discord_guild_id is required")
+	ErrInvalidActorID   = errors.New("actor_id is required")
+	ErrNothingToSettle  = errors.New("no outstanding bills to settle")
+	ErrPlanChanged      = errors.New("transfers don't match the current settle-up plan")
+	ErrNotParticipant   = errors.New("only a payee of the transfers can confirm the settlement")
+	ErrAlreadyConfirmed = errors.New("you already confirmed this settlement")
+	ErrSettlementBusy   = errors.New("the settlement changed while recording; try again")
+
+	ErrInvalidTransferAmount = errors.New("transfer amount must be > 0")
+)
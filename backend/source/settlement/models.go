@@ -0,0 +1,64 @@
+package settlement
+
+import "time"
+
+// Balance is a user's net position across a guild, in the settlement
+// currency: positive when others owe them, negative when they owe.
+type Balance struct {
+	UserID string  `json:"user_id"`
+	Net    float64 `json:"net"`
+}
+
+// Transfer is one suggested payment.
+type Transfer struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+}
+
+// Plan is the settle-up suggestion for a guild: the fewest transfers that
+// clear every outstanding bill between its members.
+type Plan struct {
+	GuildID   string     `json:"guild_id"`
+	Currency  string     `json:"currency"`
+	Balances  []Balance  `json:"balances"`
+	Transfers []Transfer `json:"transfers"`
+	BillIDs   []int64    `json:"bill_ids"`
+}
+
+type Status string
+
+const (
+	// StatusPending waits for the payees of the transfers to confirm them.
+	StatusPending Status = "pending"
+	// StatusCompleted closed the bills behind the plan.
+	StatusCompleted Status = "completed"
+	// StatusSuperseded was pending when the plan changed; its confirmations
+	// no longer count.
+	StatusSuperseded Status = "superseded"
+)
+
+// Settlement records a plan that was carried out and the bills it closed.
+// The bills close once every payee confirmed the transfers they received.
+type Settlement struct {
+	ID          int64      `json:"id"`
+	GuildID     string     `json:"guild_id"`
+	Currency    string     `json:"currency"`
+	Transfers   []Transfer `json:"transfers"`
+	BillIDs     []int64    `json:"bill_ids"`
+	Status      Status     `json:"status"`
+	ConfirmedBy []string   `json:"confirmed_by"`
+	ActorID     string     `json:"actor_id"`
+	Note        string     `json:"note,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// RecordSettlementRequest is a payee confirming that the transfers of the
+// current plan they receive were made. The transfers must match the plan the
+// server computes, otherwise bills changed in between and the plan has to be
+// fetched again.
+type RecordSettlementRequest struct {
+	ActorID   string     `json:"actor_id"`
+	Transfers []Transfer `json:"transfers"`
+	Note      string     `json:"note"`
+}
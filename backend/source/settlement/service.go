@@ -0,0 +1,356 @@
+package settlement
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/NoNiiEa/subShare-Discord/source/audit"
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+type Store interface {
+	GetOutstandingBillsByGuild(ctx context.Context, guildID string) ([]bill.Bill, error)
+	NextSettlementID(ctx context.Context) (int64, error)
+	// SaveSettlement reports false when st is pending and the guild already
+	// has a pending settlement.
+	SaveSettlement(ctx context.Context, st Settlement) (bool, error)
+	// UpdateSettlement writes the status and confirmations of st, only while
+	// the stored one is still pending and confirmed by exactly prev.
+	UpdateSettlement(ctx context.Context, st Settlement, prev []string) (bool, error)
+	GetSettlementsByGuild(ctx context.Context, guildID string) ([]Settlement, error)
+}
+
+type Service struct {
+	store  Store
+	groups *group.Service
+	audit  *audit.Service
+}
+
+func NewService(store Store, groups *group.Service) *Service {
+	return &Service{store: store, groups: groups}
+}
+
+// SetAuditLog makes the service record every settlement.
+func (s *Service) SetAuditLog(a *audit.Service) {
+	s.audit = a
+}
+
+// SettleUp nets every outstanding bill in the guild and suggests transfers.
+func (s *Service) SettleUp(ctx context.Context, guildID string) (*Plan, error) {
+	if guildID == "" {
+		return nil, ErrInvalidGuildID
+	}
+
+	bills, err := s.store.GetOutstandingBillsByGuild(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildPlan(guildID, bills), nil
+}
+
+// RecordSettlement confirms the transfers of the current plan. A payee
+// confirms the transfers they received, and only those: a group owner is a
+// payee like any other. The bills behind the plan close once every payee has
+// confirmed, since netting moves debts between creditors.
+func (s *Service) RecordSettlement(ctx context.Context, req RecordSettlementRequest, guildID string) (*Settlement, error) {
+	if guildID == "" {
+		return nil, ErrInvalidGuildID
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	bills, err := s.store.GetOutstandingBillsByGuild(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	plan := buildPlan(guildID, bills)
+
+	if len(plan.BillIDs) == 0 {
+		return nil, ErrNothingToSettle
+	}
+	if !sameTransfers(plan.Transfers, req.Transfers) {
+		return nil, ErrPlanChanged
+	}
+
+	groupIDs, billsByGroup := groupBills(bills, plan.BillIDs)
+	if !isPayee(plan, req.ActorID) {
+		return nil, ErrNotParticipant
+	}
+
+	st, err := s.pendingSettlement(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	if st == nil {
+		id, err := s.store.NextSettlementID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		st = &Settlement{
+			ID:          id,
+			GuildID:     guildID,
+			Currency:    plan.Currency,
+			Transfers:   plan.Transfers,
+			BillIDs:     plan.BillIDs,
+			Status:      StatusPending,
+			ConfirmedBy: []string{req.ActorID},
+			ActorID:     req.ActorID,
+			Note:        req.Note,
+			CreatedAt:   time.Now().UTC(),
+		}
+
+		saved, err := s.store.SaveSettlement(ctx, *st)
+		if err != nil {
+			return nil, err
+		}
+		if !saved {
+			return nil, ErrSettlementBusy
+		}
+	} else {
+		if slices.Contains(st.ConfirmedBy, req.ActorID) {
+			return nil, ErrAlreadyConfirmed
+		}
+
+		prev := st.ConfirmedBy
+		st.ConfirmedBy = append(slices.Clone(prev), req.ActorID)
+
+		updated, err := s.store.UpdateSettlement(ctx, *st, prev)
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			return nil, ErrSettlementBusy
+		}
+	}
+
+	// the confirmation is saved while pending, which keeps a concurrent one
+	// from settling the bills twice; the settlement only completes once they
+	// are all settled
+	if settlementStatus(st) == StatusCompleted {
+		reference := fmt.Sprintf("settlement:%d", st.ID)
+		for _, billID := range st.BillIDs {
+			if _, err := s.groups.SettleBill(ctx, billID, reference, req.ActorID); err != nil {
+				return nil, err
+			}
+		}
+
+		st.Status = StatusCompleted
+		updated, err := s.store.UpdateSettlement(ctx, *st, st.ConfirmedBy)
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			return nil, ErrSettlementBusy
+		}
+	}
+
+	// a settlement spans the guild; every group it touches logs it
+	for _, groupID := range groupIDs {
+		if err := s.audit.Record(ctx, audit.Entry{
+			GroupID:    groupID,
+			ActorID:    req.ActorID,
+			Action:     audit.ActionSettlement,
+			EntityType: audit.EntitySettlement,
+			EntityID:   strconv.FormatInt(st.ID, 10),
+			After: map[string]any{
+				"bill_ids":     billsByGroup[groupID],
+				"transfers":    st.Transfers,
+				"status":       st.Status,
+				"confirmed_by": st.ConfirmedBy,
+				"note":         st.Note,
+			},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return st, nil
+}
+
+// pendingSettlement returns the guild's pending settlement for plan, or nil
+// when there is none. One left pending for another plan is superseded: its
+// payees confirmed transfers that are no longer suggested.
+func (s *Service) pendingSettlement(ctx context.Context, plan *Plan) (*Settlement, error) {
+	settlements, err := s.store.GetSettlementsByGuild(ctx, plan.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range settlements {
+		st := &settlements[i]
+		if st.Status != StatusPending {
+			continue
+		}
+		if sameTransfers(st.Transfers, plan.Transfers) && slices.Equal(st.BillIDs, plan.BillIDs) {
+			return st, nil
+		}
+
+		st.Status = StatusSuperseded
+		updated, err := s.store.UpdateSettlement(ctx, *st, st.ConfirmedBy)
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			return nil, ErrSettlementBusy
+		}
+	}
+
+	return nil, nil
+}
+
+func settlementStatus(st *Settlement) Status {
+	for _, t := range st.Transfers {
+		if !slices.Contains(st.ConfirmedBy, t.To) {
+			return StatusPending
+		}
+	}
+	return StatusCompleted
+}
+
+// groupBills lists the groups the given bills belong to, in the order they
+// first appear, with the bills of each.
+func groupBills(bills []bill.Bill, billIDs []int64) ([]int64, map[int64][]int64) {
+	var groupIDs []int64
+	byGroup := map[int64][]int64{}
+	for _, b := range bills {
+		if !slices.Contains(billIDs, b.ID) {
+			continue
+		}
+		if _, ok := byGroup[b.GroupID]; !ok {
+			groupIDs = append(groupIDs, b.GroupID)
+		}
+		byGroup[b.GroupID] = append(byGroup[b.GroupID], b.ID)
+	}
+	return groupIDs, byGroup
+}
+
+func (s *Service) GetSettlements(ctx context.Context, guildID string) ([]Settlement, error) {
+	if guildID == "" {
+		return nil, ErrInvalidGuildID
+	}
+	return s.store.GetSettlementsByGuild(ctx, guildID)
+}
+
+// buildPlan works in satang so the transfers add up exactly. Bills in other
+// currencies count with the rate snapshot taken when they were issued.
+func buildPlan(guildID string, bills []bill.Bill) *Plan {
+	net := map[string]int64{}
+	plan := &Plan{
+		GuildID:   guildID,
+		Currency:  currency.Settlement,
+		Balances:  []Balance{},
+		Transfers: []Transfer{},
+		BillIDs:   []int64{},
+	}
+
+	for _, b := range bills {
+		if b.PayeeID == "" || b.PayeeID == b.MemberID {
+			continue
+		}
+
+		rate := b.ExchangeRate
+		if rate <= 0 {
+			rate = 1
+		}
+		owed := int64(math.Round(b.Outstanding() * rate * 100))
+		if owed <= 0 {
+			continue
+		}
+
+		net[b.MemberID] -= owed
+		net[b.PayeeID] += owed
+		plan.BillIDs = append(plan.BillIDs, b.ID)
+	}
+
+	type position struct {
+		userID string
+		amount int64
+	}
+	var debtors, creditors []position
+
+	users := make([]string, 0, len(net))
+	for u := range net {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+
+	for _, u := range users {
+		n := net[u]
+		plan.Balances = append(plan.Balances, Balance{UserID: u, Net: float64(n) / 100})
+		switch {
+		case n < 0:
+			debtors = append(debtors, position{u, -n})
+		case n > 0:
+			creditors = append(creditors, position{u, n})
+		}
+	}
+
+	// largest debtor pays largest creditor; every step clears at least one
+	// side, so there are never more than users-1 transfers
+	byAmount := func(p []position) func(i, j int) bool {
+		return func(i, j int) bool {
+			if p[i].amount != p[j].amount {
+				return p[i].amount > p[j].amount
+			}
+			return p[i].userID < p[j].userID
+		}
+	}
+	sort.SliceStable(debtors, byAmount(debtors))
+	sort.SliceStable(creditors, byAmount(creditors))
+
+	for i, j := 0, 0; i < len(debtors) && j < len(creditors); {
+		amount := debtors[i].amount
+		if creditors[j].amount < amount {
+			amount = creditors[j].amount
+		}
+
+		plan.Transfers = append(plan.Transfers, Transfer{
+			From:   debtors[i].userID,
+			To:     creditors[j].userID,
+			Amount: float64(amount) / 100,
+		})
+
+		debtors[i].amount -= amount
+		creditors[j].amount -= amount
+		if debtors[i].amount == 0 {
+			i++
+		}
+		if creditors[j].amount == 0 {
+			j++
+		}
+	}
+
+	return plan
+}
+
+func sameTransfers(a, b []Transfer) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].From != b[i].From || a[i].To != b[i].To || math.Abs(a[i].Amount-b[i].Amount) >= 0.005 {
+			return false
+		}
+	}
+	return true
+}
+
+func isPayee(plan *Plan, userID string) bool {
+	for _, t := range plan.Transfers {
+		if t.To == userID {
+			return true
+		}
+	}
+	return false
+}
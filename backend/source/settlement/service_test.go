@@ -0,0 +1,374 @@
+package settlement
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/NoNiiEa/subShare-Discord/source/bill"
+	"github.com/NoNiiEa/subShare-Discord/source/currency"
+	"github.com/NoNiiEa/subShare-Discord/source/group"
+)
+
+// memStore keeps the guild's open bills and settlements in memory.
+type memStore struct {
+	bills       []bill.Bill
+	settlements []Settlement
+}
+
+func (m *memStore) GetOutstandingBillsByGuild(ctx context.Context, guildID string) ([]bill.Bill, error) {
+	return m.bills, nil
+}
+
+func (m *memStore) NextSettlementID(ctx context.Context) (int64, error) {
+	return int64(len(m.settlements)) + 1, nil
+}
+
+func (m *memStore) SaveSettlement(ctx context.Context, st Settlement) (bool, error) {
+	for _, other := range m.settlements {
+		if other.Status == StatusPending && st.Status == StatusPending {
+			return false, nil
+		}
+	}
+	m.settlements = append(m.settlements, st)
+	return true, nil
+}
+
+func (m *memStore) UpdateSettlement(ctx context.Context, st Settlement, prev []string) (bool, error) {
+	for i := range m.settlements {
+		cur := &m.settlements[i]
+		if cur.ID == st.ID && cur.Status == StatusPending && slices.Equal(cur.ConfirmedBy, prev) {
+			cur.Status, cur.ConfirmedBy = st.Status, slices.Clone(st.ConfirmedBy)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memStore) GetSettlementsByGuild(ctx context.Context, guildID string) ([]Settlement, error) {
+	return slices.Clone(m.settlements), nil
+}
+
+func TestBuildPlan(t *testing.T) {
+	owes := func(id int64, member, payee string, due, paid float64) bill.Bill {
+		return bill.Bill{ID: id, GroupID: 1, MemberID: member, PayeeID: payee, AmountDue: due, AmountPaid: paid}
+	}
+
+	tests := []struct {
+		name      string
+		bills     []bill.Bill
+		balances  []Balance
+		transfers []Transfer
+		billIDs   []int64
+	}{
+		{
+			name:      "nothing outstanding",
+			bills:     []bill.Bill{owes(1, "a", "b", 100, 100)},
+			balances:  []Balance{},
+			transfers: []Transfer{},
+			billIDs:   []int64{},
+		},
+		{
+			name:      "only the rest of a part-paid bill",
+			bills:     []bill.Bill{owes(1, "a", "b", 100, 40)},
+			balances:  []Balance{{"a", -60}, {"b", 60}},
+			transfers: []Transfer{{"a", "b", 60}},
+			billIDs:   []int64{1},
+		},
+		{
+			name:      "skips bills without a payee and to oneself",
+			bills:     []bill.Bill{owes(1, "a", "", 100, 0), owes(2, "a", "a", 100, 0), owes(3, "a", "b", 10, 0)},
+			balances:  []Balance{{"a", -10}, {"b", 10}},
+			transfers: []Transfer{{"a", "b", 10}},
+			billIDs:   []int64{3},
+		},
+		{
+			name:      "debts in both directions net out",
+			bills:     []bill.Bill{owes(1, "a", "b", 100, 0), owes(2, "b", "a", 30, 0)},
+			balances:  []Balance{{"a", -70}, {"b", 70}},
+			transfers: []Transfer{{"a", "b", 70}},
+			billIDs:   []int64{1, 2},
+		},
+		{
+			name:      "a chain collapses into one transfer",
+			bills:     []bill.Bill{owes(1, "a", "b", 100, 0), owes(2, "b", "c", 100, 0)},
+			balances:  []Balance{{"a", -100}, {"b", 0}, {"c", 100}},
+			transfers: []Transfer{{"a", "c", 100}},
+			billIDs:   []int64{1, 2},
+		},
+		{
+			name: "largest debtor pays largest creditor first",
+			bills: []bill.Bill{
+				owes(1, "a", "c", 50, 0),
+				owes(2, "a", "d", 30, 0),
+				owes(3, "b", "d", 20, 0),
+			},
+			balances:  []Balance{{"a", -80}, {"b", -20}, {"c", 50}, {"d", 50}},
+			transfers: []Transfer{{"a", "c", 50}, {"a", "d", 30}, {"b", "d", 20}},
+			billIDs:   []int64{1, 2, 3},
+		},
+		{
+			name: "foreign bills count at their rate snapshot",
+			bills: []bill.Bill{
+				{ID: 1, MemberID: "a", PayeeID: "b", AmountDue: 10, ExchangeRate: 35.5},
+			},
+			balances:  []Balance{{"a", -355}, {"b", 355}},
+			transfers: []Transfer{{"a", "b", 355}},
+			billIDs:   []int64{1},
+		},
+		{
+			name:      "thirds add up in satang",
+			bills:     []bill.Bill{owes(1, "a", "c", 33.333, 0), owes(2, "b", "c", 33.333, 0)},
+			balances:  []Balance{{"a", -33.33}, {"b", -33.33}, {"c", 66.66}},
+			transfers: []Transfer{{"a", "c", 33.33}, {"b", "c", 33.33}},
+			billIDs:   []int64{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := buildPlan("guild", tt.bills)
+
+			if plan.GuildID != "guild" || plan.Currency != "THB" {
+				t.Errorf("plan is for %s in %s", plan.GuildID, plan.Currency)
+			}
+			if !reflect.DeepEqual(plan.Balances, tt.balances) {
+				t.Errorf("balances = %v, want %v", plan.Balances, tt.balances)
+			}
+			if !reflect.DeepEqual(plan.Transfers, tt.transfers) {
+				t.Errorf("transfers = %v, want %v", plan.Transfers, tt.transfers)
+			}
+			if !reflect.DeepEqual(plan.BillIDs, tt.billIDs) {
+				t.Errorf("bill ids = %v, want %v", plan.BillIDs, tt.billIDs)
+			}
+		})
+	}
+}
+
+func TestSettlementStatus(t *testing.T) {
+	transfers := []Transfer{{"a", "c", 50}, {"a", "d", 30}, {"b", "d", 20}}
+
+	tests := []struct {
+		name      string
+		confirmed []string
+		want      Status
+	}{
+		{"nobody yet", nil, StatusPending},
+		{"one payee of two", []string{"c"}, StatusPending},
+		{"every payee", []string{"c", "d"}, StatusCompleted},
+		{"payers don't count", []string{"a", "b", "c"}, StatusPending},
+		{"an owner who is no payee doesn't count", []string{"c", "o"}, StatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &Settlement{Transfers: transfers, ConfirmedBy: tt.confirmed}
+			if got := settlementStatus(st); got != tt.want {
+				t.Errorf("status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	plan := &Plan{Transfers: transfers}
+	for user, want := range map[string]bool{"a": false, "c": true, "d": true, "e": false} {
+		if got := isPayee(plan, user); got != want {
+			t.Errorf("isPayee(%s) = %v, want %v", user, got, want)
+		}
+	}
+}
+
+func TestGroupBills(t *testing.T) {
+	bills := []bill.Bill{{ID: 1, GroupID: 2}, {ID: 2, GroupID: 1}, {ID: 3, GroupID: 2}, {ID: 4, GroupID: 3}}
+
+	groupIDs, byGroup := groupBills(bills, []int64{1, 2, 3})
+	if want := []int64{2, 1}; !reflect.DeepEqual(groupIDs, want) {
+		t.Errorf("groups = %v, want %v", groupIDs, want)
+	}
+	if want := map[int64][]int64{2: {1, 3}, 1: {2}}; !reflect.DeepEqual(byGroup, want) {
+		t.Errorf("bills by group = %v, want %v", byGroup, want)
+	}
+}
+
+func TestRecordSettlementConfirmations(t *testing.T) {
+	const (
+		owner = "100000000000000001"
+		a     = "100000000000000002"
+		b     = "100000000000000003"
+		c     = "100000000000000004"
+	)
+	// the owner is owed a's bill; c's expense share is owed to b
+	toOwner := bill.Bill{ID: 1, GroupID: 1, MemberID: a, PayeeID: owner, AmountDue: 100}
+	toMember := bill.Bill{ID: 2, GroupID: 1, MemberID: c, PayeeID: b, AmountDue: 40}
+
+	tests := []struct {
+		name      string
+		bills     []bill.Bill
+		confirmed []string
+		actor     string
+		wantErr   error
+		want      []string
+	}{
+		{name: "owner confirms the transfer to them", bills: []bill.Bill{toOwner, toMember}, actor: owner, want: []string{owner}},
+		{name: "payee confirms", bills: []bill.Bill{toOwner, toMember}, actor: b, want: []string{b}},
+		{name: "owner can't confirm a transfer to a member", bills: []bill.Bill{toMember}, actor: owner, wantErr: ErrNotParticipant},
+		{name: "payer can't confirm", bills: []bill.Bill{toOwner, toMember}, actor: a, wantErr: ErrNotParticipant},
+		{name: "twice", bills: []bill.Bill{toOwner, toMember}, confirmed: []string{b}, actor: b, wantErr: ErrAlreadyConfirmed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := buildPlan("guild", tt.bills)
+			store := &memStore{bills: tt.bills}
+			if tt.confirmed != nil {
+				store.settlements = []Settlement{{
+					ID: 1, GuildID: "guild", Transfers: plan.Transfers, BillIDs: plan.BillIDs,
+					Status: StatusPending, ConfirmedBy: tt.confirmed,
+				}}
+			}
+			s := NewService(store, nil)
+
+			st, err := s.RecordSettlement(context.Background(), RecordSettlementRequest{ActorID: tt.actor, Transfers: plan.Transfers}, "guild")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RecordSettlement = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if st.Status != StatusPending || !reflect.DeepEqual(st.ConfirmedBy, tt.want) {
+				t.Errorf("settlement is %s, confirmed by %v; want pending, confirmed by %v", st.Status, st.ConfirmedBy, tt.want)
+			}
+		})
+	}
+}
+
+// groupStore backs the group service SettleBill runs on; the methods it
+// doesn't override panic through the nil Store.
+type groupStore struct {
+	group.Store
+	g        group.Group
+	bills    []bill.Bill
+	payments []bill.Payment
+	ledger   int64
+
+	failBill int64 // UpdateBill fails for this bill
+}
+
+var errStoreDown = errors.New("store down")
+
+func (s *groupStore) GetGroup(ctx context.Context, id int64) (*group.Group, error) {
+	g := s.g
+	g.Members = slices.Clone(s.g.Members)
+	return &g, nil
+}
+
+func (s *groupStore) UpdateGroupVersion(ctx context.Context, id, version int64, g group.Group) (bool, error) {
+	s.g = g
+	return true, nil
+}
+
+func (s *groupStore) GetLedgerBalances(ctx context.Context, groupID int64) (map[string]currency.Minor, error) {
+	return map[string]currency.Minor{}, nil
+}
+
+func (s *groupStore) NextLedgerEntryID(ctx context.Context) (int64, error) { return s.ledger + 1, nil }
+
+func (s *groupStore) SaveLedgerEntry(ctx context.Context, e group.LedgerEntry) error {
+	s.ledger++
+	return nil
+}
+
+func (s *groupStore) GetBillByID(ctx context.Context, id int64) (*bill.Bill, error) {
+	for _, b := range s.bills {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+	return nil, bill.ErrBillNotFound
+}
+
+func (s *groupStore) UpdateBill(ctx context.Context, b bill.Bill) (*bill.Bill, error) {
+	if b.ID == s.failBill {
+		return nil, errStoreDown
+	}
+	for i := range s.bills {
+		if s.bills[i].ID == b.ID {
+			s.bills[i] = b
+		}
+	}
+	return &b, nil
+}
+
+func (s *groupStore) NextPaymentID(ctx context.Context) (int64, error) {
+	return int64(len(s.payments)) + 1, nil
+}
+
+func (s *groupStore) SavePayment(ctx context.Context, p bill.Payment) error {
+	s.payments = append(s.payments, p)
+	return nil
+}
+
+func (s *groupStore) GetPaymentsByBillID(ctx context.Context, billID int64) ([]bill.Payment, error) {
+	var result []bill.Payment
+	for _, p := range s.payments {
+		if p.BillID == billID {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (s *groupStore) NextBillEventID(ctx context.Context) (int64, error) { return 1, nil }
+
+func (s *groupStore) SaveBillEvent(ctx context.Context, e bill.BillEvent) error { return nil }
+
+func TestRecordSettlementCompletesAfterTheBills(t *testing.T) {
+	const (
+		owner = "100000000000000001"
+		a     = "100000000000000002"
+		b     = "100000000000000003"
+	)
+	bills := []bill.Bill{
+		{ID: 1, GroupID: 1, MemberID: a, PayeeID: owner, AmountDue: 100, Status: bill.BillStatusPending},
+		{ID: 2, GroupID: 1, MemberID: b, PayeeID: owner, AmountDue: 40, Status: bill.BillStatusPending},
+	}
+
+	tests := []struct {
+		name     string
+		failBill int64
+		wantErr  error
+		want     Status
+	}{
+		{name: "every bill settles", want: StatusCompleted},
+		{name: "a bill fails to settle", failBill: 2, wantErr: errStoreDown, want: StatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &groupStore{
+				g: group.Group{ID: 1, OwnerDiscordID: owner, Currency: "THB", Members: []group.GroupMember{
+					{MemberID: owner, Status: group.MemberStatusActive},
+					{MemberID: a, Status: group.MemberStatusActive},
+					{MemberID: b, Status: group.MemberStatusActive},
+				}},
+				bills:    slices.Clone(bills),
+				failBill: tt.failBill,
+			}
+			store := &memStore{bills: bills}
+			s := NewService(store, group.NewService(gs))
+			plan := buildPlan("guild", bills)
+
+			_, err := s.RecordSettlement(context.Background(), RecordSettlementRequest{ActorID: owner, Transfers: plan.Transfers}, "guild")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RecordSettlement = %v, want %v", err, tt.wantErr)
+			}
+			if got := store.settlements[0].Status; got != tt.want {
+				t.Errorf("stored status = %s, want %s", got, tt.want)
+			}
+			if gs.bills[0].Status != bill.BillStatusVerified {
+				t.Errorf("first bill is %s, want verified", gs.bills[0].Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package settlement
+
+import "github.com/NoNiiEa/subShare-Discord/source/validate"
+
+// Validate checks the form of the transfers; whether they match the plan is
+// up to RecordSettlement.
+func (req RecordSettlementRequest) Validate() error {
+	var v validate.Validator
+	v.Snowflake("actor_id", req.ActorID)
+	for i, t := range req.Transfers {
+		field := validate.Index("transfers", i)
+		v.Snowflake(field+".from", t.From)
+		v.Snowflake(field+".to", t.To)
+		v.Check(t.Amount > 0, field+".amount", ErrInvalidTransferAmount)
+	}
+	return v.Err()
+}
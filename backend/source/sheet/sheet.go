@@ -0,0 +1,46 @@
+// Package sheet writes tables as CSV or as a minimal XLSX workbook, for the
+// exports accountants open in a spreadsheet.
+package sheet
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type Column struct {
+	Name    string
+	Numeric bool // written as a number in XLSX; member IDs and the like stay text
+}
+
+// Sheet is one table. Every row has one cell per column; empty cells are left
+// blank.
+type Sheet struct {
+	Name    string
+	Columns []Column
+	Rows    [][]string
+}
+
+func (s *Sheet) AddRow(cells ...string) {
+	s.Rows = append(s.Rows, cells)
+}
+
+func WriteCSV(w io.Writer, s Sheet) error {
+	cw := csv.NewWriter(w)
+
+	header := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		header[i] = c.Name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range s.Rows {
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
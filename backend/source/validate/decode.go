@@ -0,0 +1,149 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeJSON reads one JSON value from r into v. Fields v does not have and
+// values of the wrong type come back as Errors naming the field; anything
+// that is not JSON at all is ErrMalformed.
+func DecodeJSON(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("%w: unexpected data after the JSON value", ErrMalformed)
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	// a body over the server's limit keeps its own error
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: empty body", ErrMalformed)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return Errors{{Field: field, Err: fmt.Errorf("%w: want %s, got %s", ErrWrongType, jsonType(typeErr.Type.Kind()), typeErr.Value)}}
+	}
+
+	// encoding/json has no type for this one
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return Errors{{Field: strings.Trim(name, `"`), Err: ErrUnknownField}}
+	}
+
+	return fmt.Errorf("%w: %v", ErrMalformed, err)
+}
+
+// jsonType names a Go kind the way a JSON client thinks of it.
+func jsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Bool:
+		return "boolean"
+	}
+	return kind.String()
+}
+
+// DecodeForm decodes the text fields of a form into v the way DecodeJSON
+// decodes a body, matching field names to v's JSON tags: names v does not
+// have are ErrUnknownField, and numbers or RFC 3339 times that don't parse
+// are ErrWrongType, all reported at once.
+func DecodeForm(form map[string][]string, v any) error {
+	fields := jsonFields(reflect.TypeOf(v).Elem())
+
+	var errs Errors
+	obj := map[string]any{}
+	for _, name := range slices.Sorted(maps.Keys(form)) {
+		t, ok := fields[name]
+		if !ok {
+			errs = append(errs, FieldError{Field: name, Err: ErrUnknownField})
+			continue
+		}
+		if len(form[name]) == 0 {
+			continue
+		}
+
+		value := form[name][0]
+		switch {
+		case t == reflect.TypeOf(time.Time{}):
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				errs = append(errs, FieldError{Field: name, Err: fmt.Errorf("%w: want an RFC 3339 time, got %q", ErrWrongType, value)})
+				continue
+			}
+			obj[name] = value
+		case jsonType(t.Kind()) == "integer" || jsonType(t.Kind()) == "number":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				errs = append(errs, FieldError{Field: name, Err: fmt.Errorf("%w: want %s, got %q", ErrWrongType, jsonType(t.Kind()), value)})
+				continue
+			}
+			obj[name] = n
+		default:
+			obj[name] = value
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return DecodeJSON(bytes.NewReader(body), v)
+}
+
+// jsonFields maps the JSON names of struct t's fields to their types, with
+// pointers dereferenced. Fields tagged "-" are left out.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		fields[name] = ft
+	}
+	return fields
+}
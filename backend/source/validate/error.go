@@ -0,0 +1,13 @@
+package validate
+
+import "errors"
+
+var (
+	ErrInvalid   = errors.New("validation failed") // wrapped by every Errors
+	ErrMalformed = errors.New("malformed JSON body")
+
+	ErrRequired     = errors.New("is required")
+	ErrUnknownField = errors.New("unknown field")
+	ErrWrongType    = errors.New("wrong type")
+	ErrSnowflake    = errors.New("must be a Discord ID of 17 to 20 digits")
+)
@@ -0,0 +1,48 @@
+package validate
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// FieldError is one problem with one field of a request. Field is the JSON
+// path, e.g. payment_accounts[1].account.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+func (e FieldError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	}{e.Field, e.Err.Error()})
+}
+
+// Errors are all the problems found with a request. It matches ErrInvalid and
+// each field's own error with errors.Is.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return ErrInvalid.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e Errors) Unwrap() []error {
+	errs := []error{ErrInvalid}
+	for _, fe := range e {
+		errs = append(errs, fe)
+	}
+	return errs
+}
@@ -0,0 +1,87 @@
+package validate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Validator collects the field errors of a request so the caller learns about
+// all of them at once:
+//
+//	var v validate.Validator
+//	v.Check(req.Name != "", "name", ErrInvalidName)
+//	v.Snowflake("owner_id", req.OwnerID)
+//	return v.Err()
+type Validator struct {
+	errs Errors
+}
+
+// Add records err against field.
+func (v *Validator) Add(field string, err error) {
+	v.errs = append(v.errs, FieldError{Field: field, Err: err})
+}
+
+// Check records err against field unless ok.
+func (v *Validator) Check(ok bool, field string, err error) {
+	if !ok {
+		v.Add(field, err)
+	}
+}
+
+// Required records ErrRequired against field when value is empty.
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, ErrRequired)
+}
+
+// Snowflake checks a required Discord ID.
+func (v *Validator) Snowflake(field, id string) {
+	if id == "" {
+		v.Add(field, ErrRequired)
+		return
+	}
+	v.Check(IsSnowflake(id), field, ErrSnowflake)
+}
+
+// OptionalSnowflake checks a Discord ID that may be left out.
+func (v *Validator) OptionalSnowflake(field, id string) {
+	if id != "" {
+		v.Snowflake(field, id)
+	}
+}
+
+// Snowflakes checks a list of Discord IDs, naming each bad one by index.
+func (v *Validator) Snowflakes(field string, ids []string) {
+	for i, id := range ids {
+		v.Snowflake(Index(field, i), id)
+	}
+}
+
+// Err returns the collected errors as Errors, or nil when there are none.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Index names an element of a list field, e.g. member_ids[2].
+func Index(field string, i int) string {
+	return fmt.Sprintf("%s[%d]", field, i)
+}
+
+// IsSnowflake reports whether id looks like a Discord ID: a 64-bit unsigned
+// integer written in decimal, 17 digits or more for anything created since
+// 2015.
+func IsSnowflake(id string) bool {
+	if len(id) < 17 || len(id) > 20 {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
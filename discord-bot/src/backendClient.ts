@@ -1,21 +1,154 @@
-import axios, { AxiosInstance } from "axios";
+import axios, { AxiosError, AxiosInstance, InternalAxiosRequestConfig } from "axios";
+import { randomUUID } from "node:crypto";
 
 export interface HealthResponse {
     status: string;
 }
 
+// Body of every error the backend returns. `code` is stable; `message` is
+// meant for people and may change.
+export interface ErrorResponse {
+    code: string;
+    message: string;
+    details?: unknown;
+}
+
+// One entry of `details.fields` on a validation_failed error.
+export interface FieldError {
+    field: string;
+    message: string;
+}
+
+export class BackendError extends Error {
+    constructor(
+        public readonly status: number,
+        public readonly code: string,
+        message: string,
+        public readonly details?: unknown,
+    ) {
+        super(message);
+        this.name = "BackendError";
+    }
+}
+
+// What to tell users for the errors they can do something about. Anything
+// else falls back to the backend's message.
+const friendlyMessages: Record<string, string> = {
+    not_found: "I couldn't find that.",
+    not_owner: "Only the group owner can do that.",
+    not_member: "Only active members of the group can do that.",
+    member_not_found: "That user isn't in this group.",
+    already_invited: "That user is already invited.",
+    already_member: "That user is already a member.",
+    not_invited: "You don't have an invite to this group.",
+    already_paid: "That member has already paid.",
+    bill_closed: "That bill is already paid, canceled or waived.",
+    not_owed_to_owner: "That share is owed to the member who paid; pay them directly.",
+    currency_locked: "The currency can't change once the group has bills.",
+    bill_already_verified: "That bill is already paid.",
+    bill_member_mismatch: "That bill belongs to someone else.",
+    illegal_transition: "That bill can't be changed that way right now.",
+    wrong_receiver: "The slip was paid to the wrong account.",
+    duplicate_slip: "That slip was already used.",
+    verification_failed: "The slip couldn't be verified.",
+    slip_date_missing: "The slip has no transfer date.",
+    slip_date_invalid: "The slip's transfer date couldn't be read.",
+    slip_predates_bill: "The slip is older than the bill.",
+    slip_in_future: "The slip is dated in the future.",
+    slip_outside_cycle: "The slip is outside this bill's month.",
+    slip_verification_unavailable: "Slip checking is not set up on the server.",
+    plan_changed: "The balances changed; run settle-up again.",
+    nothing_to_settle: "There is nothing to settle.",
+    not_participant: "Only someone receiving a transfer can confirm the settlement.",
+    already_confirmed: "You already confirmed this settlement.",
+    settlement_busy: "Someone else confirmed at the same time; try again.",
+    request_too_large: "That file is too large.",
+};
+
+// describeError turns any error from BackendClient into a sentence for Discord.
+export function describeError(err: unknown): string {
+    if (err instanceof BackendError) {
+        const fields = fieldErrors(err);
+        if (fields.length > 0) {
+            return "Please fix: " + fields.map((f) => `${f.field}: ${f.message}`).join("; ");
+        }
+        return friendlyMessages[err.code] ?? err.message;
+    }
+    return "Could not reach the backend, it might be down.";
+}
+
+// fieldErrors lists what was wrong with each field of a rejected request.
+export function fieldErrors(err: BackendError): FieldError[] {
+    if (err.code !== "validation_failed") {
+        return [];
+    }
+    const details = err.details as { fields?: FieldError[] } | undefined;
+    return details?.fields ?? [];
+}
+
+function toBackendError(err: AxiosError<ErrorResponse>): Error {
+    const res = err.response;
+    if (!res) {
+        return err;
+    }
+
+    const body = res.data;
+    if (body && typeof body === "object" && typeof body.code === "string") {
+        return new BackendError(res.status, body.code, body.message, body.details);
+    }
+    return new BackendError(res.status, "internal_error", String(body ?? err.message));
+}
+
+const idempotencyKeyHeader = "Idempotency-Key";
+const mutatingMethods = new Set(["post", "put", "patch", "delete"]);
+
 export class BackendClient {
     private http: AxiosInstance;
-    
-    constructor(baseURL: string) {
+    private mutatingCalls = 0;
+
+    // interactionId, when set, is what the Idempotency-Key of every mutating
+    // call is derived from; see forInteraction.
+    constructor(
+        private readonly baseURL: string,
+        private readonly interactionId?: string,
+    ) {
         this.http = axios.create({
             baseURL,
             timeout: 5000,
         });
+        this.http.interceptors.request.use((config) => this.withIdempotencyKey(config));
+        this.http.interceptors.response.use(
+            (res) => res,
+            (err: AxiosError<ErrorResponse>) => Promise.reject(toBackendError(err)),
+        );
+    }
+
+    // forInteraction returns a client for handling one Discord interaction.
+    // Its mutating calls send Idempotency-Key "<interaction id>-<n>" for the
+    // n-th call, so handling the same interaction again, e.g. after a timeout,
+    // replays the backend's answers instead of paying or inviting twice.
+    forInteraction(interactionId: string): BackendClient {
+        return new BackendClient(this.baseURL, interactionId);
+    }
+
+    // withIdempotencyKey gives each mutating request its key. A request that
+    // already has one keeps it, so a retried request is the same request.
+    private withIdempotencyKey(config: InternalAxiosRequestConfig): InternalAxiosRequestConfig {
+        const method = (config.method ?? "get").toLowerCase();
+        if (!mutatingMethods.has(method) || config.headers.has(idempotencyKeyHeader)) {
+            return config;
+        }
+
+        this.mutatingCalls++;
+        const key = this.interactionId
+            ? `${this.interactionId}-${this.mutatingCalls}`
+            : randomUUID();
+        config.headers.set(idempotencyKeyHeader, key);
+        return config;
     }
 
     async health(): Promise<HealthResponse> {
         const res = await this.http.get<HealthResponse>("/health");
         return res.data;
     }
-}
\ No newline at end of file
+}
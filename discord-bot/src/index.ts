@@ -4,7 +4,7 @@ import {
   GatewayIntentBits,
   Message
 } from "discord.js";
-import { BackendClient } from "./backendClient.js";
+import { BackendClient, describeError } from "./backendClient.js";
 
 const token = process.env.DISCORD_TOKEN;
 const backendBaseUrl = process.env.BACKEND_BASE_URL ?? "http://localhost:8080";
@@ -33,9 +33,11 @@ client.on("messageCreate", async (message: Message) => {
   if (message.author.bot) return;
 
   const content = message.content.trim();
+  // one client per message, so its writes are keyed to it
+  const api = backend.forInteraction(message.id);
 
   if (content.startsWith("!ping")) {
-    await handlePingCommand(message);
+    await handlePingCommand(message, api);
   }
 
   // Later:
@@ -43,15 +45,15 @@ client.on("messageCreate", async (message: Message) => {
   // if (content.startsWith("!group")) { ... }
 });
 
-async function handlePingCommand(message: Message) {
+async function handlePingCommand(message: Message, api: BackendClient) {
   try {
-    const health = await backend.health();
+    const health = await api.health();
     const status = health.status || "unknown";
 
     await message.reply(`✅ Backend status: **${status}**`);
   } catch (err) {
     console.error("Error calling backend /api/health:", err);
-    await message.reply("❌ Could not reach backend, it might be down.");
+    await message.reply(`❌ ${describeError(err)}`);
   }
 }
 